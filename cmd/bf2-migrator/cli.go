@@ -0,0 +1,544 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/prefix"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/preset"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/prompt"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/readonly"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/remotepatch"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	passwordEnv = "BF2_MIGRATOR_PASSWORD"
+)
+
+var migrateProviders = map[string]gamespy.Provider{
+	"bf2hub":  gamespy.ProviderBF2Hub,
+	"playbf2": gamespy.ProviderPlayBF2,
+	"openspy": gamespy.ProviderOpenSpy,
+}
+
+// runCLI runs the portable subset of the migrator's features without requiring the GUI (or Windows), e.g. on Linux
+// hosts running the server or game under Wine/Proton
+func runCLI(o options) error {
+	if o.profiles || o.migrate != "" {
+		return runProfiles(o)
+	}
+
+	if o.redirect != "" || o.unredirect || o.cleanHosts {
+		return runRedirect(o)
+	}
+
+	return runPatch(o)
+}
+
+// runPatch detects/patches the executables in the given folder
+func runPatch(o options) error {
+	if len(o.remote) > 0 {
+		return runRemotePatch(o)
+	}
+
+	if o.dir == "" {
+		dir, err := detectInstallDir(o.prefix)
+		if err != nil {
+			return fmt.Errorf("-dir is required, failed to detect install folder: %w", err)
+		}
+		log.Info().
+			Str("dir", dir).
+			Msg("Detected install folder")
+		o.dir = dir
+	}
+
+	var paths []string
+	if o.catalog != "" {
+		paths = append(paths, o.catalog)
+	}
+
+	catalog, err := patchable.LoadCatalog(paths...)
+	if err != nil {
+		return fmt.Errorf("failed to load provider catalog: %w", err)
+	}
+
+	patchables := selectInstalled(catalog.Patchables(), o)
+	if len(patchables) == 0 {
+		return fmt.Errorf("found no (selected) executables in %s", o.dir)
+	}
+
+	if o.detect {
+		for _, p := range patchables {
+			provider, err2 := patch.DetectProvider(p, o.dir)
+			if err2 != nil {
+				log.Error().
+					Err(err2).
+					Str("executable", p.GetFileName()).
+					Str("variant", patchable.VariantOf(p)).
+					Msg("Failed to detect provider")
+				continue
+			}
+			log.Info().
+				Str("executable", p.GetFileName()).
+				Str("variant", patchable.VariantOf(p)).
+				Str("provider", string(provider)).
+				Msg("Detected provider")
+		}
+
+		checks, err2 := catalog.CheckDLLs(o.dir)
+		if err2 != nil {
+			return fmt.Errorf("failed to check provider DLLs: %w", err2)
+		}
+		for _, check := range checks {
+			e := log.Info()
+			if check.Broken() {
+				e = log.Warn()
+			}
+			e.Str("dll", check.FileName).
+				Str("status", string(check.Status)).
+				Str("reason", check.Reason).
+				Msg("Checked provider DLL")
+		}
+
+		conflicts, err2 := hosts.FindConflicts(o.hosts, catalog.Domains())
+		if err2 != nil {
+			return fmt.Errorf("failed to check hosts file: %w", err2)
+		}
+		for _, conflict := range conflicts {
+			log.Warn().
+				Str("hosts", o.hosts).
+				Int("line", conflict.Line).
+				Str("entry", conflict.Text).
+				Msg("Found conflicting hosts file entry, remove it using -clean-hosts")
+		}
+		return nil
+	}
+
+	provider := patch.Provider(o.patch)
+	if _, ok := catalog.Provider(provider); !ok {
+		return fmt.Errorf("provider %s is not defined in catalog", provider)
+	}
+
+	if o.readOnly {
+		// Patch copies of the executables to verify patching would succeed
+		if err = patchable.DryRun(patchables, o.dir, provider); err != nil {
+			return fmt.Errorf("patching would fail: %w", err)
+		}
+		log.Info().
+			Str("dir", o.dir).
+			Str("provider", string(provider)).
+			Msg("Would patch executables (read-only mode)")
+		return nil
+	}
+
+	if err = ensureNotInterrupted(); err != nil {
+		return err
+	}
+
+	if o.adopt {
+		adopted, err2 := patchable.AdoptAll(patchables, o.dir)
+		if err2 != nil {
+			return fmt.Errorf("failed to clean up executables patched by other patchers: %w", err2)
+		}
+		for _, name := range adopted {
+			log.Info().
+				Str("executable", name).
+				Msg("Cleaned up leftovers of other patcher")
+		}
+	}
+
+	if err = patchable.PatchAll(patchables, o.dir, provider); err != nil {
+		if errors.Is(err, patch.ErrUnknownModifications) && !o.adopt {
+			return fmt.Errorf("%w (executables patched by other patchers can be cleaned up using -adopt)", err)
+		}
+		return err
+	}
+
+	log.Info().
+		Str("dir", o.dir).
+		Str("provider", string(provider)).
+		Msg("Patched executables")
+
+	return nil
+}
+
+// isInteractive returns whether stdin is a terminal (rather than e.g. a pipe or no console at all)
+func isInteractive() bool {
+	stats, err := os.Stdin.Stat()
+	return err == nil && stats.Mode()&os.ModeCharDevice != 0
+}
+
+// runRemotePatch patches the server executables on each remote target
+func runRemotePatch(o options) error {
+	if o.patch == "" {
+		return fmt.Errorf("-patch is required with -remote")
+	}
+
+	ts := make([]remotepatch.Target, 0, len(o.remote))
+	for _, r := range o.remote {
+		t, err := remotepatch.ParseTarget(r)
+		if err != nil {
+			return err
+		}
+		ts = append(ts, t)
+	}
+
+	var paths []string
+	if o.catalog != "" {
+		paths = append(paths, o.catalog)
+	}
+
+	catalog, err := patchable.LoadCatalog(paths...)
+	if err != nil {
+		return fmt.Errorf("failed to load provider catalog: %w", err)
+	}
+
+	provider := patch.Provider(o.patch)
+	if _, ok := catalog.Provider(provider); !ok {
+		return fmt.Errorf("provider %s is not defined in catalog", provider)
+	}
+
+	servers := make([]patch.Patchable, 0)
+	for _, p := range catalog.Patchables() {
+		if patchable.IsServer(p) {
+			servers = append(servers, p)
+		}
+	}
+
+	// Keep unreachable servers from stalling the remaining ones, reporting those which could not be patched in time
+	var deadline time.Time
+	if o.deadline > 0 {
+		deadline = time.Now().Add(o.deadline)
+	}
+
+	// Patch as many servers as possible, rather than stopping at the first one which fails
+	// Without a terminal to prompt on (e.g. in scripts), ssh has to rely on keys/ssh-agent and known hosts
+	var tr remotepatch.Transport = remotepatch.SCP{IdentityFile: o.sshKey, Deadline: deadline, BatchMode: !isInteractive()}
+	if o.readOnly {
+		// Still download and patch the executables (locally) to verify they can be patched
+		tr = remotepatch.ReadOnly(tr)
+	}
+	var failed, skipped int
+	for _, t := range ts {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			log.Warn().
+				Str("target", t.String()).
+				Msg("Skipped remote server, deadline exceeded")
+			skipped++
+			continue
+		}
+
+		patched, err2 := remotepatch.Patch(tr, t, servers, provider)
+		if errors.Is(err2, readonly.ErrReadOnly) {
+			log.Info().
+				Str("target", t.String()).
+				Str("provider", string(provider)).
+				Msg("Would patch remote server (read-only mode)")
+			continue
+		}
+		if err2 != nil {
+			log.Error().
+				Err(err2).
+				Str("target", t.String()).
+				Msg("Failed to patch remote server")
+			failed++
+			continue
+		}
+
+		log.Info().
+			Str("target", t.String()).
+			Strs("executables", patched).
+			Str("provider", string(provider)).
+			Msg("Patched remote server")
+	}
+
+	if skipped > 0 {
+		return fmt.Errorf("failed to patch %d and skipped %d of %d remote servers", failed, skipped, len(ts))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to patch %d of %d remote servers", failed, len(ts))
+	}
+
+	return nil
+}
+
+// runRedirect adds/removes the hosts file entries redirecting the GameSpy hostnames to a provider, or removes
+// conflicting entries left behind by other patchers
+func runRedirect(o options) error {
+	if o.unredirect {
+		if o.readOnly {
+			provider, installed, err := hosts.Installed(o.hosts)
+			if err != nil {
+				return err
+			}
+			log.Info().
+				Str("hosts", o.hosts).
+				Bool("redirect", installed).
+				Str("provider", provider).
+				Msg("Would remove redirect from hosts file (read-only mode)")
+			return nil
+		}
+
+		removed, err := hosts.Remove(o.hosts)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			log.Info().
+				Str("hosts", o.hosts).
+				Msg("Hosts file contains no redirect")
+			return nil
+		}
+		log.Info().
+			Str("hosts", o.hosts).
+			Msg("Removed redirect from hosts file")
+		return nil
+	}
+
+	var paths []string
+	if o.catalog != "" {
+		paths = append(paths, o.catalog)
+	}
+
+	catalog, err := patchable.LoadCatalog(paths...)
+	if err != nil {
+		return fmt.Errorf("failed to load provider catalog: %w", err)
+	}
+
+	if o.cleanHosts && o.readOnly {
+		conflicts, err2 := hosts.FindConflicts(o.hosts, catalog.Domains())
+		if err2 != nil {
+			return err2
+		}
+		for _, conflict := range conflicts {
+			log.Info().
+				Str("hosts", o.hosts).
+				Int("line", conflict.Line).
+				Str("entry", conflict.Text).
+				Msg("Would remove conflicting hosts file entry (read-only mode)")
+		}
+		return nil
+	}
+
+	if o.cleanHosts {
+		removed, err2 := hosts.RemoveConflicts(o.hosts, catalog.Domains())
+		if err2 != nil {
+			return err2
+		}
+		log.Info().
+			Str("hosts", o.hosts).
+			Int("entries", removed).
+			Msg("Removed conflicting entries from hosts file")
+		return nil
+	}
+
+	redirects, err := catalog.Redirects(patch.Provider(o.redirect))
+	if err != nil {
+		return err
+	}
+
+	entries, err := hosts.Resolve(redirects, hosts.LookupIPv4)
+	if err != nil {
+		return err
+	}
+
+	if o.readOnly {
+		for _, e := range entries {
+			log.Info().
+				Str("hosts", o.hosts).
+				Str("entry", fmt.Sprintf("%s %s", e.IP, e.Hostname)).
+				Msg("Would add hosts file entry (read-only mode)")
+		}
+		return nil
+	}
+
+	if err = hosts.Apply(o.hosts, o.redirect, entries); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("hosts", o.hosts).
+		Str("provider", o.redirect).
+		Int("entries", len(entries)).
+		Msg("Redirected GameSpy hostnames via hosts file")
+
+	return nil
+}
+
+// applyPreset sets any options not explicitly set via flags to the preset's values
+func applyPreset(o *options, name string) error {
+	path, err := preset.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	presets, err := preset.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load presets: %w", err)
+	}
+
+	p, ok := preset.Find(presets, name)
+	if !ok {
+		return fmt.Errorf("preset %q does not exist", name)
+	}
+	if p.PatchProvider == "" {
+		return fmt.Errorf("preset %q does not contain a provider to patch to", p.Name)
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["dir"] {
+		o.dir = p.Dir
+	}
+	if !set["patch"] {
+		o.patch = p.PatchProvider
+	}
+	if !set["game"] {
+		o.patchGame = p.PatchGame
+	}
+	if !set["server"] {
+		o.patchServer = p.PatchServer
+	}
+
+	return nil
+}
+
+// detectInstallDir returns the install folder registered in the given prefix or, if none is given,
+// in the first detected prefix
+func detectInstallDir(p string) (string, error) {
+	if p != "" {
+		return prefix.FindInstallDir(p)
+	}
+
+	return prefix.DetectInstallDir()
+}
+
+// selectInstalled returns the patchables whose executables exist in the folder and are selected for patching
+func selectInstalled(patchables []patch.Patchable, o options) []patch.Patchable {
+	installed := make([]patch.Patchable, 0, len(patchables))
+	for _, p := range patchables {
+		if patchable.IsServer(p) && !o.patchServer || !patchable.IsServer(p) && !o.patchGame {
+			continue
+		}
+		if _, err := os.Stat(patch.LongPath(filepath.Join(o.dir, p.GetFileName()))); err != nil {
+			continue
+		}
+		installed = append(installed, p)
+	}
+
+	return installed
+}
+
+// runProfiles lists/migrates the profiles in the given prefix
+func runProfiles(o options) error {
+	if o.prefix == "" {
+		p, err := prefix.Detect()
+		if err != nil {
+			return fmt.Errorf("-prefix is required, failed to detect prefix: %w", err)
+		}
+		log.Info().
+			Str("prefix", p).
+			Msg("Detected prefix")
+		o.prefix = p
+	}
+
+	dir, err := prefix.FindProfilesDir(o.prefix)
+	if err != nil {
+		return err
+	}
+
+	if o.profiles {
+		profiles, err2 := prefix.ReadProfiles(dir)
+		if err2 != nil {
+			return fmt.Errorf("failed to read profiles: %w", err2)
+		}
+		for _, p := range profiles {
+			log.Info().
+				Str("key", p.Key).
+				Str("name", p.Name).
+				Str("nick", p.Nick).
+				Bool("multiplayer", p.Multiplayer()).
+				Msg("Found profile")
+		}
+		return nil
+	}
+
+	provider, ok := migrateProviders[strings.ToLower(o.migrate)]
+	if !ok {
+		return fmt.Errorf("unknown provider: %s", o.migrate)
+	}
+
+	if o.profile == "" {
+		return fmt.Errorf("-profile is required")
+	}
+
+	profile, err := prefix.ReadProfile(dir, o.profile)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+	if !profile.Multiplayer() || profile.Nick == "" {
+		return fmt.Errorf("profile %s is not a multiplayer profile", profile.Key)
+	}
+
+	// Profile passwords can only be decrypted on Windows, so they are always provided by the user
+	p := prompt.NewTerminal(os.Stdin, os.Stdout, passwordEnv)
+	password, ok := p.PromptPassword(fmt.Sprintf("Migrating %s (%s)", profile.Name, profile.Nick), "Profile password")
+	if !ok {
+		return fmt.Errorf("no password provided")
+	}
+
+	creds := migrate.Credentials{
+		Nick:     profile.Nick,
+		Email:    profile.Email,
+		Password: password,
+	}
+	client := gamespy.NewClientWithOptions(clientOptions(o, loadSettings()))
+	defer client.Close()
+	var c migrate.Client = client
+	if o.readOnly {
+		c = migrate.ReadOnly(c)
+	}
+
+	migrated, err := migrate.Profile(c, p, provider, &creds)
+	if errors.Is(err, readonly.ErrReadOnly) {
+		log.Info().
+			Str("nick", creds.Nick).
+			Str("provider", o.migrate).
+			Msg("Would migrate profile (read-only mode)")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !migrated {
+		log.Info().
+			Str("nick", creds.Nick).
+			Str("provider", o.migrate).
+			Msg("Profile is already set up on provider")
+		return nil
+	}
+
+	log.Info().
+		Str("nick", creds.Nick).
+		Str("provider", o.migrate).
+		Str("profileID", creds.ProfileID).
+		Msg("Migrated profile")
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+func runGUI(_ options) {
+	log.Fatal().Msg("The GUI is only available on Windows, use -patch or -detect (see -help)")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	filerepo "github.com/cetteup/filerepo/pkg"
+	"github.com/cetteup/joinme.click-launcher/pkg/registry_repository"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/conman/pkg/handler"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/gui"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/logfile"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+func runGUI(o options) {
+	// GUI builds have no console, so also show log output in the main window's log pane
+	// and write structured logs to rotated files, which can be attached to support requests.
+	// Debug output (e.g. packet logs) is only meant for the files.
+	logs := gui.NewLogBuffer()
+	writers := []io.Writer{
+		&zerolog.FilteredLevelWriter{
+			Writer: zerolog.LevelWriterAdapter{Writer: zerolog.ConsoleWriter{Out: logs, NoColor: true, TimeFormat: "15:04:05"}},
+			Level:  zerolog.InfoLevel,
+		},
+		zerolog.ConsoleWriter{Out: os.Stdout},
+	}
+	logDir, err := logfile.DefaultDir()
+	var file *logfile.Writer
+	if err == nil {
+		file, err = logfile.Open(logDir, logfile.DefaultMaxSize, logfile.DefaultMaxBackups)
+	}
+	if err == nil {
+		defer file.Close()
+		writers = append(writers, file)
+	}
+	log.Logger = log.Output(zerolog.MultiLevelWriter(writers...))
+	if err != nil {
+		// Logging to the log pane still works
+		log.Error().Err(err).Msg("Failed to open log file")
+	}
+
+	fileRepository := filerepo.New()
+	registryRepository := registry_repository.New()
+	h := handler.New(fileRepository)
+
+	s := loadSettings()
+	c := gamespy.NewClientWithOptions(clientOptions(o, s))
+	defer c.Close()
+	mw, err := gui.CreateMainWindow(h, registryRepository, c, gui.Options{
+		PatchGame:   o.patchGame,
+		PatchServer: o.patchServer,
+		ReadOnly:    o.readOnly,
+		Dir:         o.dir,
+		Log:         logs,
+		LogDir:      logDir,
+		Proxy:       networkProxy(o, s),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create main window")
+	}
+
+	mw.Run()
+}
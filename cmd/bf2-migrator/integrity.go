@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+)
+
+const releasesURL = "https://github.com/cetteup/bf2-migrator/releases"
+
+// Checks the binary before doing anything else, since truncated or re-packed copies otherwise fail in obscure ways
+func init() {
+	if err := checkIntegrity(); err != nil {
+		failStartup(fmt.Sprintf("This copy of bf2-migrator is damaged or has been modified (%s).\n\nPlease download it again from %s", err.Error(), releasesURL))
+	}
+}
+
+// checkIntegrity verifies that the executable and its embedded resources are intact
+func checkIntegrity() error {
+	if err := verifyExecutable(); err != nil {
+		return err
+	}
+	return patchable.VerifyEmbedded()
+}
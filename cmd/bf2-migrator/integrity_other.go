@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// verifyExecutable is a no-op, since release builds are only validated for the Windows executable format
+func verifyExecutable() error {
+	return nil
+}
+
+func failStartup(message string) {
+	_, _ = fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
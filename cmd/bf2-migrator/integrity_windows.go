@@ -0,0 +1,113 @@
+package main
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lxn/walk"
+)
+
+const (
+	resourceDirectoryLength = 16
+	resourceEntryLength     = 8
+	resourceNameIsString    = 0x80000000
+	resourceTypeGroupIcon   = 14
+	resourceTypeVersion     = 16
+)
+
+// Sections the Go linker always emits, which packers such as UPX replace with their own
+var requiredSections = []string{".text", ".rdata", ".data"}
+
+// verifyExecutable checks that the running executable is complete, has not been re-packed and (if it was built with
+// resources) contains its icon and version information
+func verifyExecutable() error {
+	path, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine executable path: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open executable: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read executable size: %w", err)
+	}
+
+	executable, err := pe.NewFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse executable (it may be truncated): %w", err)
+	}
+
+	for _, section := range executable.Sections {
+		if end := int64(section.Offset) + int64(section.Size); end > stat.Size() {
+			return fmt.Errorf("executable is truncated, section %s ends at %d but file is %d bytes", section.Name, end, stat.Size())
+		}
+	}
+
+	for _, name := range requiredSections {
+		if executable.Section(name) == nil {
+			return fmt.Errorf("executable is missing section %s", name)
+		}
+	}
+
+	// Development builds (without go generate) do not contain any resources
+	rsrc := executable.Section(".rsrc")
+	if rsrc == nil {
+		return nil
+	}
+	data, err := rsrc.Data()
+	if err != nil {
+		return fmt.Errorf("failed to read executable resources: %w", err)
+	}
+	types, err := resourceTypes(data)
+	if err != nil {
+		return err
+	}
+	if !types[resourceTypeGroupIcon] {
+		return errors.New("executable is missing its icon")
+	}
+	if !types[resourceTypeVersion] {
+		return errors.New("executable is missing its version information")
+	}
+
+	return nil
+}
+
+// resourceTypes returns the (numeric) types listed in the root of the resource directory
+func resourceTypes(data []byte) (map[uint32]bool, error) {
+	errMalformed := errors.New("executable resources are malformed")
+	if len(data) < resourceDirectoryLength {
+		return nil, errMalformed
+	}
+
+	named := int(binary.LittleEndian.Uint16(data[12:]))
+	ids := int(binary.LittleEndian.Uint16(data[14:]))
+	if len(data) < resourceDirectoryLength+(named+ids)*resourceEntryLength {
+		return nil, errMalformed
+	}
+
+	types := make(map[uint32]bool, ids)
+	for i := 0; i < named+ids; i++ {
+		name := binary.LittleEndian.Uint32(data[resourceDirectoryLength+i*resourceEntryLength:])
+		if name&resourceNameIsString == 0 {
+			types[name] = true
+		}
+	}
+
+	return types, nil
+}
+
+func failStartup(message string) {
+	// Release builds have no console, so the message needs to be shown in a message box
+	walk.MsgBox(nil, "Error", message, walk.MsgBoxIconError)
+	os.Exit(1)
+}
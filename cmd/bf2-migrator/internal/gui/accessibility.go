@@ -0,0 +1,14 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/win"
+)
+
+// showKeyboardCues makes Windows always draw focus rectangles and accelerator underlines in the form, rather than
+// hiding them until the user first presses Alt/Tab (so keyboard-only users can see what has focus right away)
+func showKeyboardCues(form walk.Form) {
+	win.SendMessage(form.Handle(), win.WM_CHANGEUISTATE, uintptr(win.MAKELONG(win.UIS_CLEAR, win.UISF_HIDEFOCUS|win.UISF_HIDEACCEL)), 0)
+}
@@ -0,0 +1,134 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// accountMatrixRow is a profile along with a short status of its account on each provider
+type accountMatrixRow struct {
+	Profile  string
+	Statuses []string
+}
+
+// accountMatrixModel shows one row per profile and one column per provider
+type accountMatrixModel struct {
+	walk.TableModelBase
+	rows []accountMatrixRow
+}
+
+func (m *accountMatrixModel) RowCount() int {
+	return len(m.rows)
+}
+
+func (m *accountMatrixModel) Value(row, col int) interface{} {
+	if col == 0 {
+		return m.rows[row].Profile
+	}
+	return m.rows[row].Statuses[col-1]
+}
+
+// buildAccountMatrix looks up the account of each multiplayer profile on all providers, calling progress before
+// looking up each profile
+func buildAccountMatrix(h game.Handler, c client, profiles []game.Profile, options []providerCBOption[gamespy.Provider], progress func(current, total int)) []accountMatrixRow {
+	providers := make([]gamespy.Provider, 0, len(options))
+	for _, option := range options {
+		providers = append(providers, option.Value)
+	}
+
+	rows := make([]accountMatrixRow, 0, len(profiles))
+	for i, profile := range profiles {
+		progress(i, len(profiles))
+		if profile.Type != game.ProfileTypeMultiplayer {
+			continue
+		}
+
+		row := accountMatrixRow{Profile: profile.Name, Statuses: make([]string, 0, len(options))}
+		creds, err := readProfileCredentials(h, profile.Key)
+		if err != nil {
+			for range options {
+				row.Statuses = append(row.Statuses, "Unreadable profile")
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		results := c.GetNicksAll(providers, creds.Email, creds.Password)
+		for _, provider := range providers {
+			row.Statuses = append(row.Statuses, summarizeNicksResult(results[provider], creds.Nick))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// summarizeNicksResult returns a short status of the account/nick on a provider (see describeNicksResult)
+func summarizeNicksResult(result gamespy.NicksResult, nick string) string {
+	switch getAccountStatus(result, nick) {
+	case accountStatusNoAccount:
+		return "No account"
+	case accountStatusBadPassword:
+		return "Wrong password"
+	case accountStatusMigrated:
+		return "Migrated"
+	case accountStatusNickMissing:
+		return "Nick missing"
+	default:
+		return "Lookup failed"
+	}
+}
+
+// showAccountMatrix shows for each profile whether (and how far) it has been migrated to each provider
+func showAccountMatrix(owner walk.Form, options []providerCBOption[gamespy.Provider], rows []accountMatrixRow) {
+	var dlg *walk.Dialog
+	var okPB *walk.PushButton
+
+	columns := []declarative.TableViewColumn{{Title: "Profile", Width: 140}}
+	for _, option := range options {
+		columns = append(columns, declarative.TableViewColumn{Title: option.Name, Width: 110})
+	}
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Where are my accounts?",
+		DefaultButton: &okPB,
+		CancelButton:  &okPB,
+		MinSize:       declarative.Size{Width: 520, Height: 300},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: "Migrated: nick is set up on the provider\nNick missing: account exists, but the nick still needs to be migrated\nNo account: profile still needs to be migrated",
+			},
+			declarative.TableView{
+				Name:             "Accounts",
+				Columns:          columns,
+				Model:            &accountMatrixModel{rows: rows},
+				AlternatingRowBG: true,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return
+	}
+
+	showKeyboardCues(dlg)
+	dlg.Run()
+}
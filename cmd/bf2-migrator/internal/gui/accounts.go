@@ -0,0 +1,81 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+
+	"github.com/cetteup/conman/pkg/config"
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/cetteup/conman/pkg/game/bf2"
+)
+
+type configWriter interface {
+	WriteConfigFile(c *config.Config) error
+}
+
+// updateProfileLogin stores the email and password of the provider account the profile's nick was attached to in the
+// profile's Profile.con, so the game logs in using the account's credentials
+func updateProfileLogin(h game.Handler, profileKey string, email, password string) error {
+	return updateProfileCon(h, profileKey, func(profileCon *config.Config) error {
+		return setProfileLogin(profileCon, email, password)
+	})
+}
+
+// updateProfileNick changes the profile's nick (both the GameSpy nick and the nick shown in-game), e.g. since the
+// original nick is taken on the provider
+func updateProfileNick(h game.Handler, profileKey string, nick string) error {
+	return updateProfileCon(h, profileKey, func(profileCon *config.Config) error {
+		setProfileNick(profileCon, nick)
+		return nil
+	})
+}
+
+// updateProfileCredentials stores the nick, email and password in the profile's Profile.con, so the game logs in to
+// the account the credentials belong to
+func updateProfileCredentials(h game.Handler, profileKey string, creds credentials) error {
+	return updateProfileCon(h, profileKey, func(profileCon *config.Config) error {
+		setProfileNick(profileCon, creds.Nick)
+		return setProfileLogin(profileCon, creds.Email, creds.Password)
+	})
+}
+
+// updateProfileCon reads the profile's Profile.con, applies update and writes it back
+func updateProfileCon(h game.Handler, profileKey string, update func(profileCon *config.Config) error) error {
+	w, ok := h.(configWriter)
+	if !ok {
+		return fmt.Errorf("handler does not support writing config files")
+	}
+
+	profileCon, err := readProfileCon(h, profileKey)
+	if err != nil {
+		return fmt.Errorf("failed to read profile config file: %w", err)
+	}
+
+	if err = update(profileCon); err != nil {
+		return err
+	}
+
+	if err = w.WriteConfigFile(profileCon); err != nil {
+		return fmt.Errorf("failed to write profile config file: %w", err)
+	}
+
+	return nil
+}
+
+func setProfileLogin(profileCon *config.Config, email, password string) error {
+	encrypted, err := bf2.EncryptProfileConPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt profile password: %w", err)
+	}
+
+	profileCon.SetValue(bf2.ProfileConKeyEmail, *config.NewQuotedValue(email))
+	profileCon.SetValue(bf2.ProfileConKeyPassword, *config.NewQuotedValue(encrypted))
+
+	return nil
+}
+
+func setProfileNick(profileCon *config.Config, nick string) {
+	profileCon.SetValue(bf2.ProfileConKeyGamespyNick, *config.NewQuotedValue(nick))
+	profileCon.SetValue(bf2.ProfileConKeyNick, *config.NewQuotedValue(nick))
+}
@@ -0,0 +1,102 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/conman/pkg/config"
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/cetteup/conman/pkg/game/bf2"
+	"github.com/cetteup/conman/pkg/handler"
+)
+
+const (
+	// Battlefield 2142 profiles are listed alongside Battlefield 2 profiles, so prefix their keys to tell them apart
+	bf2142ProfileKeyPrefix  = "bf2142:"
+	bf2142ProfileNameSuffix = " (BF2142)"
+	bf2142BaseDirName       = "Battlefield 2142"
+)
+
+// getBF2142Profiles reads the Battlefield 2142 profiles. conman only supports Battlefield 2, but both games use the same
+// profile layout and Profile.con format, with 2142's profiles folder being next to Battlefield 2's.
+func getBF2142Profiles(h game.Handler) ([]game.Profile, error) {
+	dir, err := buildBF2142ProfilesFolderPath(h)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// Battlefield 2142 is not installed/has never been started
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var profiles []game.Profile
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == bf2.DefaultProfileKey {
+			continue
+		}
+
+		profileCon, err2 := h.ReadConfigFile(filepath.Join(dir, entry.Name(), string(bf2.ProfileConfigFileProfileCon)))
+		if err2 != nil {
+			// Not a profile folder
+			continue
+		}
+
+		name, err2 := profileCon.GetValue(bf2.ProfileConKeyName)
+		if err2 != nil {
+			return nil, err2
+		}
+
+		profileType := game.ProfileTypeMultiplayer
+		// Singleplayer profiles do not contain an email address
+		if !profileCon.HasKey(bf2.ProfileConKeyEmail) {
+			profileType = game.ProfileTypeSingleplayer
+		}
+
+		profiles = append(profiles, game.Profile{
+			Key:  bf2142ProfileKeyPrefix + entry.Name(),
+			Name: name.String() + bf2142ProfileNameSuffix,
+			Type: profileType,
+		})
+	}
+
+	return profiles, nil
+}
+
+// readProfileCon reads the Profile.con of the Battlefield 2 or (prefixed key) Battlefield 2142 profile
+func readProfileCon(h game.Handler, profileKey string) (*config.Config, error) {
+	return readProfileConfigFile(h, profileKey, bf2.ProfileConfigFileProfileCon)
+}
+
+// readProfileConfigFile reads a config file of the Battlefield 2 or (prefixed key) Battlefield 2142 profile
+func readProfileConfigFile(h game.Handler, profileKey string, configFile bf2.ProfileConfigFile) (*config.Config, error) {
+	if !strings.HasPrefix(profileKey, bf2142ProfileKeyPrefix) {
+		return bf2.ReadProfileConfigFile(h, profileKey, configFile)
+	}
+
+	dir, err := buildBF2142ProfilesFolderPath(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.ReadConfigFile(filepath.Join(dir, strings.TrimPrefix(profileKey, bf2142ProfileKeyPrefix), string(configFile)))
+}
+
+func buildBF2142ProfilesFolderPath(h game.Handler) (string, error) {
+	bf2Dir, err := h.BuildProfilesFolderPath(handler.GameBf2)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine profiles folder: %w", err)
+	}
+
+	// <Documents>\Battlefield 2\Profiles -> <Documents>\Battlefield 2142\Profiles
+	return filepath.Join(filepath.Dir(filepath.Dir(bf2Dir)), bf2142BaseDirName, filepath.Base(bf2Dir)), nil
+}
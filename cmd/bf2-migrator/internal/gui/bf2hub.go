@@ -0,0 +1,186 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-ps"
+	"go.uber.org/multierr"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	bf2hubSystemsKeyPath = "SOFTWARE\\BF2Hub Systems"
+)
+
+// DLLs the BF2Hub Client copies into the installation folder
+var bf2hubDLLs = []string{"bf2hbc.dll", "bf2hub.dll"}
+
+// Registry keys containing programs started on login
+var autostartLocations = []registryLocation{
+	{source: "user", key: registry.CURRENT_USER, path: "SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Run"},
+	{source: "machine", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Run"},
+	{source: "machine", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\WOW6432Node\\Microsoft\\Windows\\CurrentVersion\\Run"},
+}
+
+// autostartEntry is a registry value starting a program on login
+type autostartEntry struct {
+	location  registryLocation
+	valueName string
+}
+
+// bf2hubComponents are the parts of the BF2Hub Client found on the system, which keep re-patching the executables
+type bf2hubComponents struct {
+	Processes   map[int]string
+	RegistryKey bool
+	Autostart   []autostartEntry
+	DLLs        []string
+}
+
+func (c bf2hubComponents) Empty() bool {
+	return len(c.Processes) == 0 && !c.RegistryKey && len(c.Autostart) == 0 && len(c.DLLs) == 0
+}
+
+// Describe lists the components, one per line
+func (c bf2hubComponents) Describe() string {
+	var lines []string
+	for pid, executable := range c.Processes {
+		lines = append(lines, fmt.Sprintf("Running process: %s (PID %d)", executable, pid))
+	}
+	if c.RegistryKey {
+		lines = append(lines, fmt.Sprintf("Registry key: HKEY_CURRENT_USER\\%s", bf2hubSystemsKeyPath))
+	}
+	for _, entry := range c.Autostart {
+		lines = append(lines, fmt.Sprintf("Autostart entry (%s): %s", entry.location.source, entry.valueName))
+	}
+	for _, dll := range c.DLLs {
+		lines = append(lines, fmt.Sprintf("DLL: %s", dll))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// findBF2HubComponents looks for the BF2Hub Client's processes, settings, autostart entries and DLLs (in dir, if set)
+func findBF2HubComponents(r registryRepository, dir string) (bf2hubComponents, error) {
+	c := bf2hubComponents{
+		Processes: map[int]string{},
+	}
+
+	processes, err := ps.Processes()
+	if err != nil {
+		return bf2hubComponents{}, fmt.Errorf("failed to retrieve process list: %w", err)
+	}
+	for _, process := range processes {
+		if strings.EqualFold(process.Executable(), bf2hubExecutableName) {
+			c.Processes[process.Pid()] = process.Executable()
+		}
+	}
+
+	err = r.OpenKey(registry.CURRENT_USER, bf2hubSystemsKeyPath, registry.QUERY_VALUE, func(key registry.Key) error {
+		return nil
+	})
+	if err == nil {
+		c.RegistryKey = true
+	} else if !errors.Is(err, registry.ErrNotExist) {
+		return bf2hubComponents{}, fmt.Errorf("failed to check for BF2Hub Client settings: %w", err)
+	}
+
+	for _, l := range autostartLocations {
+		err = r.OpenKey(l.key, l.path, registry.QUERY_VALUE, func(key registry.Key) error {
+			names, err2 := key.ReadValueNames(-1)
+			if err2 != nil {
+				return err2
+			}
+			for _, name := range names {
+				value, _, err3 := key.GetStringValue(name)
+				if err3 == nil && strings.Contains(strings.ToLower(value), bf2hubExecutableName) {
+					c.Autostart = append(c.Autostart, autostartEntry{location: l, valueName: name})
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return bf2hubComponents{}, fmt.Errorf("failed to check autostart entries: %w", err)
+		}
+	}
+
+	if dir != "" {
+		for _, dll := range bf2hubDLLs {
+			path := filepath.Join(dir, dll)
+			if _, err = os.Stat(path); err == nil {
+				c.DLLs = append(c.DLLs, path)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// removeBF2HubComponents stops the BF2Hub Client and removes its settings, autostart entries and DLLs, removing as
+// many of them as possible rather than stopping at the first failure
+func removeBF2HubComponents(r registryRepository, c bf2hubComponents) error {
+	var err error
+	for pid, executable := range c.Processes {
+		if err2 := killProcess(pid); err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to stop %s: %w", executable, err2))
+		}
+	}
+	if len(c.Processes) > 0 {
+		// The client may still hold the DLLs open, so wait for it to exit before deleting them
+		if err2 := waitForProcessesToExit(c.Processes, func(running map[int]string, elapsed int) bool {
+			return elapsed < processExitTimeout
+		}); err2 != nil {
+			err = multierr.Append(err, err2)
+		}
+	}
+
+	if c.RegistryKey {
+		if err2 := deleteKeyRecursive(r, registry.CURRENT_USER, bf2hubSystemsKeyPath); err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to remove BF2Hub Client settings: %w", err2))
+		}
+	}
+
+	for _, entry := range c.Autostart {
+		err2 := r.OpenKey(entry.location.key, entry.location.path, registry.SET_VALUE, func(key registry.Key) error {
+			return key.DeleteValue(entry.valueName)
+		})
+		if err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to remove autostart entry %s: %w", entry.valueName, err2))
+		}
+	}
+
+	for _, dll := range c.DLLs {
+		if err2 := os.Remove(dll); err2 != nil && !errors.Is(err2, os.ErrNotExist) {
+			err = multierr.Append(err, fmt.Errorf("failed to delete %s: %w", filepath.Base(dll), err2))
+		}
+	}
+
+	return err
+}
+
+// deleteKeyRecursive deletes the key along with all of its subkeys (registry.DeleteKey only deletes empty keys)
+func deleteKeyRecursive(r registryRepository, k registry.Key, path string) error {
+	var names []string
+	err := r.OpenKey(k, path, registry.ENUMERATE_SUB_KEYS, func(key registry.Key) error {
+		var err error
+		names, err = key.ReadSubKeyNames(-1)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err = deleteKeyRecursive(r, k, path+"\\"+name); err != nil {
+			return err
+		}
+	}
+
+	return registry.DeleteKey(k, path)
+}
@@ -0,0 +1,113 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// promptCredentials asks the user to enter the nick, email and password to migrate to the provider, for users whose
+// local profile is gone (e.g. after reinstalling Windows). Returns false if the user canceled the dialog.
+func promptCredentials(owner walk.Form, providerName string, provider gamespy.Provider) (migrate.Credentials, bool) {
+	var dlg *walk.Dialog
+	var okPB, cancelPB *walk.PushButton
+	var nickLE, emailLE, passwordLE *walk.LineEdit
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         fmt.Sprintf("Migrate to %s", providerName),
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 360},
+		Layout:        declarative.Grid{Columns: 2},
+		Children: []declarative.Widget{
+			declarative.Label{
+				ColumnSpan: 2,
+				Text:       "Enter the credentials of your original GameSpy account",
+			},
+			declarative.Label{Text: "Nick"},
+			declarative.LineEdit{
+				AssignTo: &nickLE,
+				Name:     "Nick",
+			},
+			declarative.Label{Text: "Email"},
+			declarative.LineEdit{
+				AssignTo: &emailLE,
+				Name:     "Email",
+			},
+			declarative.Label{Text: "Password"},
+			declarative.LineEdit{
+				AssignTo:     &passwordLE,
+				Name:         "Password",
+				PasswordMode: true,
+			},
+			declarative.Composite{
+				ColumnSpan: 2,
+				Layout:     declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							if problem := checkCredentials(provider, nickLE.Text(), emailLE.Text(), passwordLE.Text()); problem != "" {
+								walk.MsgBox(dlg, "Error", problem, walk.MsgBoxIconError)
+								return
+							}
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return migrate.Credentials{}, false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return migrate.Credentials{}, false
+	}
+
+	return migrate.Credentials{
+		Nick:     nickLE.Text(),
+		Email:    emailLE.Text(),
+		Password: passwordLE.Text(),
+	}, true
+}
+
+// checkCredentials returns a description of the first problem with manually entered credentials (including values the
+// provider would reject), or an empty string if none was found
+func checkCredentials(provider gamespy.Provider, nick, email, password string) string {
+	switch {
+	case nick == "":
+		return "Enter a nick"
+	case strings.TrimSpace(nick) != nick:
+		return "The nick must not start or end with whitespace"
+	case email == "":
+		return "Enter an email address"
+	case !isEmailSyntaxValid(email):
+		return fmt.Sprintf("%q is not a valid email address", email)
+	case password == "":
+		return "Enter a password"
+	}
+	if err := gamespy.ValidateNewUser(email, password, nick); err != nil {
+		return describeError(err)
+	}
+	return ""
+}
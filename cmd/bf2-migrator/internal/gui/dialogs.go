@@ -0,0 +1,75 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+)
+
+// promptText shows a modal dialog asking the user to enter a single line of text,
+// returning false if the user canceled the dialog
+func promptText(owner walk.Form, title, label, initial string, password bool) (string, bool) {
+	var dlg *walk.Dialog
+	var textLE *walk.LineEdit
+	var okPB, cancelPB *walk.PushButton
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         title,
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 300},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: label,
+			},
+			declarative.LineEdit{
+				AssignTo:     &textLE,
+				Text:         initial,
+				PasswordMode: password,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return "", false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return "", false
+	}
+
+	return textLE.Text(), true
+}
+
+// dialogPrompter prompts for credentials using modal dialogs
+type dialogPrompter struct {
+	owner walk.Form
+}
+
+func (p dialogPrompter) PromptPassword(title, message string) (string, bool) {
+	password, ok := promptText(p.owner, title, message, "", true)
+	return password, ok && password != ""
+}
@@ -0,0 +1,120 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// Folders the BF2Hub Client is installed to by default, which contain the BF2Hub DLLs
+var providerDLLSearchDirs = []string{
+	filepath.Join(os.Getenv("ProgramFiles(x86)"), "BF2Hub Client"),
+	filepath.Join(os.Getenv("ProgramFiles"), "BF2Hub Client"),
+}
+
+// deployDLLs copies the DLLs required by the executables in dir into it, unless they are already present. DLLs not found in
+// any of the known folders are located using locate, which should return false if the user cancelled.
+func deployDLLs(dir string, dlls map[string]string, locate func(dll string) (string, bool)) error {
+	for executable, dll := range dlls {
+		// Server executable is optional and not included with some installers for the game
+		if _, err := os.Stat(filepath.Join(dir, executable)); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		target := filepath.Join(dir, dll)
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+
+		source, ok := findDLL(dll)
+		if !ok {
+			source, ok = locate(dll)
+			if !ok {
+				return fmt.Errorf("%s is required by %s, but was not located", dll, executable)
+			}
+		}
+
+		if err := copyFile(source, target); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", dll, err)
+		}
+	}
+
+	return nil
+}
+
+// findBrokenDLLs returns a description of each of the given DLLs in dir which is outdated or corrupt
+func findBrokenDLLs(catalog patchable.Catalog, dir string, dlls map[string]string) ([]string, error) {
+	checks, err := catalog.CheckDLLs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var broken []string
+	for _, check := range checks {
+		if !check.Broken() {
+			continue
+		}
+		for _, dll := range dlls {
+			if strings.EqualFold(dll, check.FileName) {
+				broken = append(broken, check.String())
+				break
+			}
+		}
+	}
+
+	return broken, nil
+}
+
+// selectDLLs returns the DLLs required by the given patchables' executables
+func selectDLLs(dlls map[string]string, patchables []patch.Patchable) map[string]string {
+	selected := make(map[string]string, len(dlls))
+	for _, p := range patchables {
+		for executable, dll := range dlls {
+			if strings.EqualFold(executable, p.GetFileName()) {
+				selected[executable] = dll
+			}
+		}
+	}
+	return selected
+}
+
+func findDLL(dll string) (string, bool) {
+	for _, dir := range providerDLLSearchDirs {
+		path := filepath.Join(dir, dll)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func copyFile(source, target string) (err error) {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Append(err, dst.Close())
+	}()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
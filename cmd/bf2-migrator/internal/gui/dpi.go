@@ -0,0 +1,20 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/win"
+)
+
+// getScreenSize returns the size of the primary screen in 1/96 inch units, which walk uses for all window bounds.
+// Since the migrator is DPI aware, the system reports the size in physical pixels, which only match at 100% scaling.
+func getScreenSize() (width, height int) {
+	hdc := win.GetDC(0)
+	defer win.ReleaseDC(0, hdc)
+	dpi := int(win.GetDeviceCaps(hdc, win.LOGPIXELSY))
+
+	width = walk.IntTo96DPI(int(win.GetSystemMetrics(win.SM_CXSCREEN)), dpi)
+	height = walk.IntTo96DPI(int(win.GetSystemMetrics(win.SM_CYSCREEN)), dpi)
+	return width, height
+}
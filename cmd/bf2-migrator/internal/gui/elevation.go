@@ -0,0 +1,55 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/lxn/win"
+	"golang.org/x/sys/windows"
+)
+
+// isElevated returns whether the migrator is running with administrator privileges
+func isElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// isDirWritable returns whether new files (e.g. provider DLLs) can be created in dir
+func isDirWritable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".bf2-migrator-")
+	if err != nil {
+		// Missing folders are reported by the patch itself
+		return !errors.Is(err, os.ErrPermission)
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return true
+}
+
+// restartElevated starts another instance of the migrator with administrator privileges (triggering a UAC prompt),
+// opening the given installation folder. The caller is expected to close the current instance if no error is returned.
+func restartElevated(hwnd win.HWND, dir string, patchGame, patchServer, readOnly bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine migrator executable: %w", err)
+	}
+
+	args := []string{
+		fmt.Sprintf("-game=%t", patchGame),
+		fmt.Sprintf("-server=%t", patchServer),
+		fmt.Sprintf("-read-only=%t", readOnly),
+	}
+	if dir != "" {
+		args = append(args, fmt.Sprintf("-dir \"%s\"", strings.TrimSuffix(dir, "\\")))
+	}
+
+	if !win.ShellExecute(hwnd, syscall.StringToUTF16Ptr("runas"), syscall.StringToUTF16Ptr(executable), syscall.StringToUTF16Ptr(strings.Join(args, " ")), nil, win.SW_SHOWNORMAL) {
+		return fmt.Errorf("failed to restart as administrator (elevation prompt may have been declined)")
+	}
+
+	return nil
+}
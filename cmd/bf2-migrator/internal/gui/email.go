@@ -0,0 +1,45 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// Mail domains of discontinued services, which can no longer receive confirmation emails
+var defunctEmailDomains = map[string]bool{
+	"netscape.net":   true,
+	"netscape.com":   true,
+	"compuserve.com": true,
+	"hotpop.com":     true,
+	"excite.com":     true,
+	"lycos.co.uk":    true,
+	"arcor.de":       true,
+	"freenet.co.uk":  true,
+}
+
+// checkEmail returns a description of any problem with the given email address, or an empty string if none was found
+func checkEmail(email string) string {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Sprintf("%q is not a valid email address", email)
+	}
+
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	if defunctEmailDomains[domain] {
+		return fmt.Sprintf("The mail service of %q has been discontinued", domain)
+	}
+
+	// Domains without any mail exchanger or address cannot receive mail
+	if mxs, err := net.LookupMX(domain); err == nil && len(mxs) > 0 {
+		return ""
+	}
+	if _, err = net.LookupHost(domain); err != nil {
+		return fmt.Sprintf("%q does not seem to accept emails (anymore)", domain)
+	}
+
+	return ""
+}
@@ -0,0 +1,120 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+	"github.com/rs/zerolog/log"
+)
+
+// errorDetail is context shown alongside an error (e.g. the folder or provider involved)
+type errorDetail struct {
+	Name  string
+	Value string
+}
+
+// showError shows the message along with the error's chain of causes and the details in a dialog, offering to copy
+// everything formatted for pasting into a GitHub issue or Discord (message boxes truncate long messages)
+func showError(owner walk.Form, message string, err error, details ...errorDetail) {
+	log.Error().Err(err).Msg(message)
+
+	text := formatErrorDetails(message, err, details)
+
+	var dlg *walk.Dialog
+	var okPB *walk.PushButton
+	if err2 := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Error",
+		DefaultButton: &okPB,
+		CancelButton:  &okPB,
+		MinSize:       declarative.Size{Width: 480, Height: 280},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: message,
+			},
+			declarative.TextEdit{
+				Name:     "Error details",
+				Text:     strings.ReplaceAll(text, "\n", "\r\n"),
+				ReadOnly: true,
+				VScroll:  true,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						Text: "&Copy details",
+						OnClicked: func() {
+							if err3 := walk.Clipboard().SetText(text); err3 != nil {
+								walk.MsgBox(dlg, "Error", fmt.Sprintf("Failed to copy details to clipboard: %s", err3.Error()), walk.MsgBoxIconError)
+							}
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err2 != nil {
+		// Still show the error, just without the details
+		walk.MsgBox(owner, "Error", message, walk.MsgBoxIconError)
+		return
+	}
+
+	showKeyboardCues(dlg)
+	dlg.Run()
+}
+
+// formatErrorDetails formats the message, the error's chain of causes and the details as a Markdown code block
+func formatErrorDetails(message string, err error, details []errorDetail) string {
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	sb.WriteString(fmt.Sprintf("BF2 migrator %s (%s/%s)\n", version, runtime.GOOS, runtime.GOARCH))
+	sb.WriteString(fmt.Sprintf("Error: %s\n", message))
+
+	if causes := unwrapCauses(err); len(causes) > 0 {
+		sb.WriteString("Causes:\n")
+		for i, cause := range causes {
+			sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, cause))
+		}
+	}
+
+	if len(details) > 0 {
+		sb.WriteString("Context:\n")
+		for _, d := range details {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", d.Name, d.Value))
+		}
+	}
+
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// unwrapCauses lists the messages of each error in the chain, each without the message of the error it wraps
+func unwrapCauses(err error) []string {
+	var causes []string
+	for err != nil {
+		msg := err.Error()
+		next := errors.Unwrap(err)
+		if next != nil {
+			msg = strings.TrimSuffix(strings.TrimSuffix(msg, next.Error()), ": ")
+		}
+		if msg != "" {
+			causes = append(causes, msg)
+		}
+		err = next
+	}
+	return causes
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package gui
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+type exportedNick struct {
+	Provider   string `json:"provider"`
+	Email      string `json:"email"`
+	Nick       string `json:"nick"`
+	UniqueNick string `json:"uniquenick"`
+}
+
+// exportNicks writes the given nicks to path, using JSON for .json files and CSV for anything else
+func exportNicks(path string, provider string, email string, nicks []gamespy.NickDTO) error {
+	rows := make([]exportedNick, 0, len(nicks))
+	for _, nick := range nicks {
+		rows = append(rows, exportedNick{
+			Provider:   provider,
+			Email:      email,
+			Nick:       nick.Nick,
+			UniqueNick: nick.UniqueNick,
+		})
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	records := [][]string{{"provider", "email", "nick", "uniquenick"}}
+	for _, row := range rows {
+		records = append(records, []string{row.Provider, row.Email, row.Nick, row.UniqueNick})
+	}
+	if err = w.WriteAll(records); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
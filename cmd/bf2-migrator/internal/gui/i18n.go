@@ -0,0 +1,113 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+type language string
+
+const (
+	languageEnglish language = "en"
+	languageRussian language = "ru"
+)
+
+const (
+	msgProviderErrorWithDetails = "providerErrorWithDetails"
+)
+
+var messages = map[language]map[string]string{
+	languageEnglish: {
+		msgProviderErrorWithDetails: "%s\n\nOriginal message: %s",
+	},
+	languageRussian: {
+		msgProviderErrorWithDetails: "%s\n\nИсходное сообщение: %s",
+	},
+}
+
+var (
+	userLanguage     language
+	userLanguageOnce sync.Once
+	// Language chosen in the settings, taking precedence over the detected one (empty if none was chosen)
+	chosenLanguage   language
+	chosenLanguageMu sync.Mutex
+)
+
+// setLanguage overrides the detected language with the one chosen in the settings (empty to use the detected one)
+func setLanguage(l language) {
+	chosenLanguageMu.Lock()
+	defer chosenLanguageMu.Unlock()
+	chosenLanguage = l
+}
+
+// currentLanguage returns the language chosen in the settings, else the detected one
+func currentLanguage() language {
+	userLanguageOnce.Do(func() {
+		userLanguage = detectLanguage()
+	})
+
+	chosenLanguageMu.Lock()
+	defer chosenLanguageMu.Unlock()
+	if chosenLanguage != "" {
+		return chosenLanguage
+	}
+	return userLanguage
+}
+
+// tr returns the message in the user's language, falling back to English for untranslated messages
+func tr(key string, args ...interface{}) string {
+	msg, ok := messages[currentLanguage()][key]
+	if !ok {
+		msg = messages[languageEnglish][key]
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func detectLanguage() language {
+	preferred, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil {
+		return languageEnglish
+	}
+
+	for _, tag := range preferred {
+		// Tags are formatted like "ru-RU", only the language itself is relevant
+		l := language(strings.ToLower(strings.SplitN(tag, "-", 2)[0]))
+		if _, ok := messages[l]; ok {
+			return l
+		}
+	}
+
+	return languageEnglish
+}
+
+// describeError returns a user-friendly, localized description of errors reported by providers, else the error itself
+func describeError(err error) string {
+	var validationErr *gamespy.ValidationError
+	if errors.As(err, &validationErr) {
+		return fmt.Sprintf("The %s %s", validationErr.Field, validationErr.Reason)
+	}
+
+	var providerErr *gamespy.ProviderError
+	if !errors.As(err, &providerErr) {
+		return err.Error()
+	}
+
+	description, ok := gamespy.DescribeErrorCode(providerErr.Code, gamespy.Language(currentLanguage()))
+	if !ok {
+		return err.Error()
+	}
+
+	return tr(msgProviderErrorWithDetails, description, providerErr.Error())
+}
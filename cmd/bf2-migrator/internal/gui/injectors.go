@@ -0,0 +1,45 @@
+//go:build windows
+
+package gui
+
+import (
+	"os"
+	"strings"
+)
+
+// Proxy/injector DLLs which are loaded by the game from its install directory, mapped to a short description.
+// These commonly hook networking or rely on fixed offsets and tend to break once the executable is patched.
+var knownInjectorDLLs = map[string]string{
+	"d3d9.dll":      "Direct3D proxy (ReShade, ENB or overlay)",
+	"dxgi.dll":      "DXGI proxy (ReShade or overlay)",
+	"dinput8.dll":   "DirectInput proxy (ASI loader or trainer)",
+	"dsound.dll":    "DirectSound proxy (ASI loader)",
+	"winmm.dll":     "WinMM proxy (ASI loader)",
+	"version.dll":   "Version proxy (ASI loader)",
+	"wsock32.dll":   "Winsock proxy (network hook)",
+	"ws2_32.dll":    "Winsock proxy (network hook)",
+	"bf2hook.dll":   "BF2 hook library",
+	"ddraw.dll":     "DirectDraw proxy (wrapper or overlay)",
+	"dxwrapper.dll": "DxWrapper",
+}
+
+// detectInjectorDLLs returns descriptions of known injector DLLs present in dir
+func detectInjectorDLLs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if description, ok := knownInjectorDLLs[strings.ToLower(entry.Name())]; ok {
+			found = append(found, entry.Name()+": "+description)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,258 @@
+//go:build windows
+
+package gui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// installation is a game installation folder, along with where it was found
+type installation struct {
+	Dir    string
+	Source string
+}
+
+// Label is displayed when choosing between installations
+func (i installation) Label() string {
+	return i.Dir + " (" + i.Source + ")"
+}
+
+type registryLocation struct {
+	source    string
+	key       registry.Key
+	path      string
+	valueName string
+}
+
+// Registry values pointing to an installation folder, in the order they were previously used to detect the folder
+var installRegistryLocations = []registryLocation{
+	{source: "EA", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\WOW6432Node\\Electronic Arts\\EA Games\\Battlefield 2", valueName: "InstallDir"},
+	{source: "EA", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\Electronic Arts\\EA Games\\Battlefield 2", valueName: "InstallDir"},
+	{source: "BF2Hub", key: registry.CURRENT_USER, path: "SOFTWARE\\BF2Hub Systems\\BF2Hub Client", valueName: "bf2Dir"},
+}
+
+// Registry keys containing a subkey per installed game, each with a value pointing to the installation folder
+var launcherRegistryLocations = []registryLocation{
+	{source: "GOG", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\WOW6432Node\\GOG.com\\Games", valueName: "path"},
+	{source: "GOG", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\GOG.com\\Games", valueName: "path"},
+	{source: "EA App", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\WOW6432Node\\EA Games", valueName: "Install Dir"},
+	{source: "EA App", key: registry.LOCAL_MACHINE, path: "SOFTWARE\\EA Games", valueName: "Install Dir"},
+}
+
+// Folders commonly used for (repacked/community) installations, relative to each drive/program files folder
+var commonInstallDirs = []string{
+	filepath.Join("EA Games", "Battlefield 2"),
+	filepath.Join("Origin Games", "Battlefield 2"),
+	filepath.Join("Games", "Battlefield 2"),
+	"Battlefield 2",
+}
+
+// findInstallations returns every installation folder found in the registry (EA, BF2Hub, GOG, EA App), Steam
+// libraries, EA App download folders and common folders which contains any of the patchables' game or server executables
+func findInstallations(r registryRepository, patchables []patch.Patchable) []installation {
+	var candidates []installation
+	for _, l := range installRegistryLocations {
+		if dir, err := readRegistryString(r, l.key, l.path, l.valueName); err == nil {
+			candidates = append(candidates, installation{Dir: dir, Source: l.source})
+		}
+	}
+
+	for _, l := range launcherRegistryLocations {
+		for _, dir := range readSubKeyStrings(r, l.key, l.path, l.valueName) {
+			candidates = append(candidates, installation{Dir: dir, Source: l.source})
+		}
+	}
+
+	candidates = append(candidates, findSteamInstallations(r)...)
+	candidates = append(candidates, findEAAppInstallations()...)
+
+	var roots []string
+	for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+		if dir := os.Getenv(env); dir != "" {
+			roots = append(roots, dir)
+		}
+	}
+	for drive := 'C'; drive <= 'Z'; drive++ {
+		roots = append(roots, string(drive)+":\\")
+	}
+	for _, root := range roots {
+		for _, dir := range commonInstallDirs {
+			candidates = append(candidates, installation{Dir: filepath.Join(root, dir), Source: "common folder"})
+		}
+	}
+
+	installations := make([]installation, 0, len(candidates))
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		c.Dir = filepath.Clean(c.Dir)
+		key := strings.ToLower(c.Dir)
+		if seen[key] || !containsExecutable(patchables, c.Dir) {
+			continue
+		}
+		seen[key] = true
+		installations = append(installations, c)
+	}
+
+	return installations
+}
+
+func readRegistryString(r registryRepository, k registry.Key, path, valueName string) (string, error) {
+	var value string
+	err := r.OpenKey(k, path, registry.QUERY_VALUE, func(key registry.Key) error {
+		var err error
+		value, _, err = key.GetStringValue(valueName)
+		return err
+	})
+	return value, err
+}
+
+// readSubKeyStrings returns the value with the given name from each subkey of path (where present)
+func readSubKeyStrings(r registryRepository, k registry.Key, path, valueName string) []string {
+	var names []string
+	err := r.OpenKey(k, path, registry.ENUMERATE_SUB_KEYS, func(key registry.Key) error {
+		var err error
+		names, err = key.ReadSubKeyNames(-1)
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		if value, err2 := readRegistryString(r, k, path+"\\"+name, valueName); err2 == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// containsExecutable returns whether dir contains any of the patchables' game or dedicated server executables (which
+// are the only executable of server-only installations)
+func containsExecutable(patchables []patch.Patchable, dir string) bool {
+	for _, p := range patchables {
+		if patchable.IsOptional(p) && !patchable.IsServer(p) {
+			continue
+		}
+		if _, err := os.Stat(patch.LongPath(filepath.Join(dir, p.GetFileName()))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInstallDir returns an error if dir does not contain any of the patchables' (non-optional) executables, otherwise
+// returning whether the executable looks like a game binary (contains a known build's version or any provider's strings)
+func checkInstallDir(patchables []patch.Patchable, dir string) (bool, error) {
+	var names []string
+	for _, p := range patchables {
+		if patchable.IsOptional(p) {
+			continue
+		}
+
+		inspection, err := patch.Inspect(p, dir)
+		if errors.Is(err, patch.ErrNotExist) {
+			names = appendUnique(names, p.GetFileName())
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", p.GetFileName(), err)
+		}
+
+		if !bytes.HasPrefix(inspection.Data, []byte("MZ")) {
+			return false, nil
+		}
+
+		var notPatchable *patch.NotPatchableError
+		if inspection.DetectErr == nil || (errors.As(inspection.DetectErr, &notPatchable) && len(notPatchable.Candidates) > 0) {
+			return true, nil
+		}
+
+		info, err := patchable.IdentifyInspection(p.GetFileName(), inspection)
+		return err == nil && info.Known(), nil
+	}
+
+	return false, fmt.Errorf("folder does not contain %s", strings.Join(names, " or "))
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// chooseInstallation lets the user choose one of the installations, returning false if the user canceled
+func chooseInstallation(owner walk.Form, installations []installation) (installation, bool) {
+	if len(installations) == 1 {
+		return installations[0], true
+	}
+
+	var dlg *walk.Dialog
+	var installationCB *walk.ComboBox
+	var okPB, cancelPB *walk.PushButton
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Choose installation",
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 420},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: "Found multiple installations, which one should be patched?",
+			},
+			declarative.ComboBox{
+				AssignTo:      &installationCB,
+				DisplayMember: "Label",
+				Model:         installations,
+				CurrentIndex:  0,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return installation{}, false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK || installationCB.CurrentIndex() < 0 {
+		return installation{}, false
+	}
+
+	return installations[installationCB.CurrentIndex()], true
+}
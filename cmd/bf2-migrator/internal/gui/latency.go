@@ -0,0 +1,60 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// providerLatency is the time it took to connect to a provider's login server
+type providerLatency struct {
+	Name    string
+	Latency time.Duration
+	Err     error
+}
+
+func (l providerLatency) String() string {
+	if l.Err != nil {
+		return fmt.Sprintf("%s: unreachable", l.Name)
+	}
+	return fmt.Sprintf("%s: %d ms", l.Name, l.Latency.Milliseconds())
+}
+
+// measureLatencies pings all providers concurrently, returning the results ordered by latency (unreachable last)
+func measureLatencies(c client, options []providerCBOption[gamespy.Provider]) []providerLatency {
+	latencies := make([]providerLatency, len(options))
+	var wg sync.WaitGroup
+	for i, option := range options {
+		wg.Add(1)
+		go func(i int, option providerCBOption[gamespy.Provider]) {
+			defer wg.Done()
+			latency, err := c.Ping(option.Value)
+			latencies[i] = providerLatency{Name: option.Name, Latency: latency, Err: err}
+		}(i, option)
+	}
+	wg.Wait()
+
+	sort.SliceStable(latencies, func(i, j int) bool {
+		if (latencies[i].Err == nil) != (latencies[j].Err == nil) {
+			return latencies[i].Err == nil
+		}
+		return latencies[i].Latency < latencies[j].Latency
+	})
+
+	return latencies
+}
+
+// describeLatencies lists the latencies, one per line
+func describeLatencies(latencies []providerLatency) string {
+	lines := make([]string, 0, len(latencies))
+	for _, l := range latencies {
+		lines = append(lines, l.String())
+	}
+	return strings.Join(lines, "\n")
+}
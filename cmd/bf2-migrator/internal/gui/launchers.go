@@ -0,0 +1,151 @@
+//go:build windows
+
+package gui
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	eaAppInstallerData = "__Installer\\installerdata.xml"
+)
+
+var (
+	vdfPathRegex     = regexp.MustCompile(`"path"\s+"([^"]+)"`)
+	acfNameRegex     = regexp.MustCompile(`"name"\s+"([^"]+)"`)
+	acfInstallRegex  = regexp.MustCompile(`"installdir"\s+"([^"]+)"`)
+	eaAppLibraryKeys = []string{"user.downloadinplacedir", "user.gamedownloaddir"}
+)
+
+// findSteamInstallations returns the installation folder of each app in any Steam library, using the app manifests
+// first (installs via Steam's "add a non-Steam game" are not covered by them, so any other folder in the libraries'
+// common folder is returned as well)
+func findSteamInstallations(r registryRepository) []installation {
+	var installations []installation
+	for _, library := range findSteamLibraries(r) {
+		steamapps := filepath.Join(library, "steamapps")
+		manifests, _ := filepath.Glob(filepath.Join(steamapps, "appmanifest_*.acf"))
+		for _, manifest := range manifests {
+			content, err := os.ReadFile(manifest)
+			if err != nil {
+				continue
+			}
+
+			installDir := acfInstallRegex.FindSubmatch(content)
+			if installDir == nil {
+				continue
+			}
+
+			source := "Steam"
+			if name := acfNameRegex.FindSubmatch(content); name != nil {
+				source = "Steam: " + string(name[1])
+			}
+			installations = append(installations, installation{Dir: filepath.Join(steamapps, "common", string(installDir[1])), Source: source})
+		}
+
+		games, err := os.ReadDir(filepath.Join(steamapps, "common"))
+		if err != nil {
+			continue
+		}
+		for _, game := range games {
+			if game.IsDir() {
+				installations = append(installations, installation{Dir: filepath.Join(steamapps, "common", game.Name()), Source: "Steam"})
+			}
+		}
+	}
+	return installations
+}
+
+// findSteamLibraries returns the folders of all Steam libraries configured in the Steam client
+func findSteamLibraries(r registryRepository) []string {
+	steamPath, err := readRegistryString(r, registry.CURRENT_USER, "SOFTWARE\\Valve\\Steam", "SteamPath")
+	if err != nil {
+		return nil
+	}
+
+	libraries := []string{filepath.Clean(steamPath)}
+	content, err := os.ReadFile(filepath.Join(steamPath, "steamapps", "libraryfolders.vdf"))
+	if err != nil {
+		return libraries
+	}
+
+	for _, match := range vdfPathRegex.FindAllSubmatch(content, -1) {
+		// Backslashes are escaped in vdf files
+		libraries = append(libraries, filepath.Clean(strings.ReplaceAll(string(match[1]), "\\\\", "\\")))
+	}
+
+	return libraries
+}
+
+// findEAAppInstallations returns the folder of each game installed by the EA App. Unlike the old EA launchers, the EA
+// App does not always write the classic EA Games registry key, so the games are found via the EA App's download
+// folders instead (each game installed by the EA App contains its installer metadata).
+func findEAAppInstallations() []installation {
+	var installations []installation
+	for _, library := range findEAAppLibraries() {
+		games, err := os.ReadDir(library)
+		if err != nil {
+			continue
+		}
+		for _, game := range games {
+			if !game.IsDir() {
+				continue
+			}
+			dir := filepath.Join(library, game.Name())
+			if _, err = os.Stat(filepath.Join(dir, eaAppInstallerData)); err == nil {
+				installations = append(installations, installation{Dir: dir, Source: "EA App"})
+			}
+		}
+	}
+	return installations
+}
+
+// findEAAppLibraries returns the EA App's download folders configured by any user, as well as its default one
+func findEAAppLibraries() []string {
+	var libraries []string
+	if dir := os.Getenv("ProgramFiles"); dir != "" {
+		libraries = append(libraries, filepath.Join(dir, "EA Games"))
+	}
+
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return libraries
+	}
+
+	configs, _ := filepath.Glob(filepath.Join(localAppData, "Electronic Arts", "EA Desktop", "user_*.ini"))
+	for _, config := range configs {
+		libraries = append(libraries, readEAAppLibraries(config)...)
+	}
+
+	return libraries
+}
+
+// readEAAppLibraries returns the download folders from an EA App user config (plain key=value lines)
+func readEAAppLibraries(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var libraries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found || value == "" {
+			continue
+		}
+		for _, k := range eaAppLibraryKeys {
+			if strings.EqualFold(key, k) {
+				libraries = append(libraries, filepath.Clean(value))
+			}
+		}
+	}
+	return libraries
+}
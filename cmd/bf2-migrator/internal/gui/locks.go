@@ -0,0 +1,139 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Length of a Restart Manager session key (CCH_RM_SESSION_KEY), excluding the terminating nil
+	rmSessionKeyLength = 32
+	// Length of a Restart Manager application name (CCH_RM_MAX_APP_NAME), excluding the terminating nil
+	rmMaxAppNameLength = 255
+	// Length of a Restart Manager service name (CCH_RM_MAX_SVC_NAME), excluding the terminating nil
+	rmMaxSvcNameLength = 63
+)
+
+var (
+	modRstrtmgr             = windows.NewLazySystemDLL("rstrtmgr.dll")
+	procRmStartSession      = modRstrtmgr.NewProc("RmStartSession")
+	procRmRegisterResources = modRstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList           = modRstrtmgr.NewProc("RmGetList")
+	procRmEndSession        = modRstrtmgr.NewProc("RmEndSession")
+)
+
+// rmProcessInfo is the RM_PROCESS_INFO struct returned by RmGetList
+type rmProcessInfo struct {
+	ProcessID        uint32
+	ProcessStartTime windows.Filetime
+	AppName          [rmMaxAppNameLength + 1]uint16
+	ServiceShortName [rmMaxSvcNameLength + 1]uint16
+	ApplicationType  uint32
+	AppStatus        uint32
+	TSSessionID      uint32
+	Restartable      int32
+}
+
+// lockingProcess is a process holding one of the executables open
+type lockingProcess struct {
+	PID  int
+	Name string
+}
+
+func (p lockingProcess) String() string {
+	return fmt.Sprintf("%s (PID %d)", p.Name, p.PID)
+}
+
+// findLockingProcesses returns the processes holding any of the patchables' executables in dir open, using the
+// Restart Manager (which also reports launchers/overlays not known to the migrator)
+func findLockingProcesses(patchables []patch.Patchable, dir string) ([]lockingProcess, error) {
+	paths := make([]*uint16, 0, len(patchables))
+	for _, p := range patchables {
+		path := filepath.Join(dir, p.GetFileName())
+		if _, err := os.Stat(path); err != nil {
+			// Missing files are reported by the patch itself
+			continue
+		}
+
+		ptr, err := windows.UTF16PtrFromString(patch.LongPath(path))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, ptr)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	if err := modRstrtmgr.Load(); err != nil {
+		return nil, fmt.Errorf("restart manager is not available: %w", err)
+	}
+
+	var session uint32
+	key := make([]uint16, rmSessionKeyLength+1)
+	if ret, _, _ := procRmStartSession.Call(uintptr(unsafe.Pointer(&session)), 0, uintptr(unsafe.Pointer(&key[0]))); ret != 0 {
+		return nil, fmt.Errorf("failed to start restart manager session: %w", windows.Errno(ret))
+	}
+	defer func() {
+		_, _, _ = procRmEndSession.Call(uintptr(session))
+	}()
+
+	ret, _, _ := procRmRegisterResources.Call(uintptr(session), uintptr(len(paths)), uintptr(unsafe.Pointer(&paths[0])), 0, 0, 0, 0)
+	if ret != 0 {
+		return nil, fmt.Errorf("failed to register executables with restart manager: %w", windows.Errno(ret))
+	}
+
+	// The number of processes may change between calls, so retry until the buffer is large enough
+	var infos []rmProcessInfo
+	for {
+		var needed, reasons uint32
+		var ptr *rmProcessInfo
+		count := uint32(len(infos))
+		if count > 0 {
+			ptr = &infos[0]
+		}
+		ret, _, _ = procRmGetList.Call(uintptr(session), uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(ptr)), uintptr(unsafe.Pointer(&reasons)))
+		if errors.Is(windows.Errno(ret), windows.ERROR_MORE_DATA) {
+			infos = make([]rmProcessInfo, needed)
+			continue
+		}
+		if ret != 0 {
+			return nil, fmt.Errorf("failed to list processes using executables: %w", windows.Errno(ret))
+		}
+		infos = infos[:count]
+		break
+	}
+
+	processes := make([]lockingProcess, 0, len(infos))
+	for _, info := range infos {
+		processes = append(processes, lockingProcess{
+			PID:  int(info.ProcessID),
+			Name: windows.UTF16ToString(info.AppName[:]),
+		})
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].PID < processes[j].PID
+	})
+
+	return processes, nil
+}
+
+// describeLockingProcesses lists the processes, one per line
+func describeLockingProcesses(processes []lockingProcess) string {
+	lines := make([]string, 0, len(processes))
+	for _, p := range processes {
+		lines = append(lines, p.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/lxn/win"
+)
+
+const (
+	// Number of lines kept for the log pane, dropping the oldest ones
+	logBufferLines = 1000
+	// Height the window grows by when showing the log pane
+	logPaneHeight = 200
+)
+
+// LogBuffer collects log output (e.g. as the output of a zerolog.ConsoleWriter) for display in the log pane, since the
+// GUI build has no console to write to
+type LogBuffer struct {
+	mu       sync.Mutex
+	lines    []string
+	listener func(line string)
+}
+
+func NewLogBuffer() *LogBuffer {
+	return &LogBuffer{}
+}
+
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		b.lines = append(b.lines, line)
+		if b.listener != nil {
+			b.listener(line)
+		}
+	}
+
+	if len(b.lines) > logBufferLines {
+		b.lines = b.lines[len(b.lines)-logBufferLines:]
+	}
+
+	return len(p), nil
+}
+
+// String returns the buffered lines, using Windows line breaks for display in/copying from a text edit
+func (b *LogBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.lines) == 0 {
+		return ""
+	}
+	return strings.Join(b.lines, "\r\n") + "\r\n"
+}
+
+// attach calls listener for every line written from now on (replacing any previous listener). listener is called from
+// the logging goroutine, so it must not block.
+func (b *LogBuffer) attach(listener func(line string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listener = listener
+}
+
+// openFolder shows the folder in Windows Explorer
+func openFolder(hwnd win.HWND, dir string) error {
+	if !win.ShellExecute(hwnd, syscall.StringToUTF16Ptr("open"), syscall.StringToUTF16Ptr(dir), nil, nil, win.SW_SHOWNORMAL) {
+		return fmt.Errorf("failed to open %s", dir)
+	}
+	return nil
+}
@@ -1,9 +1,18 @@
+//go:build windows
+
 package gui
 
 import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/cetteup/conman/pkg/game/bf2"
 	"github.com/lxn/walk"
@@ -14,16 +23,21 @@ import (
 	"golang.org/x/sys/windows/registry"
 
 	"github.com/cetteup/conman/pkg/game"
-	"github.com/cetteup/joinme.click-launcher/pkg/software_finder"
 
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
 	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/preset"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/readonly"
 	"github.com/cetteup/bf2-migrator/pkg/gamespy"
 	"github.com/cetteup/bf2-migrator/pkg/patch"
 )
 
 const (
-	windowWidth  = 290
-	windowHeight = 412
+	version = "v0.7.0"
+
+	windowWidth  = 360
+	windowHeight = 460
 
 	bf2hubExecutableName = "bf2hub.exe"
 
@@ -32,295 +46,1543 @@ const (
 	providerNameOpenSpy = "OpenSpy"
 )
 
-type finder interface {
-	GetInstallDirFromSomewhere(configs []software_finder.Config) (string, error)
-}
-
 type registryRepository interface {
 	OpenKey(k registry.Key, path string, access uint32, cb func(key registry.Key) error) error
 }
 
-type client interface {
-	GetNicks(provider gamespy.Provider, email, password string) ([]gamespy.NickDTO, error)
-	CreateUser(provider gamespy.Provider, email, password, nick string) error
-}
+type client interface {
+	GetNicks(provider gamespy.Provider, email, password string) ([]gamespy.NickDTO, error)
+	GetNicksAll(providers []gamespy.Provider, email, password string) map[gamespy.Provider]gamespy.NicksResult
+	CreateUser(provider gamespy.Provider, email, password, nick string) error
+	UniqueNickExists(provider gamespy.Provider, uniqueNick string) (bool, error)
+	SearchProfiles(provider gamespy.Provider, nick string) ([]gamespy.ProfileDTO, error)
+	ChangePassword(provider gamespy.Provider, email, oldPassword, newPassword string) error
+	ChangeEmail(provider gamespy.Provider, email, password, newEmail string) error
+	Login(provider gamespy.Provider, uniqueNick, password string) (gamespy.LoginDTO, error)
+	Ping(provider gamespy.Provider) (time.Duration, error)
+}
+
+type providerCBOption[T patch.Provider | gamespy.Provider] struct {
+	Name  string
+	Value T
+}
+
+var migrateProviderOptions = []providerCBOption[gamespy.Provider]{
+	{
+		Name:  providerNameBF2Hub,
+		Value: gamespy.ProviderBF2Hub,
+	},
+	{
+		Name:  providerNamePlayBF2,
+		Value: gamespy.ProviderPlayBF2,
+	},
+	{
+		Name:  providerNameOpenSpy,
+		Value: gamespy.ProviderOpenSpy,
+	},
+	// Not offering GameSpy (obsolete, cannot migrate anything to it)
+}
+
+var patchProviderOptions = []providerCBOption[patch.Provider]{
+	{
+		Name:  providerNameBF2Hub,
+		Value: patchable.ProviderBF2Hub,
+	},
+	{
+		Name:  providerNamePlayBF2,
+		Value: patchable.ProviderPlayBF2,
+	},
+	{
+		Name:  providerNameOpenSpy,
+		Value: patchable.ProviderOpenSpy,
+	},
+	// Not offering GameSpy (obsolete, only used for reverting)
+}
+
+type credentials = migrate.Credentials
+
+// Options are the initial settings of the main window
+type Options struct {
+	PatchGame   bool
+	PatchServer bool
+	// Only run the detection/diagnostic parts of actions, refusing to make any changes
+	ReadOnly bool
+	// Installation folder to open instead of detecting installations (e.g. when restarted as administrator)
+	Dir string
+	// Log output to show in the log pane
+	Log *LogBuffer
+	// Folder the log files are written to (empty if logs are not written to files)
+	LogDir string
+	// Returns the proxy to send HTTP requests via (nil to use the system proxy)
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+func CreateMainWindow(h game.Handler, r registryRepository, c client, o Options) (*walk.MainWindow, error) {
+	icon, err := walk.NewIconFromResourceIdWithSize(2, walk.Size{Width: 256, Height: 256})
+	if err != nil {
+		return nil, err
+	}
+
+	screenWidth, screenHeight := getScreenSize()
+
+	w := &mainWindow{
+		h:        h,
+		r:        r,
+		c:        c,
+		o:        o,
+		readOnly: o.ReadOnly,
+		summary:  newSessionSummary(),
+	}
+
+	var migrateGB *walk.GroupBox
+	var versionLB *walk.Label
+	var patchPB *walk.PushButton
+	var revertPB *walk.PushButton
+	var presetsMenu *walk.Menu
+	var verifyPB *walk.PushButton
+	var readOnlyAction *walk.Action
+	var readOnlyCB *walk.CheckBox
+	var showLogCB *walk.CheckBox
+	var showLogAction *walk.Action
+	var logGB *walk.GroupBox
+	var logTE *walk.TextEdit
+
+	logs := o.Log
+	if logs == nil {
+		logs = NewLogBuffer()
+	}
+
+	if w.prefs, err = loadSettings(); err != nil {
+		// Settings are optional, so continue with the defaults
+		log.Error().
+			Err(err).
+			Msg("Failed to load settings")
+	}
+	setLanguage(language(w.prefs.Language))
+
+	if w.catalogPath, err = getCatalogPath(); err != nil {
+		return nil, err
+	}
+
+	if w.remoteCatalogPath, err = getRemoteCatalogPath(); err != nil {
+		return nil, err
+	}
+
+	// Local catalog takes precedence over the remote one, so users can always override providers
+	if w.catalog, err = patchable.LoadCatalog(w.remoteCatalogPath, w.catalogPath); err != nil {
+		// Local catalog is optional, so continue with the embedded catalog only
+		log.Error().
+			Err(err).
+			Str("path", w.catalogPath).
+			Msg("Failed to load local provider catalog")
+	}
+
+	w.patchables = w.catalog.Patchables()
+
+	// Returns the patchables of the executables selected for patching
+	selectedPatchables := func() []patch.Patchable {
+		return w.patchablesFor(w.pathTE.Text())
+	}
+
+	enablePatch := func(path string) {
+		_ = w.pathTE.SetText(path)
+		_ = w.pathTE.SetToolTipText(path)
+		_ = versionLB.SetText(fmt.Sprintf("Detected version: %s", describeGameBuild(w.patchables, path)))
+		patchPB.SetEnabled(true)
+		revertPB.SetEnabled(true)
+		verifyPB.SetEnabled(true)
+
+		// Pre-select the provider the game is already patched for, so re-patching does not switch providers
+		for _, p := range w.patchablesFor(path) {
+			if patchable.IsServer(p) {
+				continue
+			}
+			provider, err2 := patch.DetectProvider(p, path)
+			if err2 != nil {
+				continue
+			}
+			for i, option := range w.patchProviderCB.Model().([]providerCBOption[patch.Provider]) {
+				if option.Value == provider {
+					_ = w.patchProviderCB.SetCurrentIndex(i)
+				}
+			}
+			break
+		}
+
+		// Remember the folder, so it is opened again on the next launch
+		if !w.readOnly && w.prefs.LastDir != path {
+			w.prefs.LastDir = path
+			if err2 := saveSettings(w.prefs); err2 != nil {
+				log.Error().
+					Err(err2).
+					Str("dir", path).
+					Msg("Failed to remember installation folder")
+			}
+		}
+	}
+
+	// (Re-)loads the profiles, keeping the currently selected profile selected if it still exists
+	reloadProfiles := func() error {
+		var current string
+		if profiles, ok := w.profileCB.Model().([]game.Profile); ok && w.profileCB.CurrentIndex() >= 0 {
+			current = profiles[w.profileCB.CurrentIndex()].Key
+		}
+
+		profiles, selected, err2 := getProfiles(w.h)
+		switch {
+		case err2 != nil:
+			_ = migrateGB.SetTitle("Migrate (failed to load profiles)")
+		case len(profiles) == 0:
+			_ = migrateGB.SetTitle("Migrate (no profiles found)")
+		default:
+			_ = migrateGB.SetTitle("Migrate")
+		}
+
+		// Credentials can still be entered manually, so keep the provider selectable
+		available := err2 == nil && len(profiles) > 0
+		w.profileCB.SetEnabled(available)
+		if !available {
+			_ = w.profileCB.SetModel([]game.Profile{})
+			w.migratePB.SetEnabled(false)
+			return err2
+		}
+
+		for i, profile := range profiles {
+			if profile.Key == current {
+				selected = i
+			}
+		}
+		_ = w.profileCB.SetModel(profiles)
+		_ = w.profileCB.SetCurrentIndex(selected)
+		w.migratePB.SetEnabled(profiles[selected].Type == game.ProfileTypeMultiplayer)
+
+		return nil
+	}
+
+	// Reloads the profiles on request of the user
+	refreshProfiles := func() {
+		if err2 := reloadProfiles(); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to load profiles: %s", err2.Error()), err2)
+		}
+	}
+
+	// Shows/hides the log pane, growing/shrinking the window to make room for it
+	setLogVisible := func(visible bool) {
+		if logGB.Visible() == visible {
+			return
+		}
+
+		size := w.mw.Size()
+		if visible {
+			size.Height += logPaneHeight
+		} else {
+			size.Height -= logPaneHeight
+		}
+		logGB.SetVisible(visible)
+		_ = w.mw.SetSize(size)
+
+		// Pane can be toggled from both the menu and the settings tab
+		_ = showLogAction.SetChecked(visible)
+		showLogCB.SetChecked(visible)
+	}
+
+	// Enables/disables read-only mode, which can be toggled from both the menu and the settings tab
+	setReadOnly := func(enabled bool) {
+		if w.readOnly == enabled {
+			return
+		}
+
+		w.readOnly = enabled
+		_ = readOnlyAction.SetChecked(enabled)
+		readOnlyCB.SetChecked(enabled)
+	}
+
+	// Reverts the most recent change, keeping it on the stack if reverting it fails
+	undoLast := func() {
+		action, ok := w.undo.peek()
+		if !ok {
+			walk.MsgBox(w.mw, "Undo", "There is nothing to undo", walk.MsgBoxIconInformation)
+			return
+		}
+
+		if walk.MsgBox(w.mw, "Undo", fmt.Sprintf("Undo %s?", action.Description), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+			return
+		}
+
+		if w.refuseInReadOnly(fmt.Sprintf("undoing %s", action.Description)) {
+			return
+		}
+
+		w.mw.SetEnabled(false)
+		defer w.mw.SetEnabled(true)
+
+		if err2 := action.Undo(); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to undo %s: %s", action.Description, err2.Error()), err2)
+			return
+		}
+
+		w.undo.pop()
+		w.updateUndoButton()
+		// Restored executables would otherwise be reported as changed by another patcher
+		w.recordPatch(selectedPatchables(), w.pathTE.Text())
+		w.summary.record(fmt.Sprintf("Undid %s", action.Description))
+		walk.MsgBox(w.mw, "Success", fmt.Sprintf("Undid %s", action.Description), walk.MsgBoxIconInformation)
+	}
+
+	// Patches copies of the executables, reporting whether patching them would succeed
+	dryRunPatch := func(selected []patch.Patchable, provider string, new patch.Provider) {
+		if err2 := patchable.DryRun(selected, w.pathTE.Text(), new); err2 != nil {
+			walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("Patching to use %s would fail: %s", provider, err2.Error()), walk.MsgBoxIconWarning)
+			return
+		}
+		walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("Patching to use %s would succeed\n\nRead-only mode is enabled, so no files were modified", provider), walk.MsgBoxIconInformation)
+	}
+
+	// Returns whether patching should continue
+	confirmNoFileVerification := func() bool {
+		verifier, err2 := detectFileVerification(w.pathTE.Text())
+		if err2 != nil {
+			// Failing to detect verification should not prevent patching
+			log.Error().
+				Err(err2).
+				Msg("Failed to detect running file verification")
+			return true
+		}
+		if verifier == "" {
+			return true
+		}
+
+		msg := fmt.Sprintf("%s\n\nPatching now may fail or be reverted once verification finishes. Continue anyway?", verifier)
+		return walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+	}
+
+	// Returns whether patching should continue
+	confirmNoInjectors := func() bool {
+		injectors, err2 := detectInjectorDLLs(w.pathTE.Text())
+		if err2 != nil || len(injectors) == 0 {
+			// Unreadable folders are reported by the patch itself
+			return true
+		}
+
+		msg := fmt.Sprintf("Found DLLs in the installation folder which are known to break after patching:\n\n%s\n\nConsider removing them if the game crashes after patching. Continue anyway?", strings.Join(injectors, "\n"))
+		return walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+	}
+
+	// Returns whether patching should continue, along with the inspection of the game executable (so patching acts on
+	// the binary which was checked, without reading it again)
+	confirmKnownVersion := func() (map[string]patch.Inspection, bool) {
+		info, inspection, err2 := identifyGameBuild(w.patchables, w.pathTE.Text())
+		if err2 != nil {
+			// Missing/unreadable executables are reported by the patch itself
+			return nil, true
+		}
+
+		inspections := map[string]patch.Inspection{patchable.GameExecutableName: inspection}
+		if info.Known() {
+			return inspections, true
+		}
+
+		msg := fmt.Sprintf("The game executable reports a version the migrator does not know: %s\n\nPatching executables of other versions may only partially succeed. Continue anyway?", info)
+		return inspections, walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+	}
+
+	// Returns whether patching should continue
+	confirmNoHostsConflicts := func() bool {
+		domains := w.catalog.Domains()
+		conflicts, err2 := hosts.FindConflicts(hosts.DefaultPath(), domains)
+		if err2 != nil {
+			// Failing to check the hosts file should not prevent patching
+			log.Error().
+				Err(err2).
+				Msg("Failed to check hosts file for conflicting entries")
+			return true
+		}
+		if len(conflicts) == 0 {
+			return true
+		}
+
+		lines := make([]string, 0, len(conflicts))
+		for _, conflict := range conflicts {
+			lines = append(lines, conflict.Text)
+		}
+		if w.readOnly {
+			msg := fmt.Sprintf("Found hosts file entries which were likely left behind by another patcher:\n\n%s\n\nThey will make the game connect to the wrong servers even once patched. Read-only mode is enabled, so they were not removed.", strings.Join(lines, "\n"))
+			walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxIconWarning)
+			return true
+		}
+
+		msg := fmt.Sprintf("Found hosts file entries which were likely left behind by another patcher:\n\n%s\n\nThey will make the game connect to the wrong servers even once patched. Remove them now? This requires administrator privileges.", strings.Join(lines, "\n"))
+		switch walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNoCancel|walk.MsgBoxIconWarning) {
+		case walk.DlgCmdYes:
+			if err2 = cleanHosts(w.mw.Handle(), domains); err2 != nil {
+				showError(w.mw, fmt.Sprintf("Failed to remove hosts file entries: %s", err2.Error()), err2)
+				return false
+			}
+			return true
+		case walk.DlgCmdNo:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// Returns whether patching should continue
+	confirmWorkingDLLs := func(dlls map[string]string) bool {
+		broken, err2 := findBrokenDLLs(w.catalog, w.pathTE.Text(), dlls)
+		if err2 != nil {
+			// Failing to check DLLs should not prevent patching
+			log.Error().
+				Err(err2).
+				Msg("Failed to check provider DLLs")
+			return true
+		}
+		if len(broken) == 0 {
+			return true
+		}
+
+		msg := fmt.Sprintf("The following DLLs are outdated or corrupt and will likely crash the game at startup:\n\n%s\n\nReplace them with the copies included with the latest version of the provider's client. Continue anyway?", strings.Join(broken, "\n"))
+		return walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) == walk.DlgCmdYes
+	}
+
+	// Returns whether patching should continue, presenting all problems found before patching at once
+	confirmPreflight := func(selected []patch.Patchable) bool {
+		problems := findPreflightProblems(selected, w.pathTE.Text())
+		if len(problems) == 0 {
+			return true
+		}
+
+		for _, p := range problems {
+			if p.Fatal {
+				msg := fmt.Sprintf("Cannot patch due to the following problems:\n\n%s", describePreflightProblems(problems))
+				walk.MsgBox(w.mw, "Error", msg, walk.MsgBoxIconError)
+				return false
+			}
+		}
+
+		msg := fmt.Sprintf("Found the following problems:\n\n%s\n\nTry to fix them now? Fixing access problems may require administrator privileges.", describePreflightProblems(problems))
+		if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+			return false
+		}
+
+		// Any access problems are fixed by ensureWritable
+		if err2 := clearReadOnly(problems); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to remove read-only attribute: %s", err2.Error()), err2)
+			return false
+		}
+
+		return true
+	}
+
+	// Returns whether patching should continue
+	ensureWritable := func(selected []patch.Patchable) bool {
+		unwritable := findUnwritable(selected, w.pathTE.Text())
+		// Protected folders (e.g. Program Files) are writable once elevated, whereas files owned by another account
+		// (e.g. TrustedInstaller) need to be taken ownership of
+		if (len(unwritable) > 0 || !isDirWritable(w.pathTE.Text())) && w.offerElevation("Writing to the install folder") {
+			return false
+		}
+		if len(unwritable) == 0 {
+			return true
+		}
+
+		// takeown/icacls cannot change the permissions of files on the host file system
+		if isWine() {
+			msg := fmt.Sprintf("Access to the following files is denied:\n\n%s\n\nWhen running under Wine, make sure your Linux user can write to them (e.g. using chmod/chown) and try again.", strings.Join(unwritable, "\n"))
+			walk.MsgBox(w.mw, "Error", msg, walk.MsgBoxIconError)
+			return false
+		}
+
+		msg := fmt.Sprintf("Access to the following files is denied:\n\n%s\n\nThey are likely owned by another account (e.g. TrustedInstaller). Take ownership of them now? This requires administrator privileges.", strings.Join(unwritable, "\n"))
+		if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+			return false
+		}
+
+		if err2 := takeOwnership(w.mw.Handle(), unwritable); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to take ownership: %s", err2.Error()), err2)
+			return false
+		}
+
+		return true
+	}
+
+	// Returns whether patching should continue, letting the user close any processes still holding the executables open
+	confirmNotLocked := func(selected []patch.Patchable) bool {
+		for {
+			processes, err2 := findLockingProcesses(selected, w.pathTE.Text())
+			if err2 != nil {
+				// Failing to check for locks should not prevent patching (e.g. Wine does not implement the Restart Manager)
+				log.Error().
+					Err(err2).
+					Msg("Failed to check for processes using the executables")
+				return true
+			}
+			if len(processes) == 0 {
+				return true
+			}
+
+			msg := fmt.Sprintf("The executables are in use by the following processes:\n\n%s\n\nThey cannot be patched while in use. Close the processes (e.g. launchers or overlays) and retry.", describeLockingProcesses(processes))
+			if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxRetryCancel|walk.MsgBoxIconWarning) != walk.DlgCmdRetry {
+				return false
+			}
+		}
+	}
+
+	// Returns those of the nick's suggested variants which are not yet taken on the provider
+	findAvailableNicks := func(provider gamespy.Provider, nick string) []string {
+		suggestions := migrate.SuggestNicks(nick)
+		available := make([]string, 0, len(suggestions))
+		_ = runWithProgress(w.mw, "Finding available nicks", []string{"Check suggested nicks"}, func(report progressFunc) error {
+			for i, suggestion := range suggestions {
+				report(0, i, len(suggestions))
+				exists, err3 := w.c.UniqueNickExists(provider, suggestion)
+				if err3 != nil {
+					log.Error().
+						Err(err3).
+						Str("nick", suggestion).
+						Msg("Failed to check whether suggested nick is available")
+					continue
+				}
+				if !exists {
+					available = append(available, suggestion)
+				}
+			}
+			return nil
+		})
+		return available
+	}
+
+	// Migrates the credentials to the provider, referring to them by subject (e.g. the profile's name) in messages.
+	// If the nick is taken by another account, the user can choose a different one to migrate instead, which is passed
+	// to rename (if set) once migrated. Returns whether the nick is set up on the provider afterwards.
+	migrateCredentials := func(provider providerCBOption[gamespy.Provider], subject string, creds *migrate.Credentials, rename func(nick string) error, details ...errorDetail) bool {
+		details = append(details, errorDetail{Name: "Provider", Value: provider.Name})
+
+		// Some providers require confirming the email address, so warn about addresses which cannot receive mail
+		if problem := checkEmail(creds.Email); problem != "" {
+			msg := fmt.Sprintf("%s\n\n%s may require you to confirm your email address, which will not be possible. Migrate anyway?", problem, provider.Name)
+			if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+				return false
+			}
+		}
+
+		original := creds.Nick
+		migrated, err2 := migrateWithProgress(w.mw, w.migrationClient(), provider.Value, creds)
+		for migrate.IsUniqueNickUsed(err2) {
+			nick, ok := showNickConflictDialog(w.mw, provider.Name, provider.Value, creds.Nick, findAvailableNicks(provider.Value, creds.Nick))
+			if !ok {
+				break
+			}
+			creds.Nick = nick
+			migrated, err2 = migrateWithProgress(w.mw, w.migrationClient(), provider.Value, creds)
+		}
+
+		// Only diverge from the original nick once the new one is actually set up on the provider
+		if err2 == nil && creds.Nick != original && rename != nil {
+			if err3 := rename(creds.Nick); err3 != nil {
+				showError(w.mw, fmt.Sprintf("Migrated %s to %s as %q, but failed to change its nick: %s", subject, provider.Name, creds.Nick, err3.Error()), err3, details...)
+				return false
+			}
+		}
+		if errors.Is(err2, readonly.ErrReadOnly) {
+			walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("%s is not yet set up on %s\n\nRead-only mode is enabled, so it was not created", subject, provider.Name), walk.MsgBoxIconInformation)
+			return false
+		} else if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to migrate %s to %s: %s", subject, provider.Name, describeError(err2)), err2, details...)
+			return false
+		} else if !migrated {
+			w.summary.record(fmt.Sprintf("Checked %s, already set up on %s", subject, provider.Name), fmt.Sprintf("Nick: %s", creds.Nick), fmt.Sprintf("Email: %s", creds.Email))
+			walk.MsgBox(w.mw, "Skipped", fmt.Sprintf("%s is already set up on %s", subject, provider.Name), walk.MsgBoxIconInformation)
+		} else {
+			w.summary.record(fmt.Sprintf("Migrated %s to %s", subject, provider.Name), fmt.Sprintf("Nick: %s", creds.Nick), fmt.Sprintf("Email: %s", creds.Email), fmt.Sprintf("Profile ID: %s", creds.ProfileID))
+			walk.MsgBox(w.mw, "Success", fmt.Sprintf("Migrated %s to %s (profile ID: %s)", subject, provider.Name, creds.ProfileID)+describeNextSteps(w.catalog, provider.Name), walk.MsgBoxIconInformation)
+		}
+		return true
+	}
+
+	// Migrates the selected profile to the selected provider
+	migrateSelected := func() {
+		// Block any actions during migrations
+		w.mw.SetEnabled(false)
+		defer w.mw.SetEnabled(true)
+
+		provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+		profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+
+		// Malformed config files make migrations fail half-way, so check them (and offer fixes) before starting
+		issues, err2 := checkProfileConfig(w.h, profile.Key)
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to migrate %q to %s: %s", profile.Name, provider.Name, describeError(err2)), err2, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+		if len(issues) > 0 {
+			selected, ok := showProfileChecklist(w.mw, profile.Name, issues, w.readOnly)
+			if !ok {
+				return
+			}
+			if len(selected) > 0 {
+				previous, err3 := fixProfileConfig(w.h, profile.Key, selected)
+				if err3 != nil {
+					showError(w.mw, fmt.Sprintf("Failed to fix profile %q: %s", profile.Name, err3.Error()), err3, errorDetail{Name: "Profile", Value: profile.Name})
+					return
+				}
+				w.pushUndo(fmt.Sprintf("fixing the config of profile %q", profile.Name), func() error {
+					return restoreProfileConfig(w.h, previous)
+				})
+
+				details := make([]string, 0, len(selected))
+				for _, issue := range selected {
+					details = append(details, issue.Description)
+				}
+				w.summary.record(fmt.Sprintf("Fixed config of profile %q", profile.Name), details...)
+			}
+		}
+
+		creds, err2 := readProfileCredentials(w.h, profile.Key)
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to migrate %q to %s: %s", profile.Name, provider.Name, describeError(err2)), err2, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		stored := creds
+		// Keep the profile's nick in sync with the nick created on the provider, so the game logs in using it
+		rename := func(nick string) error {
+			if err3 := updateProfileNick(w.h, profile.Key, nick); err3 != nil {
+				return err3
+			}
+			w.pushUndo(fmt.Sprintf("changing the nick of profile %q", profile.Name), func() error {
+				return updateProfileNick(w.h, profile.Key, stored.Nick)
+			})
+			w.summary.record(fmt.Sprintf("Changed nick of profile %q", profile.Name), fmt.Sprintf("Previous nick: %s", stored.Nick), fmt.Sprintf("Nick: %s", nick))
+			return nil
+		}
+
+		if !migrateCredentials(provider, fmt.Sprintf("profile %q", profile.Name), &creds, rename, errorDetail{Name: "Profile", Value: profile.Name}) || creds.Password == stored.Password {
+			return
+		}
+
+		// The password stored in the profile was rejected, so the game would fail to log in with it
+		msg := fmt.Sprintf("The password stored in profile %q differs from the one used on %s\n\nUpdate the profile to log in using the password you entered?", profile.Name, provider.Name)
+		if walk.MsgBox(w.mw, "Update profile", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+			return
+		}
+
+		if w.refuseInReadOnly("updating the profile") {
+			return
+		}
+
+		if err2 = updateProfileLogin(w.h, profile.Key, creds.Email, creds.Password); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to update profile %q: %s", profile.Name, err2.Error()), err2, errorDetail{Name: "Profile", Value: profile.Name})
+			return
+		}
+		w.pushUndo(fmt.Sprintf("updating the password of profile %q", profile.Name), func() error {
+			return updateProfileLogin(w.h, profile.Key, stored.Email, stored.Password)
+		})
+
+		w.summary.record(fmt.Sprintf("Updated password of profile %q to the one used on %s", profile.Name, provider.Name))
+	}
+
+	// Migrates credentials entered by the user to the selected provider, for users whose local profile is gone
+	migrateManually := func() {
+		provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+		creds, ok := promptCredentials(w.mw, provider.Name, provider.Value)
+		if !ok {
+			return
+		}
+
+		// Block any actions during migrations
+		w.mw.SetEnabled(false)
+		defer w.mw.SetEnabled(true)
+
+		if !migrateCredentials(provider, fmt.Sprintf("nick %q", creds.Nick), &creds, nil, errorDetail{Name: "Nick", Value: creds.Nick}) {
+			return
+		}
+
+		// Offer to set up the selected profile to log in to the account, so profile and account don't diverge
+		if !w.migratePB.Enabled() {
+			return
+		}
+		profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+		msg := fmt.Sprintf("Update profile %q to log in to %s as %q using the email address and password you entered?", profile.Name, provider.Name, creds.Nick)
+		if walk.MsgBox(w.mw, "Update profile", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+			return
+		}
+
+		if w.refuseInReadOnly("updating the profile") {
+			return
+		}
+
+		previous, err2 := readProfileCredentials(w.h, profile.Key)
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err2.Error()), err2, errorDetail{Name: "Profile", Value: profile.Name})
+			return
+		}
+
+		if err2 = updateProfileCredentials(w.h, profile.Key, creds); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to update profile %q: %s", profile.Name, err2.Error()), err2, errorDetail{Name: "Profile", Value: profile.Name})
+			return
+		}
+		w.pushUndo(fmt.Sprintf("updating the credentials of profile %q", profile.Name), func() error {
+			return updateProfileCredentials(w.h, profile.Key, previous)
+		})
+
+		w.summary.record(fmt.Sprintf("Updated profile %q to log in to %s as %q", profile.Name, provider.Name, creds.Nick), fmt.Sprintf("Email: %s", creds.Email))
+	}
+
+	// Logs in to the selected provider using the selected profile's credentials, as the game would
+	testLogin := func() {
+		if !w.migratePB.Enabled() {
+			walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+			return
+		}
+
+		provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+		profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+		creds, err2 := readProfileCredentials(w.h, profile.Key)
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err2.Error()), err2, errorDetail{Name: "Profile", Value: profile.Name})
+			return
+		}
+
+		var login gamespy.LoginDTO
+		err2 = runWithProgress(w.mw, "Testing login", []string{fmt.Sprintf("Log in to %s", provider.Name)}, func(report progressFunc) error {
+			report(0, 0, 0)
+			var err3 error
+			login, err3 = w.c.Login(provider.Value, creds.Nick, creds.Password)
+			return err3
+		})
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to log in to %s as %q: %s", provider.Name, creds.Nick, describeError(err2)), err2, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		w.summary.record(fmt.Sprintf("Tested login of profile %q on %s", profile.Name, provider.Name), fmt.Sprintf("Nick: %s", creds.Nick))
+		walk.MsgBox(w.mw, "Success", fmt.Sprintf("Logged in to %s as %q (profile ID: %s)\n\nThe profile is ready to be used on %s", provider.Name, login.UniqueNick, login.ProfileID, provider.Name), walk.MsgBoxIconInformation)
+	}
+
+	// Checks which provider the selected executables will connect to
+	verifyPatch := func() {
+		v, err2 := verifyInstallation(w.catalog, selectedPatchables(), w.pathTE.Text(), hosts.DefaultPath())
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to verify installation: %s", err2.Error()), err2)
+			return
+		}
+
+		if v.OK() {
+			walk.MsgBox(w.mw, "Verified", v.String(), walk.MsgBoxIconInformation)
+		} else {
+			walk.MsgBox(w.mw, "Verification failed", v.String(), walk.MsgBoxIconError)
+		}
+	}
+
+	// Patches the executables, offering to adopt binaries patched by other patchers if they cannot be patched as-is
+	patchOrAdopt := func(title string, selected []patch.Patchable, dir string, provider patch.Provider, inspections map[string]patch.Inspection) error {
+		err2 := patchWithProgress(w.mw, title, selected, dir, provider, inspections)
+		if !errors.Is(err2, patch.ErrUnknownModifications) {
+			return err2
+		}
+
+		msg := fmt.Sprintf("Failed to patch %s\n\nThe executables may have been patched by another patcher, which left parts of the original strings behind. Do you want to clean up these leftovers and try again?", err2.Error())
+		if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+			return err2
+		}
+
+		adopted, err3 := patchable.AdoptAll(selected, dir)
+		if err3 != nil {
+			return fmt.Errorf("failed to clean up leftovers: %w", err3)
+		}
+		if len(adopted) == 0 {
+			return err2
+		}
+
+		// Adopting changed the executables, so they need to be read again
+		return patchWithProgress(w.mw, title, selected, dir, provider, nil)
+	}
+
+	// Patches the selected executables to use the selected provider
+	applyPatch := func() {
+		// Block any actions during patching
+		w.mw.SetEnabled(false)
+		defer w.mw.SetEnabled(true)
+
+		selected := selectedPatchables()
+		if len(selected) == 0 {
+			walk.MsgBox(w.mw, "Error", "Select at least one executable to patch", walk.MsgBoxIconError)
+			return
+		}
+
+		if !confirmNoFileVerification() {
+			return
+		}
+		inspections, ok := confirmKnownVersion()
+		if !ok || !confirmNoInjectors() || !confirmNoHostsConflicts() {
+			return
+		}
+
+		provider := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])[w.patchProviderCB.CurrentIndex()]
+		if w.readOnly {
+			dryRunPatch(selected, provider.Name, provider.Value)
+			return
+		}
+
+		restore := w.snapshotPatch(selected, w.pathTE.Text())
+		err2 := prepareForPatch(w.r, selected, func(killed map[int]string) error {
+			return waitWithProgress(w.mw, killed)
+		})
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to prepare for patching: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		if err2 = clearWriteProtection(w.pathTE.Text()); err2 != nil {
+			if errors.Is(err2, os.ErrPermission) && w.offerElevation("Removing write protection") {
+				return
+			}
+			showError(w.mw, fmt.Sprintf("Failed to remove write protection: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		if !confirmPreflight(selected) || !ensureWritable(selected) || !confirmNotLocked(selected) {
+			return
+		}
+
+		dlls, err2 := w.catalog.RequiredDLLs(provider.Value)
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to determine required DLLs: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		// Deploy DLLs first, since the patched executables would not start without them
+		err2 = deployDLLs(w.pathTE.Text(), selectDLLs(dlls, selected), w.locateDLL(provider.Name))
+		if err2 != nil {
+			if errors.Is(err2, os.ErrPermission) && w.offerElevation("Deploying DLLs to the install folder") {
+				return
+			}
+			showError(w.mw, fmt.Sprintf("Failed to deploy DLLs: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		if !confirmWorkingDLLs(selectDLLs(dlls, selected)) {
+			return
+		}
+
+		err2 = patchOrAdopt(fmt.Sprintf("Patching to use %s", provider.Name), selected, w.pathTE.Text(), provider.Value, inspections)
+		if restore != nil {
+			w.pushUndo(fmt.Sprintf("patching to use %s", provider.Name), restore)
+		}
+		if err2 != nil {
+			if errors.Is(err2, os.ErrPermission) && w.offerElevation("Patching the executables") {
+				return
+			}
+			showError(w.mw, fmt.Sprintf("Failed to patch %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()}, errorDetail{Name: "Provider", Value: provider.Name})
+			return
+		}
+
+		if w.writeProtectCB.Checked() {
+			if err2 = writeProtect(selected, w.pathTE.Text()); err2 != nil {
+				showError(w.mw, fmt.Sprintf("Patched game to use %s, but failed to write-protect executables: %s", provider.Name, err2.Error()), err2, errorDetail{Name: "Provider", Value: provider.Name})
+				return
+			}
+		}
+
+		w.recordPatch(selected, w.pathTE.Text())
+		w.summary.record(fmt.Sprintf("Patched executables to use %s", provider.Name), fmt.Sprintf("Folder: %s", w.pathTE.Text()), fmt.Sprintf("Executables: %s", describeExecutables(selected)), fmt.Sprintf("Write-protected: %t", w.writeProtectCB.Checked()))
+		walk.MsgBox(w.mw, "Success", fmt.Sprintf("Patched game to use %s", provider.Name), walk.MsgBoxIconInformation)
+	}
+
+	// Reverts the selected executables to use GameSpy
+	revertPatch := func() {
+		// Block any actions during patching
+		w.mw.SetEnabled(false)
+		defer w.mw.SetEnabled(true)
+
+		selected := selectedPatchables()
+		if len(selected) == 0 {
+			walk.MsgBox(w.mw, "Error", "Select at least one executable to revert", walk.MsgBoxIconError)
+			return
+		}
+
+		if !confirmNoFileVerification() || !confirmNoHostsConflicts() {
+			return
+		}
+
+		if w.readOnly {
+			dryRunPatch(selected, "GameSpy", patchable.ProviderGameSpy)
+			return
+		}
+
+		restore := w.snapshotPatch(selected, w.pathTE.Text())
+		err2 := prepareForPatch(w.r, selected, func(killed map[int]string) error {
+			return waitWithProgress(w.mw, killed)
+		})
+		if err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to prepare for reverting: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()})
+			return
+		}
+
+		if err2 = clearWriteProtection(w.pathTE.Text()); err2 != nil {
+			if errors.Is(err2, os.ErrPermission) && w.offerElevation("Removing write protection") {
+				return
+			}
+			showError(w.mw, fmt.Sprintf("Failed to remove write protection: %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()})
+			return
+		}
+
+		if !confirmPreflight(selected) || !ensureWritable(selected) || !confirmNotLocked(selected) {
+			return
+		}
+
+		err2 = patchOrAdopt("Reverting to GameSpy", selected, w.pathTE.Text(), patchable.ProviderGameSpy, nil)
+		if restore != nil {
+			w.pushUndo("reverting to GameSpy", restore)
+		}
+		if err2 != nil {
+			if errors.Is(err2, os.ErrPermission) && w.offerElevation("Patching the executables") {
+				return
+			}
+			showError(w.mw, fmt.Sprintf("Failed to patch %s", err2.Error()), err2, errorDetail{Name: "Folder", Value: w.pathTE.Text()})
+		} else {
+			w.recordPatch(selected, w.pathTE.Text())
+			w.summary.record("Reverted executables to use GameSpy", fmt.Sprintf("Folder: %s", w.pathTE.Text()), fmt.Sprintf("Executables: %s", describeExecutables(selected)))
+			walk.MsgBox(w.mw, "Success", "Reverted game to use GameSpy\n\nYou can now use provider-specific patchers again (e.g. BF2Hub Patcher)", walk.MsgBoxIconInformation)
+		}
+	}
+
+	// Restores the preset's settings, offering to patch right away
+	loadPreset := func(p preset.Preset) {
+		if p.Dir != "" {
+			enablePatch(p.Dir)
+		}
+		for i, option := range w.patchProviderCB.Model().([]providerCBOption[patch.Provider]) {
+			if string(option.Value) == p.PatchProvider {
+				_ = w.patchProviderCB.SetCurrentIndex(i)
+			}
+		}
+		for i, option := range migrateProviderOptions {
+			if option.Name == p.MigrateProvider {
+				_ = w.migrateProviderCB.SetCurrentIndex(i)
+			}
+		}
+		w.patchGameCB.SetChecked(p.PatchGame)
+		w.patchServerCB.SetChecked(p.PatchServer)
+		w.writeProtectCB.SetChecked(p.WriteProtect)
+
+		if p.PatchProvider == "" || !patchPB.Enabled() {
+			return
+		}
+
+		msg := fmt.Sprintf("Loaded preset %q\n\nPatch %s to use %s now?", p.Name, w.pathTE.Text(), p.PatchProvider)
+		if walk.MsgBox(w.mw, "Preset", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes {
+			applyPatch()
+		}
+	}
+
+	reloadPresets := func() {
+		presets, err2 := loadPresets()
+		if err2 != nil {
+			log.Error().
+				Err(err2).
+				Msg("Failed to load presets")
+			return
+		}
+		if err2 = refreshPresetsMenu(presetsMenu, presetsMenuFixedItems, presets, loadPreset); err2 != nil {
+			log.Error().
+				Err(err2).
+				Msg("Failed to update presets menu")
+		}
+	}
+
+	// Actions of the tools menu, which are also available via the quick action launcher
+	tools := []quickAction{
+		{
+			Text: "Refresh profiles",
+			Run:  refreshProfiles,
+		},
+		{
+			Text: "Migrate with manually entered credentials",
+			Run:  migrateManually,
+		},
+		{
+			Text: "Audit installation",
+			Run:  w.auditInstallation,
+		},
+		{
+			Text: "Test login",
+			Run:  testLogin,
+		},
+		{
+			Text: "Find account on all providers",
+			Run:  w.findAccountOnAllProviders,
+		},
+		{
+			Text: "Where are my accounts?",
+			Run:  w.findAccounts,
+		},
+		{
+			Text: "Check nick on provider...",
+			Run:  w.checkNick,
+		},
+		{
+			Text: "Search profiles on provider...",
+			Run:  w.searchProfiles,
+		},
+		{
+			Text: "Select fastest provider",
+			Run:  w.selectFastestProvider,
+		},
+		{
+			Text: "Migrate to existing account...",
+			Run:  w.migrateToExistingAccount,
+		},
+		{
+			Text: "Change password on provider...",
+			Run:  w.changePassword,
+		},
+		{
+			Text: "Change email on provider...",
+			Run:  w.changeEmail,
+		},
+		{
+			Text: "Export account nicks...",
+			Run:  w.exportAccountNicks,
+		},
+		{
+			Text: "Export session summary...",
+			Run:  w.exportSessionSummary,
+		},
+		{
+			Text: "Open log folder",
+			Run:  w.openLogFolder,
+		},
+		{
+			Text: "Create report for unknown binary...",
+			Run:  w.reportUnknownBinary,
+		},
+		{
+			Text: "Check for new providers",
+			Run:  w.checkForNewProviders,
+		},
+		{
+			Text: "Add custom provider...",
+			Run:  w.addCustomProvider,
+		},
+		{
+			Text: "Import provider from patched binary...",
+			Run:  w.importProvider,
+		},
+		{
+			Text: "Redirect via hosts file...",
+			Run:  w.redirectViaHosts,
+		},
+		{
+			Text: "Remove hosts file redirect",
+			Run:  w.removeHostsRedirect,
+		},
+		{
+			Text: "Patch all installations...",
+			Run:  w.patchAllInstallations,
+		},
+		{
+			Text: "Remove BF2Hub Client...",
+			Run:  w.removeBF2HubClient,
+		},
+		{
+			Text: "Patch specific executable...",
+			Run:  w.patchSpecificExecutable,
+		},
+	}
+
+	// Returns all actions currently available to the quick action launcher, including those of the main buttons
+	quickActions := func() []quickAction {
+		var actions []quickAction
+		if w.migratePB.Enabled() {
+			actions = append(actions, quickAction{Text: "Migrate selected profile", Run: migrateSelected})
+		}
+		if patchPB.Enabled() {
+			actions = append(actions, quickAction{Text: "Apply patch", Run: applyPatch})
+			for i, option := range w.patchProviderCB.Model().([]providerCBOption[patch.Provider]) {
+				index := i
+				actions = append(actions, quickAction{
+					Text: fmt.Sprintf("Patch to %s", option.Name),
+					Run: func() {
+						_ = w.patchProviderCB.SetCurrentIndex(index)
+						applyPatch()
+					},
+				})
+			}
+			actions = append(actions, quickAction{Text: "Revert patch", Run: revertPatch})
+			actions = append(actions, quickAction{Text: "Verify patch", Run: verifyPatch})
+		}
+		if w.undoPB.Enabled() {
+			actions = append(actions, quickAction{Text: "Undo last action", Run: undoLast})
+		}
+		actions = append(actions, tools...)
 
-type providerCBOption[T patch.Provider | gamespy.Provider] struct {
-	Name  string
-	Value T
-}
+		presets, err2 := loadPresets()
+		if err2 != nil {
+			log.Error().
+				Err(err2).
+				Msg("Failed to load presets")
+		}
+		for _, p := range presets {
+			// Capture the preset for the action
+			p := p
+			actions = append(actions, quickAction{
+				Text: fmt.Sprintf("Preset: %s", p.Name),
+				Run: func() {
+					loadPreset(p)
+				},
+			})
+		}
 
-func CreateMainWindow(h game.Handler, f finder, r registryRepository, c client) (*walk.MainWindow, error) {
-	icon, err := walk.NewIconFromResourceIdWithSize(2, walk.Size{Width: 256, Height: 256})
-	if err != nil {
-		return nil, err
+		return actions
 	}
 
-	screenWidth := win.GetSystemMetrics(win.SM_CXSCREEN)
-	screenHeight := win.GetSystemMetrics(win.SM_CYSCREEN)
+	// Lets the user edit the settings, applying them right away (except for the network settings)
+	editSettings := func() {
+		options := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])
+		names := make([]string, 0, len(options))
+		for _, option := range options {
+			names = append(names, option.Name)
+		}
 
-	var mw *walk.MainWindow
-	var migrateGB *walk.GroupBox
-	var profileCB *walk.ComboBox
-	var migrateProviderCB *walk.ComboBox
-	var migratePB *walk.PushButton
-	var pathTE *walk.TextEdit
-	var patchProviderCB *walk.ComboBox
-	var patchPB *walk.PushButton
-	var revertPB *walk.PushButton
+		edited, ok := showSettingsDialog(w.mw, w.prefs, names)
+		if !ok {
+			return
+		}
 
-	enablePatch := func(path string) {
-		_ = pathTE.SetText(path)
-		_ = pathTE.SetToolTipText(path)
-		patchPB.SetEnabled(true)
-		revertPB.SetEnabled(true)
-	}
+		if w.refuseInReadOnly("saving the settings") {
+			return
+		}
+
+		if err2 := saveSettings(edited); err2 != nil {
+			showError(w.mw, fmt.Sprintf("Failed to save settings: %s", err2.Error()), err2)
+			return
+		}
 
-	patchables := []patch.Patchable{
-		patchable.GameExecutable{},
-		patchable.ServerExecutable{},
+		w.prefs = edited
+		setLanguage(language(w.prefs.Language))
+		w.undo.trim(w.prefs.UndoLimit)
+		w.updateUndoButton()
 	}
 
 	if err = (declarative.MainWindow{
-		AssignTo: &mw,
+		AssignTo: &w.mw,
 		Title:    "BF2 migrator",
 		Name:     "BF2 migrator",
 		Bounds: declarative.Rectangle{
-			X:      int((screenWidth - windowWidth) / 2),
-			Y:      int((screenHeight - windowHeight) / 2),
+			X:      (screenWidth - windowWidth) / 2,
+			Y:      (screenHeight - windowHeight) / 2,
 			Width:  windowWidth,
 			Height: windowHeight,
 		},
+		MinSize: declarative.Size{Width: windowWidth, Height: windowHeight},
 		Layout:  declarative.VBox{},
 		Icon:    icon,
 		ToolBar: declarative.ToolBar{},
-		Children: []declarative.Widget{
-			declarative.GroupBox{
-				AssignTo: &migrateGB,
-				Title:    "Migrate",
-				Name:     "Migrate",
-				Layout:   declarative.VBox{},
-				Children: []declarative.Widget{
-					declarative.Label{
-						Text:       "Select profile",
-						TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
-						Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+		MenuItems: []declarative.MenuItem{
+			declarative.Menu{
+				Text: "&Tools",
+				Items: append([]declarative.MenuItem{
+					declarative.Action{
+						Text:     "Quick actions...",
+						Shortcut: declarative.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyK},
+						OnTriggered: func() {
+							showQuickActions(w.mw, quickActions())
+						},
 					},
-					declarative.ComboBox{
-						AssignTo:      &profileCB,
-						DisplayMember: "Name",
-						BindingMember: "Key",
-						Name:          "Select profile",
-						ToolTipText:   "Select profile",
-						OnCurrentIndexChanged: func() {
-							// Password actions cannot be used with singleplayer profiles, since those don't have passwords
-							if profileCB.Model().([]game.Profile)[profileCB.CurrentIndex()].Type == game.ProfileTypeMultiplayer {
-								migratePB.SetEnabled(true)
-							} else {
-								migratePB.SetEnabled(false)
-							}
+					declarative.Action{
+						AssignTo:  &readOnlyAction,
+						Text:      "Read-only mode",
+						Checkable: true,
+						Checked:   w.readOnly,
+						OnTriggered: func() {
+							setReadOnly(readOnlyAction.Checked())
 						},
 					},
-					declarative.Label{
-						Text:       "Select provider",
-						TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
-						Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+					declarative.Action{
+						Text:        "Settings...",
+						OnTriggered: editSettings,
 					},
-					declarative.ComboBox{
-						AssignTo:      &migrateProviderCB,
-						DisplayMember: "Name",
-						BindingMember: "Value",
-						Name:          "Select provider",
-						ToolTipText:   "Select provider",
-						Model: []providerCBOption[gamespy.Provider]{
-							{
-								Name:  providerNameBF2Hub,
-								Value: gamespy.ProviderBF2Hub,
-							},
-							{
-								Name:  providerNamePlayBF2,
-								Value: gamespy.ProviderPlayBF2,
-							},
-							{
-								Name:  providerNameOpenSpy,
-								Value: gamespy.ProviderOpenSpy,
-							},
-							// Not offering GameSpy (obsolete, cannot migrate anything to it)
+					declarative.Action{
+						AssignTo:  &showLogAction,
+						Text:      "Show log",
+						Checkable: true,
+						Shortcut:  declarative.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyL},
+						OnTriggered: func() {
+							setLogVisible(showLogAction.Checked())
 						},
-						CurrentIndex: 2, // Select OpenSpy as default
 					},
-					declarative.PushButton{
-						AssignTo: &migratePB,
-						Text:     "Migrate profile",
-						OnClicked: func() {
-							// Block any actions during migrations
-							mw.SetEnabled(false)
-							_ = migratePB.SetText("Migrating...")
-							defer func() {
-								_ = migratePB.SetText("Migrate profile")
-								mw.SetEnabled(true)
-							}()
-
-							provider := migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[migrateProviderCB.CurrentIndex()]
-							profile := profileCB.Model().([]game.Profile)[profileCB.CurrentIndex()]
-							migrated, err2 := migrateProfile(h, c, provider.Value, profile.Key)
-							if err2 != nil {
-								walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to migrate %q to %s: %s", profile.Name, provider.Name, err2.Error()), walk.MsgBoxIconError)
-							} else if !migrated {
-								walk.MsgBox(mw, "Skipped", fmt.Sprintf("%q is already set up on %s", profile.Name, provider.Name), walk.MsgBoxIconInformation)
-							} else {
-								walk.MsgBox(mw, "Success", fmt.Sprintf("Migrated %q to %s", profile.Name, provider.Name), walk.MsgBoxIconInformation)
+					declarative.Separator{},
+				}, buildActionMenuItems(tools)...),
+			},
+			declarative.Menu{
+				AssignTo: &presetsMenu,
+				Text:     "Pre&sets",
+				Items: []declarative.MenuItem{
+					declarative.Action{
+						Text: "Save current settings as preset...",
+						OnTriggered: func() {
+							name, ok := promptText(w.mw, "Save preset", "Preset name (e.g. \"Clan server -> OpenSpy\")", "", false)
+							if !ok || name == "" {
+								return
+							}
+
+							if w.refuseInReadOnly("saving the preset") {
+								return
+							}
+
+							p := preset.Preset{
+								Name:            name,
+								Dir:             w.pathTE.Text(),
+								PatchProvider:   string(w.patchProviderCB.Model().([]providerCBOption[patch.Provider])[w.patchProviderCB.CurrentIndex()].Value),
+								MigrateProvider: w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()].Name,
+								PatchGame:       w.patchGameCB.Checked(),
+								PatchServer:     w.patchServerCB.Checked(),
+								WriteProtect:    w.writeProtectCB.Checked(),
 							}
+							if err2 := savePreset(p); err2 != nil {
+								showError(w.mw, fmt.Sprintf("Failed to save preset: %s", err2.Error()), err2)
+								return
+							}
+
+							reloadPresets()
+						},
+					},
+					declarative.Action{
+						Text: "Delete preset...",
+						OnTriggered: func() {
+							name, ok := promptText(w.mw, "Delete preset", "Name of the preset to delete", "", false)
+							if !ok || name == "" {
+								return
+							}
+
+							if w.refuseInReadOnly("deleting the preset") {
+								return
+							}
+
+							if err2 := deletePreset(name); err2 != nil {
+								showError(w.mw, fmt.Sprintf("Failed to delete preset: %s", err2.Error()), err2)
+								return
+							}
+
+							reloadPresets()
 						},
 					},
+					declarative.Separator{},
 				},
 			},
-			declarative.GroupBox{
-				Title:  "Patch",
-				Name:   "Patch",
-				Layout: declarative.VBox{},
-				Children: []declarative.Widget{
-					declarative.Label{
-						Text:       "Installation folder",
-						TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
-						Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
-					},
-					declarative.TextEdit{
-						AssignTo: &pathTE,
-						Name:     "Installation folder",
-						ReadOnly: true,
-					},
-					declarative.HSplitter{
+		},
+		Children: []declarative.Widget{
+			declarative.TabWidget{
+				StretchFactor: 2,
+				Pages: []declarative.TabPage{
+					{
+						Title:  "Migrate",
+						Layout: declarative.VBox{},
 						Children: []declarative.Widget{
-							declarative.PushButton{
-								Text: "Detect",
-								OnClicked: func() {
-									detected, err2 := detectInstallPath(f)
-									if err2 != nil {
-										walk.MsgBox(mw, "Warning", "Could not detect game installation folder, please choose the path manually", walk.MsgBoxIconWarning)
-										return
-									}
-
-									enablePatch(detected)
-								},
-							},
-							declarative.PushButton{
-								Text: "Choose",
-								OnClicked: func() {
-									dlg := &walk.FileDialog{
-										Title: "Choose installation folder",
-									}
-
-									ok, err2 := dlg.ShowBrowseFolder(mw)
-									if err2 != nil {
-										walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to choose installation folder: %s", err2.Error()), walk.MsgBoxIconError)
-										return
-									} else if !ok {
-										// User canceled dialog
-										return
-									}
-
-									enablePatch(dlg.FilePath)
+							declarative.GroupBox{
+								AssignTo: &migrateGB,
+								Title:    "Migrate",
+								Name:     "Migrate",
+								Layout:   declarative.VBox{},
+								Children: []declarative.Widget{
+									declarative.Label{
+										Text:       "Select profile",
+										TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
+										Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+									},
+									declarative.Composite{
+										Layout: declarative.HBox{MarginsZero: true},
+										Children: []declarative.Widget{
+											declarative.ComboBox{
+												AssignTo:      &w.profileCB,
+												DisplayMember: "Name",
+												BindingMember: "Key",
+												Name:          "Select profile",
+												ToolTipText:   "Select profile",
+												StretchFactor: 1,
+												OnCurrentIndexChanged: func() {
+													// Model is being replaced
+													if w.profileCB.CurrentIndex() < 0 {
+														return
+													}
+													// Password actions cannot be used with singleplayer profiles, since those don't have passwords
+													if w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()].Type == game.ProfileTypeMultiplayer {
+														w.migratePB.SetEnabled(true)
+													} else {
+														w.migratePB.SetEnabled(false)
+													}
+												},
+											},
+											declarative.PushButton{
+												Text:        "Re&fresh",
+												ToolTipText: "Reload profiles (e.g. after creating a profile in-game)",
+												OnClicked:   refreshProfiles,
+											},
+										},
+									},
+									declarative.Label{
+										Text:       "Select provider",
+										TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
+										Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+									},
+									declarative.ComboBox{
+										AssignTo:      &w.migrateProviderCB,
+										DisplayMember: "Name",
+										BindingMember: "Value",
+										Name:          "Select provider",
+										ToolTipText:   "Select provider",
+										Model:         migrateProviderOptions,
+										CurrentIndex:  2, // Select OpenSpy as default
+									},
+									declarative.Composite{
+										Layout: declarative.HBox{MarginsZero: true},
+										Children: []declarative.Widget{
+											declarative.PushButton{
+												AssignTo:      &w.migratePB,
+												Text:          "&Migrate profile",
+												StretchFactor: 1,
+												OnClicked:     migrateSelected,
+											},
+											declarative.PushButton{
+												Text:        "&Test login",
+												ToolTipText: "Log in to the selected provider using the profile's credentials, as the game would",
+												OnClicked:   testLogin,
+											},
+										},
+									},
+									declarative.PushButton{
+										Text:        "Enter &credentials manually...",
+										ToolTipText: "Migrate by entering nick, email and password (e.g. if your profile is gone after reinstalling Windows)",
+										OnClicked:   migrateManually,
+									},
 								},
 							},
+							declarative.VSpacer{},
 						},
 					},
-					declarative.VSpacer{Size: 1},
-					declarative.Composite{
-						Layout: declarative.VBox{
-							MarginsZero: true,
-						},
+					{
+						Title:  "Patch",
+						Layout: declarative.VBox{},
 						Children: []declarative.Widget{
-							declarative.Label{
-								Text:       "Select provider",
-								TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
-								Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
-							},
-							declarative.ComboBox{
-								AssignTo:      &patchProviderCB,
-								DisplayMember: "Name",
-								BindingMember: "Value",
-								Name:          "Select provider",
-								ToolTipText:   "Select provider",
-								Model: []providerCBOption[patch.Provider]{
-									// Not offering BF2Hub (needs a .dll in addition to .exe changes)
-									{
-										Name:  providerNamePlayBF2,
-										Value: patchable.ProviderPlayBF2,
+							declarative.GroupBox{
+								Title:  "Patch",
+								Name:   "Patch",
+								Layout: declarative.VBox{},
+								Children: []declarative.Widget{
+									declarative.Label{
+										Text:       "Installation folder",
+										TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
+										Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
 									},
-									{
-										Name:  providerNameOpenSpy,
-										Value: patchable.ProviderOpenSpy,
+									declarative.TextEdit{
+										AssignTo: &w.pathTE,
+										Name:     "Installation folder",
+										ReadOnly: true,
 									},
-									// Not offering GameSpy (obsolete, only used for reverting)
-								},
-								CurrentIndex: 1, // Select OpenSpy as default
-							},
-							declarative.HSplitter{
-								Children: []declarative.Widget{
-									declarative.PushButton{
-										AssignTo: &patchPB,
-										Text:     "Apply patch",
-										Enabled:  false,
-										OnClicked: func() {
-											// Block any actions during patching
-											mw.SetEnabled(false)
-											_ = patchPB.SetText("Patching...")
-											defer func() {
-												_ = patchPB.SetText("Apply patch")
-												mw.SetEnabled(true)
-											}()
-
-											err2 := prepareForPatch(r)
-											if err2 != nil {
-												walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to prepare for patching: %s", err2.Error()), walk.MsgBoxIconError)
-												return
-											}
-
-											provider := patchProviderCB.Model().([]providerCBOption[patch.Provider])[patchProviderCB.CurrentIndex()]
-											err2 = patchAll(patchables, pathTE.Text(), provider.Value)
-											if err2 != nil {
-												walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to patch %s", err2.Error()), walk.MsgBoxIconError)
-											} else {
-												walk.MsgBox(mw, "Success", fmt.Sprintf("Patched game to use %s", provider.Name), walk.MsgBoxIconInformation)
-											}
+									declarative.Label{
+										AssignTo:   &versionLB,
+										Text:       "Detected version: -",
+										TextColor:  walk.Color(win.GetSysColor(win.COLOR_GRAYTEXT)),
+										Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+									},
+									declarative.HSplitter{
+										Children: []declarative.Widget{
+											declarative.PushButton{
+												Text: "&Detect",
+												OnClicked: func() {
+													installations := findInstallations(w.r, w.patchables)
+													if len(installations) == 0 {
+														walk.MsgBox(w.mw, "Warning", "Could not detect game installation folder, please choose the path manually", walk.MsgBoxIconWarning)
+														return
+													}
+
+													if chosen, ok := chooseInstallation(w.mw, installations); ok {
+														enablePatch(chosen.Dir)
+													}
+												},
+											},
+											declarative.PushButton{
+												Text: "&Choose",
+												OnClicked: func() {
+													dlg := &walk.FileDialog{
+														Title: "Choose installation folder",
+													}
+
+													ok, err2 := dlg.ShowBrowseFolder(w.mw)
+													if err2 != nil {
+														showError(w.mw, fmt.Sprintf("Failed to choose installation folder: %s", err2.Error()), err2)
+														return
+													} else if !ok {
+														// User canceled dialog
+														return
+													}
+
+													looksValid, err2 := checkInstallDir(w.patchables, dlg.FilePath)
+													if err2 != nil {
+														showError(w.mw, fmt.Sprintf("%s is not a game installation folder: %s\n\nPlease choose the folder containing the game executable.", dlg.FilePath, err2.Error()), err2)
+														return
+													}
+													if !looksValid {
+														msg := fmt.Sprintf("The game executable in %s does not look like a Battlefield 2 binary. Patching it will likely fail.\n\nUse this folder anyway?", dlg.FilePath)
+														if walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+															return
+														}
+													}
+
+													enablePatch(dlg.FilePath)
+												},
+											},
 										},
 									},
-									declarative.PushButton{
-										AssignTo: &revertPB,
-										Text:     "Revert patch",
-										Enabled:  false,
-										OnClicked: func() {
-											// Block any actions during patching
-											mw.SetEnabled(false)
-											_ = revertPB.SetText("Reverting...")
-											defer func() {
-												_ = revertPB.SetText("Revert patch")
-												mw.SetEnabled(true)
-											}()
-
-											err2 := prepareForPatch(r)
-											if err2 != nil {
-												walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to prepare for reverting: %s", err2.Error()), walk.MsgBoxIconError)
-												return
-											}
-
-											err2 = patchAll(patchables, pathTE.Text(), patchable.ProviderGameSpy)
-											if err2 != nil {
-												walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to patch %s", err2.Error()), walk.MsgBoxIconError)
-											} else {
-												walk.MsgBox(mw, "Success", "Reverted game to use GameSpy\n\nYou can now use provider-specific patchers again (e.g. BF2Hub Patcher)", walk.MsgBoxIconInformation)
-											}
+									declarative.VSpacer{Size: 1},
+									declarative.Composite{
+										Layout: declarative.VBox{
+											MarginsZero: true,
+										},
+										Children: []declarative.Widget{
+											declarative.Label{
+												Text:       "Select provider",
+												TextColor:  walk.Color(win.GetSysColor(win.COLOR_CAPTIONTEXT)),
+												Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
+											},
+											declarative.ComboBox{
+												AssignTo:      &w.patchProviderCB,
+												DisplayMember: "Name",
+												BindingMember: "Value",
+												Name:          "Select provider",
+												ToolTipText:   "Select provider",
+												Model:         buildPatchProviderOptions(w.catalog),
+												CurrentIndex:  2, // Select OpenSpy as default
+											},
+											declarative.Composite{
+												Layout: declarative.HBox{
+													MarginsZero: true,
+												},
+												Children: []declarative.Widget{
+													declarative.CheckBox{
+														AssignTo:    &w.patchGameCB,
+														Text:        "&Game executable",
+														ToolTipText: "Patch the game executable (e.g. BF2.exe)",
+														Checked:     w.o.PatchGame,
+													},
+													declarative.CheckBox{
+														AssignTo:    &w.patchServerCB,
+														Text:        "Ser&ver executable",
+														ToolTipText: "Patch the dedicated server executable (e.g. bf2_w32ded.exe)",
+														Checked:     w.o.PatchServer,
+													},
+												},
+											},
+											declarative.CheckBox{
+												AssignTo:    &w.writeProtectCB,
+												Text:        "&Write-protect patched executables",
+												ToolTipText: "Prevents other patchers (e.g. the BF2Hub Client) from modifying the executables again",
+											},
+											declarative.HSplitter{
+												Children: []declarative.Widget{
+													declarative.PushButton{
+														AssignTo:  &patchPB,
+														Text:      "Apply &patch",
+														Enabled:   false,
+														OnClicked: applyPatch,
+													},
+													declarative.PushButton{
+														AssignTo:  &revertPB,
+														Text:      "&Revert patch",
+														Enabled:   false,
+														OnClicked: revertPatch,
+													},
+													declarative.PushButton{
+														AssignTo:    &verifyPB,
+														Text:        "V&erify",
+														ToolTipText: "Check which provider the game will connect to",
+														Enabled:     false,
+														OnClicked:   verifyPatch,
+													},
+													declarative.PushButton{
+														AssignTo:    &w.undoPB,
+														Text:        "&Undo",
+														ToolTipText: "Nothing to undo",
+														Enabled:     false,
+														OnClicked:   undoLast,
+													},
+												},
+											},
 										},
 									},
 								},
 							},
+							declarative.VSpacer{},
+						},
+					},
+					{
+						Title:  "Tools",
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.ScrollView{
+								HorizontalFixed: true,
+								Layout:          declarative.VBox{},
+								Children:        append(buildActionButtons(tools), declarative.VSpacer{}),
+							},
+						},
+					},
+					{
+						Title:  "Settings",
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.CheckBox{
+								AssignTo:    &readOnlyCB,
+								Text:        "Read-only mode",
+								ToolTipText: "Only run detection/diagnostics, refusing to make any changes",
+								Checked:     w.readOnly,
+								OnCheckedChanged: func() {
+									setReadOnly(readOnlyCB.Checked())
+								},
+							},
+							declarative.CheckBox{
+								AssignTo: &showLogCB,
+								Text:     "Show log",
+								OnCheckedChanged: func() {
+									setLogVisible(showLogCB.Checked())
+								},
+							},
+							declarative.PushButton{
+								Text:      "Edit settings...",
+								OnClicked: editSettings,
+							},
+							declarative.VSpacer{},
+						},
+					},
+				},
+			},
+			declarative.GroupBox{
+				AssignTo:      &logGB,
+				Title:         "Log",
+				StretchFactor: 1,
+				Name:          "Log",
+				Visible:       false,
+				Layout:        declarative.VBox{},
+				Children: []declarative.Widget{
+					declarative.TextEdit{
+						AssignTo: &logTE,
+						Name:     "Log",
+						ReadOnly: true,
+						VScroll:  true,
+						MinSize:  declarative.Size{Height: logPaneHeight - 60},
+					},
+					declarative.PushButton{
+						Text: "Copy to clipboard",
+						OnClicked: func() {
+							if err2 := walk.Clipboard().SetText(logs.String()); err2 != nil {
+								showError(w.mw, fmt.Sprintf("Failed to copy log to clipboard: %s", err2.Error()), err2)
+							}
 						},
 					},
 				},
 			},
 			declarative.Label{
-				Text:       "BF2 migrator v0.7.0",
+				Text:       "BF2 migrator " + version,
 				Alignment:  declarative.AlignHCenterVCenter,
 				TextColor:  walk.Color(win.GetSysColor(win.COLOR_GRAYTEXT)),
 				Background: declarative.SolidColorBrush{Color: walk.Color(win.GetSysColor(win.COLOR_BTNFACE))},
@@ -330,33 +1592,103 @@ func CreateMainWindow(h game.Handler, f finder, r registryRepository, c client)
 		return nil, err
 	}
 
-	// Disable minimize/maximize buttons and fix size
-	win.SetWindowLong(mw.Handle(), win.GWL_STYLE, win.GetWindowLong(mw.Handle(), win.GWL_STYLE) & ^win.WS_MINIMIZEBOX & ^win.WS_MAXIMIZEBOX & ^win.WS_SIZEBOX)
+	reloadPresets()
+	showKeyboardCues(w.mw)
 
-	profiles, selected, err := getProfiles(h)
-	if err != nil {
-		walk.MsgBox(mw, "Error", fmt.Sprintf("Failed to load profiles: %s\n\nProfile migration will not be available", err.Error()), walk.MsgBoxIconError)
-		_ = migrateGB.SetTitle("Migrate (unavailable: failed to load profiles)")
-		migrateProviderCB.SetEnabled(false)
-		profileCB.SetEnabled(false)
-		migratePB.SetEnabled(false)
-	} else if len(profiles) == 0 {
-		_ = migrateGB.SetTitle("Migrate (unavailable: no profiles found)")
-		migrateProviderCB.SetEnabled(false)
-		profileCB.SetEnabled(false)
-		migratePB.SetEnabled(false)
+	// Log lines are written from any goroutine, so append them on the UI thread
+	_ = logTE.SetText(logs.String())
+	logs.attach(func(line string) {
+		w.mw.Synchronize(func() {
+			logTE.AppendText(line + "\r\n")
+		})
+	})
+	recoverInterruptedPatches(w.mw, w.readOnly)
+
+	if err = reloadProfiles(); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to load profiles: %s\n\nProfiles can only be migrated by entering credentials manually", err.Error()), err)
+	}
+
+	// Pick up profiles created/deleted in-game while the migrator is open
+	if dirs, err2 := getProfilesFolderPaths(w.h); err2 != nil {
+		log.Error().
+			Err(err2).
+			Msg("Failed to determine profiles folders to watch")
+	} else if stop, err2 := watchProfiles(dirs, func() {
+		w.mw.Synchronize(func() {
+			if err3 := reloadProfiles(); err3 != nil {
+				log.Error().
+					Err(err3).
+					Msg("Failed to reload profiles")
+			}
+		})
+	}); err2 != nil {
+		log.Error().
+			Err(err2).
+			Msg("Failed to watch profiles folders")
 	} else {
-		_ = profileCB.SetModel(profiles)
-		_ = profileCB.SetCurrentIndex(selected)
+		w.mw.Disposing().Attach(stop)
+	}
+
+	if w.prefs.Provider != "" {
+		for i, option := range migrateProviderOptions {
+			if option.Name == w.prefs.Provider {
+				_ = w.migrateProviderCB.SetCurrentIndex(i)
+			}
+		}
+		for i, option := range w.patchProviderCB.Model().([]providerCBOption[patch.Provider]) {
+			if option.Name == w.prefs.Provider {
+				_ = w.patchProviderCB.SetCurrentIndex(i)
+			}
+		}
+	}
+
+	// Open the installation folder used last time, else automatically try to detect install path once, pre-filling path
+	// if path is detected. Let the user choose if multiple installations are found, since patching the wrong one
+	// (e.g. retail instead of Steam) is easily missed.
+	if w.o.Dir != "" {
+		enablePatch(w.o.Dir)
+	} else if w.prefs.LastDir != "" && containsExecutable(w.patchables, w.prefs.LastDir) {
+		enablePatch(w.prefs.LastDir)
+	} else if installations := findInstallations(w.r, w.patchables); len(installations) > 0 {
+		if chosen, ok := chooseInstallation(w.mw, installations); ok {
+			enablePatch(chosen.Dir)
+		}
 	}
 
-	// Automatically try to detect install path once, pre-filling path if path is detected
-	detected, err := detectInstallPath(f)
-	if err == nil {
-		enablePatch(detected)
+	// Help new users figure out which provider they are using (or should be using)
+	firstRun, err := isFirstRun()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to determine whether this is the first run")
+	} else if firstRun {
+		dir := w.pathTE.Text()
+		go func() {
+			rec := recommendProvider(w.h, w.c, w.patchables, dir)
+			w.mw.Synchronize(func() {
+				for i, option := range migrateProviderOptions {
+					if option.Name == rec.Provider {
+						_ = w.migrateProviderCB.SetCurrentIndex(i)
+					}
+				}
+				for i, option := range patchProviderOptions {
+					if option.Name == rec.Provider {
+						_ = w.patchProviderCB.SetCurrentIndex(i)
+					}
+				}
+
+				walk.MsgBox(w.mw, "Welcome", rec.String(), walk.MsgBoxIconInformation)
+
+				if err2 := markFirstRunDone(); err2 != nil {
+					log.Error().
+						Err(err2).
+						Msg("Failed to mark first run as done")
+				}
+			})
+		}()
 	}
 
-	return mw, nil
+	return w.mw, nil
 }
 
 func getProfiles(h game.Handler) ([]game.Profile, int, error) {
@@ -365,6 +1697,15 @@ func getProfiles(h game.Handler) ([]game.Profile, int, error) {
 		return nil, 0, err
 	}
 
+	bf2142Profiles, err := getBF2142Profiles(h)
+	if err != nil {
+		// Battlefield 2142 profiles are optional, so still show the Battlefield 2 ones
+		log.Error().
+			Err(err).
+			Msg("Failed to get Battlefield 2142 profiles")
+	}
+	profiles = append(profiles, bf2142Profiles...)
+
 	defaultProfileKey, err := bf2.GetDefaultProfileKey(h)
 	if err != nil {
 		log.Error().
@@ -383,48 +1724,53 @@ func getProfiles(h game.Handler) ([]game.Profile, int, error) {
 	return profiles, 0, nil
 }
 
-func migrateProfile(h game.Handler, c client, provider gamespy.Provider, profileKey string) (bool, error) {
-	profileCon, err := bf2.ReadProfileConfigFile(h, profileKey, bf2.ProfileConfigFileProfileCon)
+func readProfileCredentials(h game.Handler, profileKey string) (credentials, error) {
+	profileCon, err := readProfileCon(h, profileKey)
 	if err != nil {
-		return false, fmt.Errorf("failed to read profile config file: %w", err)
+		return credentials{}, fmt.Errorf("failed to read profile config file: %w", err)
 	}
 
 	nick, encrypted, err := bf2.GetEncryptedLogin(profileCon)
 	if err != nil {
-		return false, fmt.Errorf("failed to get encrypted login from profile config file: %w", err)
+		return credentials{}, fmt.Errorf("failed to get encrypted login from profile config file: %w", err)
 	}
 
 	password, err := bf2.DecryptProfileConPassword(encrypted)
 	if err != nil {
-		return false, fmt.Errorf("failed to decrypt profile password: %w", err)
+		return credentials{}, fmt.Errorf("failed to decrypt profile password: %w", err)
 	}
 
 	email, err := profileCon.GetValue(bf2.ProfileConKeyEmail)
 	if err != nil {
-		return false, fmt.Errorf("failed to get email address from profile config file: %w", err)
+		return credentials{}, fmt.Errorf("failed to get email address from profile config file: %w", err)
 	}
 
-	nicks, err := c.GetNicks(provider, email.String(), password)
-	if err != nil {
-		return false, fmt.Errorf("failed to get OpenSpy account profiles: %w", err)
-	}
+	return credentials{
+		Nick:     nick,
+		Email:    email.String(),
+		Password: password,
+	}, nil
+}
 
-	// Don't use slices package here to maintain compatibility with go 1.20 (and thus Windows 7)
-	for _, profile := range nicks {
-		if profile.UniqueNick == nick {
-			return false, nil
-		}
+// describeNextSteps lists the steps to take after migrating to the provider (as defined in the catalog), or returns an
+// empty string if there are none
+func describeNextSteps(catalog patchable.Catalog, provider string) string {
+	d, ok := catalog.Provider(patch.Provider(provider))
+	if !ok || len(d.NextSteps) == 0 {
+		return ""
 	}
 
-	err2 := c.CreateUser(provider, email.String(), password, nick)
-	if err2 != nil {
-		return false, fmt.Errorf("failed to create OpenSpy profile: %w", err2)
+	var sb strings.Builder
+	sb.WriteString("\n\nNext steps:")
+	for i, step := range d.NextSteps {
+		sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, step))
 	}
-
-	return true, nil
+	return sb.String()
 }
 
-func prepareForPatch(r registryRepository) error {
+// prepareForPatch kills any running patchable executables (and the BF2Hub Client), using wait to wait for them to
+// exit, and stops the BF2Hub Client from re-patching
+func prepareForPatch(r registryRepository, patchables []patch.Patchable, wait func(killed map[int]string) error) error {
 	processes, err := ps.Processes()
 	if err != nil {
 		return fmt.Errorf("failed to retrieve process list: %s", err)
@@ -433,7 +1779,7 @@ func prepareForPatch(r registryRepository) error {
 	killed := map[int]string{}
 	for _, process := range processes {
 		executable := process.Executable()
-		if executable == patchable.GameExecutableName || executable == patchable.ServerExecutableName || executable == bf2hubExecutableName {
+		if isPatchableExecutable(patchables, executable) || executable == bf2hubExecutableName {
 			pid := process.Pid()
 			if err = killProcess(pid); err != nil {
 				return fmt.Errorf("failed to kill process %q: %s", executable, err)
@@ -442,13 +1788,14 @@ func prepareForPatch(r registryRepository) error {
 		}
 	}
 
-	err = waitForProcessesToExit(killed)
-	if err != nil {
-		return err
+	if len(killed) > 0 {
+		if err = wait(killed); err != nil {
+			return err
+		}
 	}
 
 	// Stop BF2Hub from re-patching the binary
-	err = r.OpenKey(registry.CURRENT_USER, "SOFTWARE\\BF2Hub Systems\\BF2Hub Client", registry.QUERY_VALUE|registry.SET_VALUE, func(key registry.Key) error {
+	err = r.OpenKey(registry.CURRENT_USER, bf2hubClientKeyPath, registry.QUERY_VALUE|registry.SET_VALUE, func(key registry.Key) error {
 		if err2 := key.SetDWordValue("hrpApplyOnStartup", 0); err2 != nil {
 			return err2
 		}
@@ -463,46 +1810,177 @@ func prepareForPatch(r registryRepository) error {
 		// Ignore error if key does not exist, as it would indicate that the BF2Hub Client is not installed and thus
 		// cannot interfere with patching
 		if !errors.Is(err, registry.ErrNotExist) {
-			return err
+			// Wine's registry may not support all operations, but the BF2Hub Client is unlikely to be running there anyway
+			if !isWine() {
+				return err
+			}
+			log.Warn().
+				Err(err).
+				Msg("Failed to stop BF2Hub Client from re-patching under Wine, continuing anyway")
 		}
 	}
 
 	return nil
 }
 
-func detectInstallPath(f finder) (string, error) {
-	// Copied from https://github.com/cetteup/joinme.click-launcher/blob/089fb595adc426aab775fe40165431501a5c38c3/internal/titles/bf2.go#L37
-	dir, err := f.GetInstallDirFromSomewhere([]software_finder.Config{
-		{
-			ForType:           software_finder.RegistryFinder,
-			RegistryKey:       software_finder.RegistryKeyLocalMachine,
-			RegistryPath:      "SOFTWARE\\WOW6432Node\\Electronic Arts\\EA Games\\Battlefield 2",
-			RegistryValueName: "InstallDir",
-		},
-		{
-			ForType:           software_finder.RegistryFinder,
-			RegistryKey:       software_finder.RegistryKeyCurrentUser,
-			RegistryPath:      "SOFTWARE\\BF2Hub Systems\\BF2Hub Client",
-			RegistryValueName: "bf2Dir",
-		},
-	})
+func isPatchableExecutable(patchables []patch.Patchable, executable string) bool {
+	for _, p := range patchables {
+		if strings.EqualFold(p.GetFileName(), executable) {
+			return true
+		}
+	}
+	return false
+}
+
+// identifyGameBuild reads the game executable once to both identify the build and detect the provider it is patched for
+func identifyGameBuild(patchables []patch.Patchable, dir string) (patchable.BuildInfo, patch.Inspection, error) {
+	for _, p := range patchables {
+		if !strings.EqualFold(p.GetFileName(), patchable.GameExecutableName) {
+			continue
+		}
+
+		inspection, err := patch.Inspect(p, dir)
+		if err != nil {
+			return patchable.BuildInfo{}, patch.Inspection{}, err
+		}
+
+		info, err := patchable.IdentifyInspection(patchable.GameExecutableName, inspection)
+		return info, inspection, err
+	}
+
+	return patchable.BuildInfo{}, patch.Inspection{}, fmt.Errorf("no patchable for %s", patchable.GameExecutableName)
+}
+
+func describeGameBuild(patchables []patch.Patchable, dir string) string {
+	info, inspection, err := identifyGameBuild(patchables, dir)
+	if err != nil {
+		return fmt.Sprintf("unknown (failed to read %s)", patchable.GameExecutableName)
+	}
+
+	if inspection.DetectErr != nil {
+		return info.String()
+	}
+
+	return fmt.Sprintf("%s, patched for %s", info, inspection.Provider)
+}
+
+func formatAuditReport(report patchable.AuditReport) string {
+	if len(report.Totals) == 0 {
+		return fmt.Sprintf("Scanned %d files, found no provider-specific strings", report.Scanned)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Scanned %d files\n\n", report.Scanned))
+	shares := report.Percentages()
+	for _, share := range shares {
+		sb.WriteString(fmt.Sprintf("%s: %.0f%% (%d strings)\n", share.Provider, share.Percent, share.Count))
+	}
+
+	if len(shares) > 1 {
+		sb.WriteString("\nInstallation contains strings of multiple providers (partially patched by multiple tools?)\n")
+	}
+
+	files := make([]string, 0, len(report.Files))
+	for file := range report.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	sb.WriteString("\nFiles:\n")
+	for _, file := range files {
+		counts := make([]string, 0, len(report.Files[file]))
+		for _, share := range shares {
+			if count, ok := report.Files[file][share.Provider]; ok {
+				counts = append(counts, fmt.Sprintf("%s: %d", share.Provider, count))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s (%s)\n", file, strings.Join(counts, ", ")))
+	}
+
+	return sb.String()
+}
+
+func getConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "bf2-migrator"), nil
+}
+
+// buildPatchProviderOptions returns the default patch targets plus any patchable non-builtin providers from the catalog
+func buildPatchProviderOptions(catalog patchable.Catalog) []providerCBOption[patch.Provider] {
+	options := make([]providerCBOption[patch.Provider], len(patchProviderOptions))
+	copy(options, patchProviderOptions)
+
+	for _, d := range catalog.Providers {
+		switch d.Provider() {
+		case patchable.ProviderBF2Hub, patchable.ProviderPlayBF2, patchable.ProviderOpenSpy, patchable.ProviderGameSpy:
+			continue
+		}
+
+		// Providers with variable strings can only be patched from, not to
+		if !patch.ParsePattern(d.HostsPath).IsExact() {
+			continue
+		}
+
+		options = append(options, providerCBOption[patch.Provider]{
+			Name:  d.Name,
+			Value: d.Provider(),
+		})
+	}
+
+	return options
+}
+
+func getRemoteCatalogPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "remote-catalog.json"), nil
+}
+
+func getCatalogPath() (string, error) {
+	dir, err := getConfigDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to determine Battlefield 2 install directory: %w", err)
+		return "", err
 	}
 
-	return dir, err
+	return filepath.Join(dir, "catalog.json"), nil
 }
 
-func patchAll(patchables []patch.Patchable, dir string, new patch.Provider) error {
+func proposeDefinition(patchables []patch.Patchable, path string) (patchable.Definition, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return patchable.Definition{}, err
+	}
+
+	// Importing a binary patched for a known provider would only result in a duplicate
 	for _, p := range patchables {
-		if err := patch.Patch(p, dir, new); err != nil {
-			// Server executable is optional and not included with some installers for the game
-			if errors.Is(err, patch.ErrNotExist) && p.GetFileName() == patchable.ServerExecutableName {
-				return nil
+		for provider, fingerprint := range p.GetFingerprints() {
+			if fingerprint.Matches(b) {
+				return patchable.Definition{}, fmt.Errorf("binary is already patched for known provider %s", provider)
 			}
-			return fmt.Errorf("%s: %w", p.GetFileName(), err)
 		}
 	}
 
-	return nil
+	return patchable.ProposeDefinition(b)
+}
+
+// findRenamedPatchable determines which executable the renamed copy at path is based on, by detecting its provider
+// using each executable's fingerprints. Returns the patchable, the original executable's name and the current provider.
+func findRenamedPatchable(catalog patchable.Catalog, path string) (patch.Patchable, string, patch.Provider, error) {
+	var err error
+	for i, p := range catalog.RenamedPatchables(filepath.Base(path)) {
+		var provider patch.Provider
+		provider, err = patch.DetectProvider(p, filepath.Dir(path))
+		if err == nil {
+			return p, catalog.Executables[i].FileName, provider, nil
+		}
+	}
+
+	return nil, "", patch.ProviderUnknown, err
 }
@@ -0,0 +1,98 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// showNickConflictDialog lets the user pick one of the suggested nicks or enter a different one, since the nick is
+// already taken on the provider. Returns false if the user canceled the dialog.
+func showNickConflictDialog(owner walk.Form, providerName string, provider gamespy.Provider, nick string, suggestions []string) (string, bool) {
+	var dlg *walk.Dialog
+	var okPB, cancelPB *walk.PushButton
+	var suggestionsLB *walk.ListBox
+	var nickLE *walk.LineEdit
+
+	initial := ""
+	if len(suggestions) > 0 {
+		initial = suggestions[0]
+	}
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Nick already taken",
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 360, Height: 300},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: fmt.Sprintf("%q is already taken by another account on %s\n\nPick one of the available nicks or enter a different one", nick, providerName),
+			},
+			declarative.ListBox{
+				AssignTo: &suggestionsLB,
+				Name:     "Available nicks",
+				Model:    suggestions,
+				OnCurrentIndexChanged: func() {
+					if i := suggestionsLB.CurrentIndex(); i >= 0 {
+						_ = nickLE.SetText(suggestions[i])
+					}
+				},
+				OnItemActivated: func() {
+					dlg.Accept()
+				},
+			},
+			declarative.Label{Text: "Nick"},
+			declarative.LineEdit{
+				AssignTo: &nickLE,
+				Name:     "Nick",
+				Text:     initial,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							chosen := nickLE.Text()
+							if err2 := gamespy.ValidateNick(chosen); err2 != nil {
+								walk.MsgBox(dlg, "Error", describeError(err2), walk.MsgBoxIconError)
+								return
+							}
+							if chosen == nick {
+								walk.MsgBox(dlg, "Error", fmt.Sprintf("%q is already taken, enter a different nick", nick), walk.MsgBoxIconError)
+								return
+							}
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return "", false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return "", false
+	}
+
+	return nickLE.Text(), true
+}
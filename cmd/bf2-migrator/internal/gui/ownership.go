@@ -0,0 +1,89 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lxn/win"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Seconds to wait for the user to confirm the elevation prompt and the ownership change to complete
+	ownershipTimeout = 30
+)
+
+// findUnwritable returns the paths of the patchables' executables in dir which cannot be opened for writing
+func findUnwritable(patchables []patch.Patchable, dir string) []string {
+	var unwritable []string
+	for _, p := range patchables {
+		path := filepath.Join(dir, p.GetFileName())
+		if isWritable(path) {
+			continue
+		}
+		unwritable = append(unwritable, path)
+	}
+	return unwritable
+}
+
+// takeOwnership makes the current user the owner of paths and grants them full control, running takeown/icacls with
+// administrator privileges (triggering a UAC prompt). Windows does not report when the elevated command finishes,
+// so wait for the files to become writable instead.
+func takeOwnership(hwnd win.HWND, paths []string) error {
+	u, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to determine current user: %w", err)
+	}
+
+	commands := make([]string, 0, len(paths)*2)
+	for _, path := range paths {
+		// Unlike Go's os package, takeown/icacls do not handle long paths without the extended-length prefix
+		path = patch.LongPath(path)
+		commands = append(commands,
+			fmt.Sprintf("takeown /F \"%s\"", path),
+			fmt.Sprintf("icacls \"%s\" /grant \"%s\":F", path, u.Username),
+		)
+	}
+
+	args := fmt.Sprintf("/C %s", strings.Join(commands, " && "))
+	if !win.ShellExecute(hwnd, syscall.StringToUTF16Ptr("runas"), syscall.StringToUTF16Ptr("cmd.exe"), syscall.StringToUTF16Ptr(args), nil, win.SW_HIDE) {
+		return fmt.Errorf("failed to run elevated ownership change (elevation prompt may have been declined)")
+	}
+
+	for i := 0; i < ownershipTimeout; i++ {
+		if allWritable(paths) {
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return fmt.Errorf("files are still not writable after %d seconds", ownershipTimeout)
+}
+
+func allWritable(paths []string) bool {
+	for _, path := range paths {
+		if !isWritable(path) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWritable(path string) bool {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		// Missing files are reported by the patch itself
+		return !errors.Is(err, os.ErrPermission)
+	}
+	_ = f.Close()
+	return true
+}
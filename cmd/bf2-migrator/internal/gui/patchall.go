@@ -0,0 +1,45 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// installResult is the outcome of patching a single installation
+type installResult struct {
+	Installation installation
+	Err          error
+}
+
+// existingPatchables returns the patchables whose executables exist in dir, so server-only installations are patched
+// without reporting the missing game executable
+func existingPatchables(patchables []patch.Patchable, dir string) []patch.Patchable {
+	existing := make([]patch.Patchable, 0, len(patchables))
+	for _, p := range patchables {
+		if _, err := os.Stat(patch.LongPath(filepath.Join(dir, p.GetFileName()))); err == nil {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
+// describeInstallResults lists the result of each installation, returning whether all of them succeeded
+func describeInstallResults(results []installResult) (string, bool) {
+	succeeded := true
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			succeeded = false
+			lines = append(lines, fmt.Sprintf("%s: failed (%s)", result.Installation.Label(), result.Err.Error()))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: OK", result.Installation.Label()))
+	}
+	return strings.Join(lines, "\n"), succeeded
+}
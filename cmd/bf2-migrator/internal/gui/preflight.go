@@ -0,0 +1,155 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// preflightProblem is an issue which would make patching fail
+type preflightProblem struct {
+	Path    string
+	Problem string
+	// Whether the problem is the read-only attribute, which can be cleared without administrator privileges
+	ReadOnly bool
+	// Whether the problem cannot be fixed by the migrator
+	Fatal bool
+}
+
+func (p preflightProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Problem)
+}
+
+// findPreflightProblems checks that the patchables' executables in dir can be patched, returning all problems at once
+// rather than failing on the first one
+func findPreflightProblems(patchables []patch.Patchable, dir string) []preflightProblem {
+	var problems []preflightProblem
+	var required int64
+	for _, p := range patchables {
+		path := filepath.Join(dir, p.GetFileName())
+		stats, err := os.Stat(patch.LongPath(path))
+		if err != nil {
+			// Missing files are reported by the patch itself
+			continue
+		}
+		// The journal keeps both the original and the modified content while patching
+		required += stats.Size() * 2
+
+		if stats.Mode().Perm()&0200 == 0 {
+			problems = append(problems, preflightProblem{Path: path, Problem: "file is read-only", ReadOnly: true})
+			// Read-only files are never writable, so the remaining checks would only repeat the problem
+			continue
+		}
+
+		if !isWritable(path) {
+			problem := "access is denied"
+			if owner, ok := describeForeignOwner(path); ok {
+				problem = fmt.Sprintf("access is denied, file is owned by %s", owner)
+			}
+			problems = append(problems, preflightProblem{Path: path, Problem: problem})
+		}
+	}
+
+	if len(patchables) > 0 && !isDirWritable(dir) {
+		problems = append(problems, preflightProblem{Path: dir, Problem: "cannot create files (e.g. provider DLLs) in folder"})
+	}
+
+	if patch.JournalDir != "" && required > 0 {
+		available, err := getAvailableSpace(patch.JournalDir)
+		if err == nil && available < uint64(required) {
+			problems = append(problems, preflightProblem{
+				Path:    patch.JournalDir,
+				Problem: fmt.Sprintf("not enough free space for backups (%d MB required, %d MB available)", required/1024/1024+1, available/1024/1024),
+				Fatal:   true,
+			})
+		}
+	}
+
+	return problems
+}
+
+// describeForeignOwner returns the account owning the file if it is not the current user
+func describeForeignOwner(path string) (string, bool) {
+	sd, err := windows.GetNamedSecurityInfo(patch.LongPath(path), windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", false
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return "", false
+	}
+
+	u, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil || owner.Equals(u.User.Sid) {
+		return "", false
+	}
+
+	account, domain, _, err := owner.LookupAccount("")
+	if err != nil {
+		return owner.String(), true
+	}
+	if domain != "" {
+		return domain + "\\" + account, true
+	}
+	return account, true
+}
+
+// getAvailableSpace returns the free space available to the current user on the volume of path (or its closest
+// existing parent folder)
+func getAvailableSpace(path string) (uint64, error) {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, fmt.Errorf("no existing parent folder of %s", path)
+		}
+		path = parent
+	}
+
+	ptr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available, total, free uint64
+	if err = windows.GetDiskFreeSpaceEx(ptr, &available, &total, &free); err != nil {
+		return 0, err
+	}
+
+	return available, nil
+}
+
+// clearReadOnly removes the read-only attribute from the problems' files
+func clearReadOnly(problems []preflightProblem) error {
+	for _, p := range problems {
+		if !p.ReadOnly {
+			continue
+		}
+		if err := os.Chmod(patch.LongPath(p.Path), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describePreflightProblems lists the problems, one per line
+func describePreflightProblems(problems []preflightProblem) string {
+	lines := make([]string, 0, len(problems))
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,93 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// describeAccountPresence lists for each provider whether the account exists and whether the nick is set up on it,
+// one provider per line
+func describeAccountPresence(options []providerCBOption[gamespy.Provider], results map[gamespy.Provider]gamespy.NicksResult, nick string) string {
+	lines := make([]string, 0, len(options))
+	for _, option := range options {
+		result, ok := results[option.Value]
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", option.Name, describeNicksResult(result, nick)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// accountStatus is how far an account/nick has been migrated to a provider
+type accountStatus int
+
+const (
+	accountStatusLookupFailed accountStatus = iota
+	accountStatusNoAccount
+	accountStatusBadPassword
+	accountStatusNickMissing
+	accountStatusMigrated
+)
+
+func getAccountStatus(result gamespy.NicksResult, nick string) accountStatus {
+	var providerErr *gamespy.ProviderError
+	if errors.As(result.Err, &providerErr) {
+		switch providerErr.Code {
+		case gamespy.ErrCodeLoginBadEmail, gamespy.ErrCodeLoginBadNick, gamespy.ErrCodeLoginBadUniqueNick:
+			return accountStatusNoAccount
+		case gamespy.ErrCodeLoginBadPassword:
+			return accountStatusBadPassword
+		}
+	}
+	if result.Err != nil {
+		return accountStatusLookupFailed
+	}
+
+	if migrate.ContainsNick(result.Nicks, nick) {
+		return accountStatusMigrated
+	}
+	return accountStatusNickMissing
+}
+
+func describeNicksResult(result gamespy.NicksResult, nick string) string {
+	switch getAccountStatus(result, nick) {
+	case accountStatusNoAccount:
+		return "no account"
+	case accountStatusBadPassword:
+		return "account exists, but uses a different password"
+	case accountStatusLookupFailed:
+		return fmt.Sprintf("lookup failed (%s)", describeError(result.Err))
+	case accountStatusMigrated:
+		return fmt.Sprintf("account exists, %q is set up", nick)
+	}
+
+	if len(result.Nicks) == 0 {
+		return fmt.Sprintf("account exists without any nicks, %q is not set up", nick)
+	}
+
+	nicks := make([]string, 0, len(result.Nicks))
+	for _, n := range result.Nicks {
+		nicks = append(nicks, n.UniqueNick)
+	}
+	return fmt.Sprintf("account exists, %q is not set up (nicks: %s)", nick, strings.Join(nicks, ", "))
+}
+
+// describeProfiles lists the profiles found by a search, one profile per line
+func describeProfiles(profiles []gamespy.ProfileDTO) string {
+	lines := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		line := profile.Nick
+		if profile.UniqueNick != "" && profile.UniqueNick != profile.Nick {
+			line = fmt.Sprintf("%s (uniquenick: %s)", line, profile.UniqueNick)
+		}
+		lines = append(lines, fmt.Sprintf("%s, profile ID %s", line, profile.ProfileID))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,68 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/lxn/walk"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/preset"
+)
+
+const (
+	// Save, delete and separator
+	presetsMenuFixedItems = 3
+)
+
+func loadPresets() ([]preset.Preset, error) {
+	path, err := preset.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return preset.Load(path)
+}
+
+func savePreset(p preset.Preset) error {
+	path, err := preset.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	return preset.Save(path, p)
+}
+
+func deletePreset(name string) error {
+	path, err := preset.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	return preset.Delete(path, name)
+}
+
+// refreshPresetsMenu replaces the menu's entries following the first fixed ones with an entry for each preset
+func refreshPresetsMenu(menu *walk.Menu, fixed int, presets []preset.Preset, apply func(p preset.Preset)) error {
+	actions := menu.Actions()
+	for actions.Len() > fixed {
+		if err := actions.RemoveAt(actions.Len() - 1); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range presets {
+		// Capture the preset for the handler
+		p := p
+		action := walk.NewAction()
+		if err := action.SetText(p.Name); err != nil {
+			return err
+		}
+		action.Triggered().Attach(func() {
+			apply(p)
+		})
+		if err := actions.Add(action); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/cetteup/conman/pkg/config"
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/cetteup/conman/pkg/game/bf2"
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+)
+
+// profileIssue is a problem with a profile's config files which may cause the migration to fail
+type profileIssue struct {
+	Description string
+	// Fixes the issue in the profile's Profile.con, nil if the issue cannot be fixed automatically
+	fix func(profileCon *config.Config)
+}
+
+func (i profileIssue) Fixable() bool {
+	return i.fix != nil
+}
+
+// checkProfileConfig validates the profile's Profile.con and General.con, looking for missing/malformed login details and
+// inconsistencies between them
+func checkProfileConfig(h game.Handler, profileKey string) ([]profileIssue, error) {
+	profileCon, err := readProfileCon(h, profileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile config file: %w", err)
+	}
+
+	var issues []profileIssue
+	gamespyNick := getTrimmedValue(profileCon, bf2.ProfileConKeyGamespyNick)
+	if gamespyNick == "" {
+		issues = append(issues, profileIssue{Description: "Profile.con does not contain a login nick"})
+	} else if raw := getRawValue(profileCon, bf2.ProfileConKeyGamespyNick); raw != gamespyNick {
+		issues = append(issues, profileIssue{
+			Description: fmt.Sprintf("Login nick %q contains leading/trailing spaces", raw),
+			fix: func(profileCon *config.Config) {
+				profileCon.SetValue(bf2.ProfileConKeyGamespyNick, *config.NewQuotedValue(gamespyNick))
+			},
+		})
+	}
+
+	// The game shows the profile nick, but logs in using the login nick
+	if nick := getRawValue(profileCon, bf2.ProfileConKeyNick); gamespyNick != "" && nick != gamespyNick {
+		issues = append(issues, profileIssue{
+			Description: fmt.Sprintf("Profile nick %q does not match login nick %q", nick, gamespyNick),
+			fix: func(profileCon *config.Config) {
+				profileCon.SetValue(bf2.ProfileConKeyNick, *config.NewQuotedValue(gamespyNick))
+			},
+		})
+	}
+
+	email := getTrimmedValue(profileCon, bf2.ProfileConKeyEmail)
+	switch raw := getRawValue(profileCon, bf2.ProfileConKeyEmail); {
+	case email == "":
+		issues = append(issues, profileIssue{Description: "Profile.con does not contain an email address"})
+	case !isEmailSyntaxValid(email):
+		issues = append(issues, profileIssue{Description: fmt.Sprintf("%q is not a valid email address", email)})
+	case raw != email:
+		issues = append(issues, profileIssue{
+			Description: fmt.Sprintf("Email address %q contains leading/trailing spaces", raw),
+			fix: func(profileCon *config.Config) {
+				profileCon.SetValue(bf2.ProfileConKeyEmail, *config.NewQuotedValue(email))
+			},
+		})
+	}
+
+	if encrypted := getRawValue(profileCon, bf2.ProfileConKeyPassword); encrypted == "" {
+		issues = append(issues, profileIssue{Description: "Profile.con does not contain a password"})
+	} else if _, err2 := bf2.DecryptProfileConPassword(encrypted); err2 != nil {
+		issues = append(issues, profileIssue{Description: "Password in Profile.con cannot be decrypted"})
+	}
+
+	// The game does not need General.con to log in, but fails to load the profile if it is broken
+	if _, err2 := readProfileConfigFile(h, profileKey, bf2.ProfileConfigFileGeneralCon); err2 != nil {
+		issues = append(issues, profileIssue{Description: fmt.Sprintf("General.con cannot be read: %s", err2)})
+	}
+
+	return issues, nil
+}
+
+// fixProfileConfig applies the fixes of the issues to the profile's Profile.con, returning the previous config so the
+// fixes can be undone
+func fixProfileConfig(h game.Handler, profileKey string, issues []profileIssue) (*config.Config, error) {
+	w, ok := h.(configWriter)
+	if !ok {
+		return nil, fmt.Errorf("handler does not support writing config files")
+	}
+
+	profileCon, err := readProfileCon(h, profileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile config file: %w", err)
+	}
+	previous := config.FromBytes(profileCon.Path, profileCon.ToBytes())
+
+	for _, issue := range issues {
+		if issue.Fixable() {
+			issue.fix(profileCon)
+		}
+	}
+
+	if err = w.WriteConfigFile(profileCon); err != nil {
+		return nil, fmt.Errorf("failed to write profile config file: %w", err)
+	}
+
+	return previous, nil
+}
+
+// restoreProfileConfig writes back a profile config file as returned by fixProfileConfig
+func restoreProfileConfig(h game.Handler, profileCon *config.Config) error {
+	w, ok := h.(configWriter)
+	if !ok {
+		return fmt.Errorf("handler does not support writing config files")
+	}
+
+	return w.WriteConfigFile(profileCon)
+}
+
+func getRawValue(c *config.Config, key string) string {
+	value, err := c.GetValue(key)
+	if err != nil {
+		return ""
+	}
+	return value.String()
+}
+
+func getTrimmedValue(c *config.Config, key string) string {
+	return strings.TrimSpace(getRawValue(c, key))
+}
+
+// isEmailSyntaxValid checks only the syntax of the email address, unlike checkEmail which also checks the domain
+func isEmailSyntaxValid(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// showProfileChecklist lists the issues found with the profile, letting the user choose which fixable issues to fix
+// before migrating. Returns the issues to fix and whether to continue migrating.
+func showProfileChecklist(owner walk.Form, profileName string, issues []profileIssue, readOnly bool) ([]profileIssue, bool) {
+	var dlg *walk.Dialog
+	var continuePB, cancelPB *walk.PushButton
+	checkBoxes := make([]*walk.CheckBox, len(issues))
+
+	children := []declarative.Widget{
+		declarative.Label{
+			Text: fmt.Sprintf("Found problems with the config files of %q, which may cause the migration to fail:", profileName),
+		},
+	}
+	for i, issue := range issues {
+		text := issue.Description
+		if !issue.Fixable() {
+			text += " (cannot be fixed automatically)"
+		}
+		children = append(children, declarative.CheckBox{
+			AssignTo: &checkBoxes[i],
+			Text:     text,
+			Checked:  issue.Fixable() && !readOnly,
+			// Fixes are written to the profile, which read-only mode does not allow
+			Enabled: issue.Fixable() && !readOnly,
+		})
+	}
+	children = append(children, declarative.Composite{
+		Layout: declarative.HBox{MarginsZero: true},
+		Children: []declarative.Widget{
+			declarative.HSpacer{},
+			declarative.PushButton{
+				AssignTo: &continuePB,
+				Text:     "&Fix selected and continue",
+				OnClicked: func() {
+					dlg.Accept()
+				},
+			},
+			declarative.PushButton{
+				AssignTo: &cancelPB,
+				Text:     "Cancel",
+				OnClicked: func() {
+					dlg.Cancel()
+				},
+			},
+		},
+	})
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Check profile",
+		DefaultButton: &continuePB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 420},
+		Layout:        declarative.VBox{},
+		Children:      children,
+	}).Create(owner); err != nil {
+		return nil, false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return nil, false
+	}
+
+	var selected []profileIssue
+	for i, issue := range issues {
+		if issue.Fixable() && checkBoxes[i].Checked() {
+			selected = append(selected, issue)
+		}
+	}
+
+	return selected, true
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/cetteup/conman/pkg/handler"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// The game creates a profile's folder before writing its config files, so give it some time to finish
+	profileChangeDelay = time.Second
+)
+
+// getProfilesFolderPaths returns the Battlefield 2 and Battlefield 2142 profiles folders
+func getProfilesFolderPaths(h game.Handler) ([]string, error) {
+	bf2Dir, err := h.BuildProfilesFolderPath(handler.GameBf2)
+	if err != nil {
+		return nil, err
+	}
+
+	bf2142Dir, err := buildBF2142ProfilesFolderPath(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{bf2Dir, bf2142Dir}, nil
+}
+
+// watchProfiles calls onChange (from a background goroutine) whenever a profile folder is created, deleted or renamed
+// in any of dirs, skipping dirs which do not exist. Returns a function stopping the watcher.
+func watchProfiles(dirs []string, onChange func()) (func(), error) {
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := []windows.Handle{stop}
+	closeHandles := func() {
+		for _, h := range handles[1:] {
+			_ = windows.FindCloseChangeNotification(h)
+		}
+		_ = windows.CloseHandle(stop)
+	}
+
+	for _, dir := range dirs {
+		h, err2 := windows.FindFirstChangeNotification(dir, false, windows.FILE_NOTIFY_CHANGE_DIR_NAME)
+		if err2 != nil {
+			// Profiles folders of games which are not installed/have never been started do not exist
+			if errors.Is(err2, windows.ERROR_FILE_NOT_FOUND) || errors.Is(err2, windows.ERROR_PATH_NOT_FOUND) {
+				continue
+			}
+			closeHandles()
+			return nil, err2
+		}
+		handles = append(handles, h)
+	}
+
+	go func() {
+		defer closeHandles()
+		for {
+			event, err2 := windows.WaitForMultipleObjects(handles, false, windows.INFINITE)
+			if err2 != nil || event == windows.WAIT_OBJECT_0 {
+				return
+			}
+
+			time.Sleep(profileChangeDelay)
+			if err2 = windows.FindNextChangeNotification(handles[event-windows.WAIT_OBJECT_0]); err2 != nil {
+				return
+			}
+			onChange()
+		}
+	}()
+
+	return func() {
+		_ = windows.SetEvent(stop)
+	}, nil
+}
@@ -0,0 +1,199 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Resolution of the progress bar
+	progressRange = 1000
+)
+
+var patchStepDescriptions = map[patch.Step]string{
+	patch.StepRead:   "read binary",
+	patch.StepModify: "apply modifications",
+	patch.StepWrite:  "write binary",
+	patch.StepVerify: "verify written binary",
+}
+
+var migrateStepDescriptions = map[migrate.Step]string{
+	migrate.StepLogIn:  "Log in to provider",
+	migrate.StepCreate: "Create profile",
+	migrate.StepVerify: "Verify profile (log in)",
+}
+
+// progressFunc reports that the operation reached the step with the given index, with current and total describing
+// the progress within the step
+type progressFunc func(step, current, total int)
+
+// runWithProgress runs the operation in the background while showing a modal dialog with a progress bar and the list of
+// steps, keeping the UI responsive. The dialog cannot be closed until the operation finishes.
+func runWithProgress(owner walk.Form, title string, steps []string, run func(report progressFunc) error) error {
+	var dlg *walk.Dialog
+	var stepsLB *walk.Label
+	var progressPB *walk.ProgressBar
+
+	var mu sync.Mutex
+	done := false
+	var runErr error
+
+	if err := (declarative.Dialog{
+		AssignTo: &dlg,
+		Title:    title,
+		MinSize:  declarative.Size{Width: 360},
+		Layout:   declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				AssignTo: &stepsLB,
+				Text:     describeSteps(steps, -1, 0, 0),
+			},
+			declarative.ProgressBar{
+				AssignTo: &progressPB,
+				MaxValue: progressRange,
+			},
+		},
+	}).Create(owner); err != nil {
+		return err
+	}
+
+	// Interrupting the operation (e.g. while writing a binary) could leave things in a broken state
+	dlg.Closing().Attach(func(canceled *bool, reason walk.CloseReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			*canceled = true
+		}
+	})
+
+	showKeyboardCues(dlg)
+
+	go func() {
+		err := run(func(step, current, total int) {
+			text := describeSteps(steps, step, current, total)
+			value := step * progressRange / len(steps)
+			if total > 0 {
+				value += current * progressRange / len(steps) / total
+			}
+			dlg.Synchronize(func() {
+				_ = stepsLB.SetText(text)
+				progressPB.SetValue(value)
+			})
+		})
+
+		dlg.Synchronize(func() {
+			mu.Lock()
+			done = true
+			runErr = err
+			mu.Unlock()
+			dlg.Accept()
+		})
+	}()
+
+	dlg.Run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return runErr
+}
+
+// describeSteps lists the steps, marking those which are done and the one currently running
+func describeSteps(steps []string, active, current, total int) string {
+	lines := make([]string, 0, len(steps))
+	for i, step := range steps {
+		switch {
+		case i < active:
+			lines = append(lines, fmt.Sprintf("[x] %s", step))
+		case i == active && total > 1:
+			lines = append(lines, fmt.Sprintf("[>] %s (%d/%d)", step, current, total))
+		case i == active:
+			lines = append(lines, fmt.Sprintf("[>] %s", step))
+		default:
+			lines = append(lines, fmt.Sprintf("[ ] %s", step))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// patchWithProgress patches the executables in dir to use the new provider (reusing any inspections of them, see
+// patchable.PatchAllInspected), showing the steps of patching each of them
+func patchWithProgress(owner walk.Form, title string, patchables []patch.Patchable, dir string, new patch.Provider, inspections map[string]patch.Inspection) error {
+	names := patchable.FileNames(patchables)
+	steps := make([]string, 0, len(names)*len(patch.Steps))
+	for _, name := range names {
+		for _, step := range patch.Steps {
+			steps = append(steps, fmt.Sprintf("%s: %s", name, patchStepDescriptions[step]))
+		}
+	}
+
+	return runWithProgress(owner, title, steps, func(report progressFunc) error {
+		return patchable.PatchAllInspected(patchables, dir, new, func(fileName string, step patch.Step, current, total int) {
+			for i, name := range names {
+				if !strings.EqualFold(name, fileName) {
+					continue
+				}
+				for j, s := range patch.Steps {
+					if s == step {
+						report(i*len(patch.Steps)+j, current, total)
+					}
+				}
+			}
+		}, inspections)
+	})
+}
+
+// migrateWithProgress migrates the profile to the provider, showing the steps of the migration
+func migrateWithProgress(owner walk.Form, c migrate.Client, provider gamespy.Provider, creds *migrate.Credentials) (bool, error) {
+	steps := make([]string, 0, len(migrate.Steps))
+	for _, step := range migrate.Steps {
+		steps = append(steps, migrateStepDescriptions[step])
+	}
+
+	var migrated bool
+	err := runWithProgress(owner, "Migrating", steps, func(report progressFunc) error {
+		var err error
+		migrated, err = migrate.ProfileWithProgress(c, syncPrompter{owner: owner}, provider, creds, func(step migrate.Step) {
+			for i, s := range migrate.Steps {
+				if s == step {
+					report(i, 0, 0)
+				}
+			}
+		})
+		return err
+	})
+
+	return migrated, err
+}
+
+// syncPrompter prompts for credentials using modal dialogs shown on the UI thread, allowing to prompt from the
+// background operations run by runWithProgress
+type syncPrompter struct {
+	owner walk.Form
+}
+
+func (p syncPrompter) PromptPassword(title, message string) (string, bool) {
+	type result struct {
+		password string
+		ok       bool
+	}
+
+	results := make(chan result, 1)
+	p.owner.Synchronize(func() {
+		password, ok := dialogPrompter{owner: p.owner}.PromptPassword(title, message)
+		results <- result{password: password, ok: ok}
+	})
+
+	r := <-results
+	return r.password, r.ok
+}
@@ -0,0 +1,121 @@
+//go:build windows
+
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// writeProtect makes the patchables' executables in dir read-only, so other patchers (e.g. the BF2Hub Client) cannot
+// silently modify them again. Protected files are recorded, so only protection set by us is cleared again later.
+func writeProtect(patchables []patch.Patchable, dir string) error {
+	protected, err := readWriteProtected()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range patchables {
+		path := filepath.Join(dir, p.GetFileName())
+		if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		// On Windows, removing write permissions sets the read-only attribute
+		if err = os.Chmod(path, 0444); err != nil {
+			return err
+		}
+
+		if !containsPath(protected, path) {
+			protected = append(protected, path)
+		}
+	}
+
+	return writeWriteProtected(protected)
+}
+
+// clearWriteProtection makes all executables in dir protected by writeProtect writable again
+func clearWriteProtection(dir string) error {
+	protected, err := readWriteProtected()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]string, 0, len(protected))
+	for _, path := range protected {
+		if !strings.EqualFold(filepath.Dir(path), filepath.Clean(dir)) {
+			remaining = append(remaining, path)
+			continue
+		}
+
+		if err = os.Chmod(path, 0644); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return writeWriteProtected(remaining)
+}
+
+func readWriteProtected() ([]string, error) {
+	path, err := getWriteProtectedPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Nothing has been protected yet
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var protected []string
+	if err = json.Unmarshal(data, &protected); err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+func writeWriteProtected(protected []string) error {
+	path, err := getWriteProtectedPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(protected, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func getWriteProtectedPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "write-protected.json"), nil
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.EqualFold(p, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+//go:build windows
+
+package gui
+
+import (
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+)
+
+// quickAction is an action which can be run from a menu and/or the quick action launcher
+type quickAction struct {
+	Text string
+	Run  func()
+}
+
+func buildActionMenuItems(actions []quickAction) []declarative.MenuItem {
+	items := make([]declarative.MenuItem, 0, len(actions))
+	for _, action := range actions {
+		items = append(items, declarative.Action{
+			Text:        action.Text,
+			OnTriggered: action.Run,
+		})
+	}
+	return items
+}
+
+// buildActionButtons returns a button for each action, for listing them on the tools tab
+func buildActionButtons(actions []quickAction) []declarative.Widget {
+	buttons := make([]declarative.Widget, 0, len(actions))
+	for _, action := range actions {
+		buttons = append(buttons, declarative.PushButton{
+			Text:      action.Text,
+			OnClicked: action.Run,
+		})
+	}
+	return buttons
+}
+
+// filterQuickActions returns the actions whose text contains all words of the query (case-insensitive)
+func filterQuickActions(actions []quickAction, query string) []quickAction {
+	words := strings.Fields(strings.ToLower(query))
+	filtered := make([]quickAction, 0, len(actions))
+	for _, action := range actions {
+		text := strings.ToLower(action.Text)
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, action)
+		}
+	}
+	return filtered
+}
+
+func quickActionTexts(actions []quickAction) []string {
+	texts := make([]string, 0, len(actions))
+	for _, action := range actions {
+		texts = append(texts, strings.TrimSuffix(action.Text, "..."))
+	}
+	return texts
+}
+
+// showQuickActions shows a searchable list of the given actions, running the chosen one once the launcher is closed
+func showQuickActions(owner walk.Form, actions []quickAction) {
+	var dlg *walk.Dialog
+	var queryLE *walk.LineEdit
+	var actionsLB *walk.ListBox
+	var okPB, cancelPB *walk.PushButton
+
+	filtered := actions
+	refresh := func() {
+		filtered = filterQuickActions(actions, queryLE.Text())
+		_ = actionsLB.SetModel(quickActionTexts(filtered))
+		if len(filtered) > 0 {
+			_ = actionsLB.SetCurrentIndex(0)
+		}
+		okPB.SetEnabled(len(filtered) > 0)
+	}
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Quick actions",
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 360, Height: 320},
+		Layout:        declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.LineEdit{
+				AssignTo:  &queryLE,
+				CueBanner: "Type to search actions",
+				OnTextChanged: func() {
+					refresh()
+				},
+				OnKeyDown: func(key walk.Key) {
+					// Allow picking an action without leaving the search field
+					index := actionsLB.CurrentIndex()
+					switch {
+					case key == walk.KeyDown && index < len(filtered)-1:
+						_ = actionsLB.SetCurrentIndex(index + 1)
+					case key == walk.KeyUp && index > 0:
+						_ = actionsLB.SetCurrentIndex(index - 1)
+					}
+				},
+			},
+			declarative.ListBox{
+				AssignTo: &actionsLB,
+				Model:    quickActionTexts(actions),
+				OnItemActivated: func() {
+					dlg.Accept()
+				},
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "Run",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return
+	}
+
+	showKeyboardCues(dlg)
+
+	refresh()
+	_ = queryLE.SetFocus()
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return
+	}
+
+	index := actionsLB.CurrentIndex()
+	if index < 0 || index >= len(filtered) {
+		return
+	}
+
+	filtered[index].Run()
+}
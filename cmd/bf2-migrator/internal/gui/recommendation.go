@@ -0,0 +1,163 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	firstRunMarkerName = "initialized"
+)
+
+type recommendation struct {
+	// Name of the provider the game executable is currently patched for (empty if unknown)
+	Patched string
+	// Number of profiles registered per provider name
+	Registered map[string]int
+	// Name of the recommended provider
+	Provider string
+	Reason   string
+}
+
+func (r recommendation) String() string {
+	var sb strings.Builder
+	if r.Patched != "" {
+		sb.WriteString(fmt.Sprintf("Your game is currently set up for %s\n", r.Patched))
+	} else {
+		sb.WriteString("Could not determine which provider your game is set up for\n")
+	}
+
+	for _, option := range migrateProviderOptions {
+		if count := r.Registered[option.Name]; count > 0 {
+			sb.WriteString(fmt.Sprintf("%d of your profiles exist on %s\n", count, option.Name))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\nRecommended provider: %s\n%s", r.Provider, r.Reason))
+	return sb.String()
+}
+
+func isFirstRun() (bool, error) {
+	path, err := getFirstRunMarkerPath()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return false, nil
+}
+
+func markFirstRunDone() error {
+	path, err := getFirstRunMarkerPath()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, nil, 0644)
+}
+
+func getFirstRunMarkerPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, firstRunMarkerName), nil
+}
+
+func recommendProvider(h game.Handler, c client, patchables []patch.Patchable, dir string) recommendation {
+	r := recommendation{
+		Registered: map[string]int{},
+	}
+
+	if dir != "" {
+		provider, err := patch.DetectProvider(patchables[0], dir)
+		if err == nil && provider != patchable.ProviderGameSpy {
+			r.Patched = string(provider)
+		}
+	}
+
+	profiles, _, err := getProfiles(h)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to load profiles for provider recommendation")
+	}
+
+	for _, profile := range profiles {
+		if profile.Type != game.ProfileTypeMultiplayer {
+			continue
+		}
+
+		creds, err2 := readProfileCredentials(h, profile.Key)
+		if err2 != nil {
+			continue
+		}
+
+		for _, option := range migrateProviderOptions {
+			nicks, err3 := c.GetNicks(option.Value, creds.Email, creds.Password)
+			if err3 != nil {
+				continue
+			}
+			for _, nick := range nicks {
+				if nick.UniqueNick == creds.Nick {
+					r.Registered[option.Name]++
+					break
+				}
+			}
+		}
+	}
+
+	// Prefer staying on the provider the game is patched for, as long as it is an active one
+	if r.Patched != "" && isPatchTarget(r.Patched) {
+		r.Provider = r.Patched
+		r.Reason = "Your game is already set up for it, just make sure all your profiles are migrated to it"
+		return r
+	}
+
+	best := ""
+	for _, option := range patchProviderOptions {
+		if r.Registered[option.Name] > r.Registered[best] {
+			best = option.Name
+		}
+	}
+	if best != "" {
+		r.Provider = best
+		r.Reason = "Most of your profiles already exist on it, patch your game to use it and migrate any remaining profiles"
+		return r
+	}
+
+	r.Provider = providerNameOpenSpy
+	r.Reason = "It is the most actively maintained provider, migrate your profiles and patch your game to use it"
+	return r
+}
+
+func isPatchTarget(name string) bool {
+	for _, option := range patchProviderOptions {
+		if option.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+
+	"github.com/lxn/walk"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// recoverInterruptedPatches lets the user resume or roll back patches which were interrupted while writing an
+// executable (e.g. by a crash or power loss), since the partially written executable would not start
+func recoverInterruptedPatches(owner walk.Form, readOnly bool) {
+	interrupted, err := patch.RecoverJournal()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to read patch journal")
+	}
+
+	for _, p := range interrupted {
+		if readOnly {
+			msg := fmt.Sprintf("Patching %s was interrupted, leaving it partially written. It will not start until it is repaired.\n\nRead-only mode is enabled, so it was not repaired.", p.Path)
+			walk.MsgBox(owner, "Interrupted patch", msg, walk.MsgBoxIconWarning)
+			continue
+		}
+
+		msg := fmt.Sprintf("Patching %s was interrupted, leaving it partially written. It will not start until it is repaired.\n\nFinish patching it? Choose \"No\" to restore it to the state before patching instead.", p.Path)
+		var err2 error
+		switch walk.MsgBox(owner, "Interrupted patch", msg, walk.MsgBoxYesNoCancel|walk.MsgBoxIconWarning) {
+		case walk.DlgCmdYes:
+			err2 = p.Resume()
+		case walk.DlgCmdNo:
+			err2 = p.RollBack()
+		default:
+			continue
+		}
+
+		if err2 != nil {
+			showError(owner, fmt.Sprintf("Failed to repair %s: %s", p.Path, err2.Error()), err2)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/lxn/win"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Seconds to wait for the user to confirm the elevation prompt and the hosts file change to complete
+	redirectTimeout = 30
+)
+
+// resolveRedirect returns the hosts file entries required to redirect the GameSpy hostnames to the provider
+func resolveRedirect(catalog patchable.Catalog, provider patch.Provider) ([]hosts.Entry, error) {
+	redirects, err := catalog.Redirects(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return hosts.Resolve(redirects, hosts.LookupIPv4)
+}
+
+// applyRedirect adds the entries redirecting the GameSpy hostnames to the provider to the system's hosts file. The hosts
+// file is only writable by administrators, so the change is repeated by an elevated copy of the migrator if required.
+func applyRedirect(hwnd win.HWND, provider patch.Provider, entries []hosts.Entry) error {
+	path := hosts.DefaultPath()
+	err := hosts.Apply(path, string(provider), entries)
+	if !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	args := fmt.Sprintf("-redirect \"%s\" -hosts \"%s\"", provider, path)
+	return runElevated(hwnd, args, func() bool {
+		installed, ok, err2 := hosts.Installed(path)
+		return err2 == nil && ok && installed == string(provider)
+	})
+}
+
+// removeRedirect removes the redirect from the system's hosts file, returning whether one was found
+func removeRedirect(hwnd win.HWND) (bool, error) {
+	path := hosts.DefaultPath()
+	removed, err := hosts.Remove(path)
+	if !errors.Is(err, os.ErrPermission) {
+		return removed, err
+	}
+
+	args := fmt.Sprintf("-unredirect -hosts \"%s\"", path)
+	err = runElevated(hwnd, args, func() bool {
+		_, ok, err2 := hosts.Installed(path)
+		return err2 == nil && !ok
+	})
+	return err == nil, err
+}
+
+// runElevated runs the migrator's command line mode with administrator privileges (triggering a UAC prompt). Windows
+// does not report when the elevated process finishes, so wait for done to report the change instead.
+func runElevated(hwnd win.HWND, args string, done func() bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine migrator executable: %w", err)
+	}
+
+	if !win.ShellExecute(hwnd, syscall.StringToUTF16Ptr("runas"), syscall.StringToUTF16Ptr(executable), syscall.StringToUTF16Ptr(args), nil, win.SW_HIDE) {
+		return fmt.Errorf("failed to run elevated hosts file change (elevation prompt may have been declined)")
+	}
+
+	for i := 0; i < redirectTimeout; i++ {
+		if done() {
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return fmt.Errorf("hosts file was not changed after %d seconds", redirectTimeout)
+}
+
+// cleanHosts removes the system's hosts file entries for any of the domains which were not written by the migrator
+func cleanHosts(hwnd win.HWND, domains []string) error {
+	path := hosts.DefaultPath()
+	_, err := hosts.RemoveConflicts(path, domains)
+	if !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	args := fmt.Sprintf("-clean-hosts -hosts \"%s\"", path)
+	return runElevated(hwnd, args, func() bool {
+		conflicts, err2 := hosts.FindConflicts(path, domains)
+		return err2 == nil && len(conflicts) == 0
+	})
+}
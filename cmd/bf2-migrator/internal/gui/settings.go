@@ -0,0 +1,210 @@
+//go:build windows
+
+package gui
+
+import (
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/settings"
+)
+
+// settingsCBOption is an option of the settings dialog's combo boxes, with an empty value selecting the default
+type settingsCBOption struct {
+	Name  string
+	Value string
+}
+
+var languageOptions = []settingsCBOption{
+	{Name: "Windows display language", Value: ""},
+	{Name: "English", Value: string(languageEnglish)},
+	{Name: "Русский", Value: string(languageRussian)},
+}
+
+func loadSettings() (settings.Settings, error) {
+	path, err := settings.DefaultPath()
+	if err != nil {
+		return settings.Settings{}, err
+	}
+
+	return settings.Load(path)
+}
+
+func saveSettings(s settings.Settings) error {
+	path, err := settings.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	return settings.Save(path, s)
+}
+
+// showSettingsDialog lets the user edit the settings, returning the edited settings and whether the user saved them
+func showSettingsDialog(owner walk.Form, current settings.Settings, providers []string) (settings.Settings, bool) {
+	var dlg *walk.Dialog
+	var okPB, cancelPB *walk.PushButton
+	var providerCB, languageCB *walk.ComboBox
+	var proxyLE, resolverLE *walk.LineEdit
+	var debugPacketsCB *walk.CheckBox
+	var timeoutNE, connectTimeoutNE, readTimeoutNE, writeTimeoutNE, undoLimitNE *walk.NumberEdit
+
+	providerOptions := []settingsCBOption{{Name: "(built-in default)", Value: ""}}
+	for _, name := range providers {
+		providerOptions = append(providerOptions, settingsCBOption{Name: name, Value: name})
+	}
+
+	if err := (declarative.Dialog{
+		AssignTo:      &dlg,
+		Title:         "Settings",
+		DefaultButton: &okPB,
+		CancelButton:  &cancelPB,
+		MinSize:       declarative.Size{Width: 360},
+		Layout:        declarative.Grid{Columns: 2},
+		Children: []declarative.Widget{
+			declarative.Label{Text: "Default provider"},
+			declarative.ComboBox{
+				AssignTo:      &providerCB,
+				Name:          "Default provider",
+				DisplayMember: "Name",
+				BindingMember: "Value",
+				Model:         providerOptions,
+				CurrentIndex:  indexOfSettingsOption(providerOptions, current.Provider),
+			},
+			declarative.Label{Text: "Network timeout (seconds)"},
+			declarative.NumberEdit{
+				AssignTo: &timeoutNE,
+				Name:     "Network timeout (seconds)",
+				MinValue: 1,
+				MaxValue: 120,
+				Value:    float64(current.GetTimeout()),
+			},
+			declarative.Label{Text: "Connect timeout (seconds, 0: network timeout)"},
+			declarative.NumberEdit{
+				AssignTo: &connectTimeoutNE,
+				Name:     "Connect timeout (seconds)",
+				MinValue: 0,
+				MaxValue: 120,
+				Value:    float64(current.ConnectTimeout),
+			},
+			declarative.Label{Text: "Read timeout (seconds, 0: network timeout)"},
+			declarative.NumberEdit{
+				AssignTo: &readTimeoutNE,
+				Name:     "Read timeout (seconds)",
+				MinValue: 0,
+				MaxValue: 120,
+				Value:    float64(current.ReadTimeout),
+			},
+			declarative.Label{Text: "Write timeout (seconds, 0: network timeout)"},
+			declarative.NumberEdit{
+				AssignTo: &writeTimeoutNE,
+				Name:     "Write timeout (seconds)",
+				MinValue: 0,
+				MaxValue: 120,
+				Value:    float64(current.WriteTimeout),
+			},
+			declarative.Label{Text: "Proxy (empty: system proxy)"},
+			declarative.LineEdit{
+				AssignTo:  &proxyLE,
+				Name:      "Proxy",
+				Text:      current.Proxy,
+				CueBanner: "socks5://127.0.0.1:1080 or " + settings.ProxyDirect,
+			},
+			declarative.Label{Text: "DNS resolver (empty: system resolver)"},
+			declarative.LineEdit{
+				AssignTo:  &resolverLE,
+				Name:      "DNS resolver",
+				Text:      current.Resolver,
+				CueBanner: "1.1.1.1 or https://cloudflare-dns.com/dns-query",
+			},
+			declarative.CheckBox{
+				AssignTo:   &debugPacketsCB,
+				ColumnSpan: 2,
+				Name:       "Log GameSpy packets",
+				Text:       "Log GameSpy packets to the log file (for troubleshooting, passwords are redacted)",
+				Checked:    current.DebugPackets,
+			},
+			declarative.Label{Text: "Changes kept for undo (0: all)"},
+			declarative.NumberEdit{
+				AssignTo: &undoLimitNE,
+				Name:     "Changes kept for undo",
+				MinValue: 0,
+				MaxValue: 100,
+				Value:    float64(current.UndoLimit),
+			},
+			declarative.Label{Text: "Language"},
+			declarative.ComboBox{
+				AssignTo:      &languageCB,
+				Name:          "Language",
+				DisplayMember: "Name",
+				BindingMember: "Value",
+				Model:         languageOptions,
+				CurrentIndex:  indexOfSettingsOption(languageOptions, current.Language),
+			},
+			declarative.Label{
+				ColumnSpan: 2,
+				Text:       "Network settings are applied after restarting the migrator. The -timeout/-proxy/-resolver flags take precedence.",
+			},
+			declarative.Composite{
+				ColumnSpan: 2,
+				Layout:     declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &okPB,
+						Text:     "OK",
+						OnClicked: func() {
+							if _, err := settings.ParseProxy(proxyLE.Text()); err != nil {
+								walk.MsgBox(dlg, "Error", err.Error(), walk.MsgBoxIconError)
+								return
+							}
+							if err := settings.ValidateResolver(resolverLE.Text()); err != nil {
+								walk.MsgBox(dlg, "Error", err.Error(), walk.MsgBoxIconError)
+								return
+							}
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return settings.Settings{}, false
+	}
+
+	showKeyboardCues(dlg)
+
+	if dlg.Run() != walk.DlgCmdOK {
+		return settings.Settings{}, false
+	}
+
+	edited := current
+	edited.Provider = providerOptions[providerCB.CurrentIndex()].Value
+	edited.Timeout = int(timeoutNE.Value())
+	edited.ConnectTimeout = int(connectTimeoutNE.Value())
+	edited.ReadTimeout = int(readTimeoutNE.Value())
+	edited.WriteTimeout = int(writeTimeoutNE.Value())
+	edited.Proxy = proxyLE.Text()
+	edited.Resolver = resolverLE.Text()
+	edited.DebugPackets = debugPacketsCB.Checked()
+	edited.UndoLimit = int(undoLimitNE.Value())
+	edited.Language = languageOptions[languageCB.CurrentIndex()].Value
+
+	return edited, true
+}
+
+// indexOfSettingsOption returns the index of the option with the given value, or 0 (the default) if there is none
+func indexOfSettingsOption(options []settingsCBOption, value string) int {
+	for i, option := range options {
+		if option.Value == value {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// sessionEntry is a change made during the session
+type sessionEntry struct {
+	Time    time.Time
+	Action  string
+	Details []string
+}
+
+// sessionSummary records the changes made during the session, so they can be handed over as a printable summary
+// (e.g. by clan admins migrating their members)
+type sessionSummary struct {
+	started time.Time
+	entries []sessionEntry
+	backups []string
+}
+
+func newSessionSummary() *sessionSummary {
+	return &sessionSummary{started: time.Now()}
+}
+
+// record notes a change for the summary, also logging it so it shows up in the log pane
+func (s *sessionSummary) record(action string, details ...string) {
+	log.Info().Strs("details", details).Msg(action)
+	s.entries = append(s.entries, sessionEntry{Time: time.Now(), Action: action, Details: details})
+}
+
+// recordBackup notes a backup taken before a change, which can be restored using undo until the migrator is closed
+func (s *sessionSummary) recordBackup(description string) {
+	s.backups = append(s.backups, fmt.Sprintf("%s Before %s", time.Now().Format("15:04"), description))
+}
+
+func (s *sessionSummary) Empty() bool {
+	return len(s.entries) == 0
+}
+
+func (s *sessionSummary) String() string {
+	var sb strings.Builder
+	sb.WriteString("BF2 migrator session summary\r\n")
+	sb.WriteString("============================\r\n\r\n")
+	sb.WriteString(fmt.Sprintf("Session: %s - %s\r\n", s.started.Format("2006-01-02 15:04"), time.Now().Format("15:04")))
+	if hostname, err := os.Hostname(); err == nil {
+		sb.WriteString(fmt.Sprintf("Computer: %s\r\n", hostname))
+	}
+	if u, err := user.Current(); err == nil {
+		sb.WriteString(fmt.Sprintf("User: %s\r\n", u.Username))
+	}
+
+	sb.WriteString("\r\nChanges\r\n-------\r\n")
+	for _, e := range s.entries {
+		sb.WriteString(fmt.Sprintf("%s %s\r\n", e.Time.Format("15:04"), e.Action))
+		for _, d := range e.Details {
+			sb.WriteString(fmt.Sprintf("      %s\r\n", d))
+		}
+	}
+
+	sb.WriteString("\r\nBackups\r\n-------\r\n")
+	if len(s.backups) == 0 {
+		sb.WriteString("None\r\n")
+	}
+	for _, b := range s.backups {
+		sb.WriteString(b + "\r\n")
+	}
+	// Undo backups only exist in memory, so make sure nobody relies on them after handing over the summary
+	sb.WriteString("\r\nBackups are kept in memory and can only be restored (using Undo) until the migrator is closed.\r\n")
+
+	return sb.String()
+}
+
+// writeSessionSummary writes the summary as plain text, which can be opened and printed using any text editor
+func writeSessionSummary(path string, s *sessionSummary) error {
+	return os.WriteFile(path, []byte(s.String()), 0644)
+}
+
+// describeExecutables lists the patchables' executables (once per build variant group)
+func describeExecutables(patchables []patch.Patchable) string {
+	var names []string
+	for _, p := range patchables {
+		names = appendUnique(names, p.GetFileName())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,172 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/lxn/walk"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/migrate"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/settings"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// mainWindow is the state shared by the main window and the actions of its tools menu
+type mainWindow struct {
+	h game.Handler
+	r registryRepository
+	c client
+	o Options
+
+	mw                *walk.MainWindow
+	profileCB         *walk.ComboBox
+	migrateProviderCB *walk.ComboBox
+	migratePB         *walk.PushButton
+	pathTE            *walk.TextEdit
+	patchProviderCB   *walk.ComboBox
+	patchGameCB       *walk.CheckBox
+	patchServerCB     *walk.CheckBox
+	writeProtectCB    *walk.CheckBox
+	undoPB            *walk.PushButton
+
+	readOnly bool
+	undo     undoStack
+	summary  *sessionSummary
+	prefs    settings.Settings
+
+	catalogPath       string
+	remoteCatalogPath string
+	// Reloaded when providers are added, so always read the current catalog/patchables from here
+	catalog    patchable.Catalog
+	patchables []patch.Patchable
+}
+
+// patchablesFor returns the patchables of the executables in dir selected for patching
+func (w *mainWindow) patchablesFor(dir string) []patch.Patchable {
+	selected := make([]patch.Patchable, 0, len(w.patchables))
+	for _, p := range w.patchables {
+		switch {
+		case !patchable.IsServer(p) && !w.patchGameCB.Checked():
+			continue
+		case patchable.IsServer(p) && !w.patchServerCB.Checked():
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return selected
+}
+
+// refuseInReadOnly returns whether the action must stop since read-only mode is enabled, telling the user what was
+// skipped
+func (w *mainWindow) refuseInReadOnly(skipped string) bool {
+	if !w.readOnly {
+		return false
+	}
+	walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("Read-only mode is enabled, so %s was skipped", skipped), walk.MsgBoxIconInformation)
+	return true
+}
+
+// updateUndoButton shows the most recent undoable change on the undo button
+func (w *mainWindow) updateUndoButton() {
+	action, ok := w.undo.peek()
+	w.undoPB.SetEnabled(ok)
+	if ok {
+		_ = w.undoPB.SetToolTipText(fmt.Sprintf("Undo %s", action.Description))
+	} else {
+		_ = w.undoPB.SetToolTipText("Nothing to undo")
+	}
+}
+
+// pushUndo makes a change undoable until the migrator is closed
+func (w *mainWindow) pushUndo(description string, run func() error) {
+	w.undo.push(description, run)
+	w.undo.trim(w.prefs.UndoLimit)
+	w.summary.recordBackup(description)
+	w.updateUndoButton()
+}
+
+// recordPatch records the state of the patched executables, so verifying can detect later changes by other patchers
+func (w *mainWindow) recordPatch(selected []patch.Patchable, dir string) {
+	if err := recordPatched(selected, dir); err != nil {
+		log.Error().
+			Err(err).
+			Str("dir", dir).
+			Msg("Failed to record patched executables")
+	}
+}
+
+// migrationClient returns the client to migrate with, which refuses to create nicks in read-only mode
+func (w *mainWindow) migrationClient() migrate.Client {
+	if w.readOnly {
+		return migrate.ReadOnly(w.c)
+	}
+	return w.c
+}
+
+// offerElevation offers to restart the migrator as administrator, returning whether it was restarted (closing this
+// instance)
+func (w *mainWindow) offerElevation(reason string) bool {
+	// Wine does not implement UAC, so restarting would not change anything
+	if isElevated() || isWine() {
+		return false
+	}
+
+	msg := fmt.Sprintf("%s requires administrator privileges. Restart the migrator as administrator?", reason)
+	if walk.MsgBox(w.mw, "Administrator privileges required", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return false
+	}
+
+	if err := restartElevated(w.mw.Handle(), w.pathTE.Text(), w.patchGameCB.Checked(), w.patchServerCB.Checked(), w.readOnly); err != nil {
+		showError(w.mw, err.Error(), err)
+		return false
+	}
+
+	_ = w.mw.Close()
+	return true
+}
+
+// exportSessionSummary saves the changes made during this session to a text file
+func (w *mainWindow) exportSessionSummary() {
+	if w.summary.Empty() {
+		walk.MsgBox(w.mw, "Skipped", "No changes have been made during this session yet", walk.MsgBoxIconInformation)
+		return
+	}
+
+	dlg := &walk.FileDialog{
+		Title:    "Export session summary",
+		Filter:   "Text files (*.txt)|*.txt",
+		FilePath: fmt.Sprintf("bf2-migrator-summary-%s.txt", time.Now().Format("2006-01-02")),
+	}
+	ok, err := dlg.ShowSave(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose export file: %s", err.Error()), err)
+		return
+	} else if !ok {
+		// User canceled dialog
+		return
+	}
+
+	if err = writeSessionSummary(dlg.FilePath, w.summary); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to export session summary: %s", err.Error()), err)
+		return
+	}
+
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Exported session summary to %s", dlg.FilePath), walk.MsgBoxIconInformation)
+}
+
+// openLogFolder opens the folder the log files are written to in the file explorer
+func (w *mainWindow) openLogFolder() {
+	if w.o.LogDir == "" {
+		walk.MsgBox(w.mw, "Warning", "Logs are not written to files, please copy the log from the log pane instead", walk.MsgBoxIconWarning)
+		return
+	}
+
+	if err := openFolder(w.mw.Handle(), w.o.LogDir); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to open log folder: %s", err.Error()), err)
+	}
+}
@@ -0,0 +1,338 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cetteup/conman/pkg/game"
+	"github.com/lxn/walk"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/readonly"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+// findAccountOnAllProviders looks up the selected profile's account on all providers
+func (w *mainWindow) findAccountOnAllProviders() {
+	if !w.migratePB.Enabled() {
+		walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+	creds, err := readProfileCredentials(w.h, profile.Key)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	providers := make([]gamespy.Provider, 0, len(migrateProviderOptions))
+	for _, option := range migrateProviderOptions {
+		providers = append(providers, option.Value)
+	}
+
+	var results map[gamespy.Provider]gamespy.NicksResult
+	_ = runWithProgress(w.mw, "Finding account", []string{"Look up account on all providers"}, func(report progressFunc) error {
+		report(0, 0, 0)
+		results = w.c.GetNicksAll(providers, creds.Email, creds.Password)
+		return nil
+	})
+
+	walk.MsgBox(w.mw, "Account", fmt.Sprintf("Account %s (profile %q):\n\n%s", creds.Email, profile.Name, describeAccountPresence(migrateProviderOptions, results, creds.Nick)), walk.MsgBoxIconInformation)
+}
+
+// findAccounts shows which providers the accounts of all multiplayer profiles are set up on
+func (w *mainWindow) findAccounts() {
+	profiles, _, err := getProfiles(w.h)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to load profiles: %s", err.Error()), err)
+		return
+	}
+
+	var rows []accountMatrixRow
+	_ = runWithProgress(w.mw, "Finding accounts", []string{"Look up profiles on all providers"}, func(report progressFunc) error {
+		rows = buildAccountMatrix(w.h, w.c, profiles, migrateProviderOptions, func(current, total int) {
+			report(0, current, total)
+		})
+		return nil
+	})
+	if len(rows) == 0 {
+		walk.MsgBox(w.mw, "Warning", "No multiplayer profiles found", walk.MsgBoxIconWarning)
+		return
+	}
+
+	showAccountMatrix(w.mw, migrateProviderOptions, rows)
+}
+
+// checkNick looks up whether a nick entered by the user is registered on the selected provider
+func (w *mainWindow) checkNick() {
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	nick, ok := promptText(w.mw, "Check nick", fmt.Sprintf("Nick to look up on %s", provider.Name), "", false)
+	if !ok || nick == "" {
+		return
+	}
+
+	exists, err := w.c.UniqueNickExists(provider.Value, nick)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to look up %q on %s: %s", nick, provider.Name, describeError(err)), err, errorDetail{Name: "Provider", Value: provider.Name})
+	} else if exists {
+		walk.MsgBox(w.mw, "Taken", fmt.Sprintf("%q is already registered on %s", nick, provider.Name), walk.MsgBoxIconInformation)
+	} else {
+		walk.MsgBox(w.mw, "Available", fmt.Sprintf("%q is not registered on %s", nick, provider.Name), walk.MsgBoxIconInformation)
+	}
+}
+
+// searchProfiles lists the profiles on the selected provider matching a nick entered by the user
+func (w *mainWindow) searchProfiles() {
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	nick, ok := promptText(w.mw, "Search profiles", fmt.Sprintf("Nick to search for on %s", provider.Name), "", false)
+	if !ok || nick == "" {
+		return
+	}
+
+	profiles, err := w.c.SearchProfiles(provider.Value, nick)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to search for %q on %s: %s", nick, provider.Name, describeError(err)), err, errorDetail{Name: "Provider", Value: provider.Name})
+	} else if len(profiles) == 0 {
+		walk.MsgBox(w.mw, "Search profiles", fmt.Sprintf("No profiles named %q found on %s", nick, provider.Name), walk.MsgBoxIconInformation)
+	} else {
+		walk.MsgBox(w.mw, "Search profiles", fmt.Sprintf("Profiles matching %q on %s:\n\n%s", nick, provider.Name, describeProfiles(profiles)), walk.MsgBoxIconInformation)
+	}
+}
+
+// migrateToExistingAccount attaches the selected profile's nick to an account the user already has on the
+// selected provider, offering to update the profile to log in to that account
+func (w *mainWindow) migrateToExistingAccount() {
+	if !w.migratePB.Enabled() {
+		walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+	creds, err := readProfileCredentials(w.h, profile.Key)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	email, ok := promptText(w.mw, "Existing account", fmt.Sprintf("Email address of your existing %s account", provider.Name), creds.Email, false)
+	if !ok || email == "" {
+		return
+	}
+
+	password, ok := promptText(w.mw, "Existing account", fmt.Sprintf("Password of your existing %s account", provider.Name), "", true)
+	if !ok || password == "" {
+		return
+	}
+
+	// Attach the profile's nick to the existing account instead of the one using the profile's credentials
+	account := credentials{
+		Nick:     creds.Nick,
+		Email:    email,
+		Password: password,
+	}
+	migrated, err := migrateWithProgress(w.mw, w.migrationClient(), provider.Value, &account)
+	if errors.Is(err, readonly.ErrReadOnly) {
+		walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("%q is not yet set up on the existing %s account\n\nRead-only mode is enabled, so it was not created", creds.Nick, provider.Name), walk.MsgBoxIconInformation)
+		return
+	} else if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to migrate %q to %s: %s", profile.Name, provider.Name, describeError(err)), err, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+
+	if migrated {
+		w.summary.record(fmt.Sprintf("Migrated profile %q to existing %s account", profile.Name, provider.Name), fmt.Sprintf("Nick: %s", creds.Nick), fmt.Sprintf("Email: %s", account.Email))
+	}
+
+	if account.Email == creds.Email && account.Password == creds.Password {
+		walk.MsgBox(w.mw, "Success", fmt.Sprintf("Migrated %q to %s", profile.Name, provider.Name), walk.MsgBoxIconInformation)
+		return
+	}
+
+	msg := fmt.Sprintf("%q is set up on the existing %s account\n\nUpdate the profile to log in using the account's email address and password?", creds.Nick, provider.Name)
+	if !migrated {
+		msg = fmt.Sprintf("%q is already set up on the existing %s account\n\nUpdate the profile to log in using the account's email address and password?", creds.Nick, provider.Name)
+	}
+	if walk.MsgBox(w.mw, "Update profile", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	if w.refuseInReadOnly("updating the profile") {
+		return
+	}
+
+	if err = updateProfileLogin(w.h, profile.Key, account.Email, account.Password); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to update profile %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+	w.pushUndo(fmt.Sprintf("updating the login of profile %q", profile.Name), func() error {
+		return updateProfileLogin(w.h, profile.Key, creds.Email, creds.Password)
+	})
+
+	w.summary.record(fmt.Sprintf("Updated profile %q to log in to the existing %s account", profile.Name, provider.Name), fmt.Sprintf("Email: %s", account.Email))
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Updated profile %q to use the existing %s account", profile.Name, provider.Name)+describeNextSteps(w.catalog, provider.Name), walk.MsgBoxIconInformation)
+}
+
+// changePassword changes the password of the selected profile's account on the selected provider, storing the new
+// password in the profile
+func (w *mainWindow) changePassword() {
+	if !w.migratePB.Enabled() {
+		walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+	creds, err := readProfileCredentials(w.h, profile.Key)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	password, ok := promptText(w.mw, "Change password", fmt.Sprintf("New password of %s on %s", creds.Email, provider.Name), "", true)
+	if !ok || password == "" {
+		return
+	}
+	confirmation, ok := promptText(w.mw, "Change password", "Repeat the new password", "", true)
+	if !ok {
+		return
+	}
+	if confirmation != password {
+		walk.MsgBox(w.mw, "Warning", "The passwords do not match", walk.MsgBoxIconWarning)
+		return
+	}
+	if password == creds.Password {
+		walk.MsgBox(w.mw, "Warning", "The new password is the same as the current one", walk.MsgBoxIconWarning)
+		return
+	}
+
+	// Profile.con only holds a single password, so other providers' accounts would no longer be logged in to
+	if walk.MsgBox(w.mw, "Change password", fmt.Sprintf("Change the password of %s on %s and store it in profile %q?\n\nAccounts on other providers keep the old password, so the game will not be able to log in to them using this profile until their password is changed as well.", creds.Email, provider.Name, profile.Name), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	if w.refuseInReadOnly("changing the password") {
+		return
+	}
+
+	err = runWithProgress(w.mw, "Changing password", []string{"Change password on provider"}, func(report progressFunc) error {
+		report(0, 0, 0)
+		return w.c.ChangePassword(provider.Value, creds.Email, creds.Password, password)
+	})
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to change the password of %s on %s: %s", creds.Email, provider.Name, describeError(err)), err, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+
+	// The old password no longer works on the provider, so changing the profile back is not offered as undo
+	if err = updateProfileLogin(w.h, profile.Key, creds.Email, password); err != nil {
+		showError(w.mw, fmt.Sprintf("Changed the password on %s, but failed to update profile %q: %s", provider.Name, profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	w.summary.record(fmt.Sprintf("Changed the password of profile %q on %s", profile.Name, provider.Name), fmt.Sprintf("Email: %s", creds.Email))
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Changed the password of %s on %s and updated profile %q", creds.Email, provider.Name, profile.Name), walk.MsgBoxIconInformation)
+}
+
+// changeEmail changes the email address of the selected profile's account on the selected provider, storing the new
+// address in the profile
+func (w *mainWindow) changeEmail() {
+	if !w.migratePB.Enabled() {
+		walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+	creds, err := readProfileCredentials(w.h, profile.Key)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	email, ok := promptText(w.mw, "Change email", fmt.Sprintf("New email address of the %s account (currently %s)", provider.Name, creds.Email), creds.Email, false)
+	if !ok || email == "" || email == creds.Email {
+		return
+	}
+	if problem := checkEmail(email); problem != "" {
+		walk.MsgBox(w.mw, "Warning", problem, walk.MsgBoxIconWarning)
+		return
+	}
+
+	// Profile.con only holds a single email address, so other providers' accounts would no longer be logged in to
+	if walk.MsgBox(w.mw, "Change email", fmt.Sprintf("Change the email address of the %s account from %s to %s and store it in profile %q?\n\nAccounts on other providers keep the old email address, so the game will not be able to log in to them using this profile until their email address is changed as well.", provider.Name, creds.Email, email, profile.Name), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	if w.refuseInReadOnly("changing the email address") {
+		return
+	}
+
+	err = runWithProgress(w.mw, "Changing email", []string{"Change email address on provider"}, func(report progressFunc) error {
+		report(0, 0, 0)
+		return w.c.ChangeEmail(provider.Value, creds.Email, creds.Password, email)
+	})
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to change the email address of %s on %s: %s", creds.Email, provider.Name, describeError(err)), err, errorDetail{Name: "Profile", Value: profile.Name}, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+
+	// The old email address no longer works on the provider, so changing the profile back is not offered as undo
+	if err = updateProfileLogin(w.h, profile.Key, email, creds.Password); err != nil {
+		showError(w.mw, fmt.Sprintf("Changed the email address on %s, but failed to update profile %q: %s", provider.Name, profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	w.summary.record(fmt.Sprintf("Changed the email address of profile %q on %s", profile.Name, provider.Name), fmt.Sprintf("Old email: %s", creds.Email), fmt.Sprintf("New email: %s", email))
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Changed the email address on %s to %s and updated profile %q", provider.Name, email, profile.Name), walk.MsgBoxIconInformation)
+}
+
+// exportAccountNicks saves the nicks of the selected profile's account on the selected provider to a file
+func (w *mainWindow) exportAccountNicks() {
+	if !w.migratePB.Enabled() {
+		walk.MsgBox(w.mw, "Warning", "Please select a multiplayer profile first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	provider := w.migrateProviderCB.Model().([]providerCBOption[gamespy.Provider])[w.migrateProviderCB.CurrentIndex()]
+	profile := w.profileCB.Model().([]game.Profile)[w.profileCB.CurrentIndex()]
+	creds, err := readProfileCredentials(w.h, profile.Key)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read credentials of %q: %s", profile.Name, err.Error()), err, errorDetail{Name: "Profile", Value: profile.Name})
+		return
+	}
+
+	nicks, err := w.c.GetNicks(provider.Value, creds.Email, creds.Password)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to get account nicks from %s: %s", provider.Name, describeError(err)), err, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+
+	if w.refuseInReadOnly(fmt.Sprintf("exporting the %d nicks", len(nicks))) {
+		return
+	}
+
+	dlg := &walk.FileDialog{
+		Title:    "Export account nicks",
+		Filter:   "CSV (*.csv)|*.csv|JSON (*.json)|*.json",
+		FilePath: fmt.Sprintf("%s-%s.csv", profile.Name, provider.Name),
+	}
+
+	ok, err := dlg.ShowSave(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose export file: %s", err.Error()), err)
+		return
+	} else if !ok {
+		// User canceled dialog
+		return
+	}
+
+	if err = exportNicks(dlg.FilePath, provider.Name, creds.Email, nicks); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to export account nicks: %s", err.Error()), err)
+		return
+	}
+
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Exported %d nicks of %q on %s", len(nicks), profile.Name, provider.Name), walk.MsgBoxIconInformation)
+}
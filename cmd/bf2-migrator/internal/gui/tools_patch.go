@@ -0,0 +1,389 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxn/walk"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// snapshotPatch backs up the executables and the BF2Hub Client settings changed by prepareForPatch, returning the
+// function to restore them (nil if backing up failed, in which case the change cannot be undone)
+func (w *mainWindow) snapshotPatch(selected []patch.Patchable, dir string) func() error {
+	settings, err := readBF2HubRepatchSettings(w.r)
+	if err != nil {
+		// Failing to back up the settings should not prevent undoing the patch itself
+		log.Error().
+			Err(err).
+			Msg("Failed to read BF2Hub Client settings")
+	}
+
+	backups, err := backupExecutables(selected, dir)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("dir", dir).
+			Msg("Failed to back up executables, patching will not be undoable")
+		return nil
+	}
+
+	return func() error {
+		return multierr.Combine(restoreExecutables(backups), restoreBF2HubRepatchSettings(w.r, settings))
+	}
+}
+
+// restoreRedirect returns the function to restore the hosts file redirect to provider (or remove it if none was
+// installed)
+func (w *mainWindow) restoreRedirect(provider string, installed bool) func() error {
+	return func() error {
+		if !installed {
+			_, err := removeRedirect(w.mw.Handle())
+			return err
+		}
+
+		entries, err := resolveRedirect(w.catalog, patch.Provider(provider))
+		if err != nil {
+			return err
+		}
+		return applyRedirect(w.mw.Handle(), patch.Provider(provider), entries)
+	}
+}
+
+// locateDLL returns a function letting the user locate a DLL missing from the installation folder
+func (w *mainWindow) locateDLL(provider string) func(dll string) (string, bool) {
+	return func(dll string) (string, bool) {
+		dlg := &walk.FileDialog{
+			Title:  fmt.Sprintf("Locate %s (included with the %s client)", dll, provider),
+			Filter: fmt.Sprintf("%s|%s", dll, dll),
+		}
+		accepted, err2 := dlg.ShowOpen(w.mw)
+		if err2 != nil || !accepted {
+			return "", false
+		}
+		return dlg.FilePath, true
+	}
+}
+
+// patchInstallation patches the selected executables in dir to use the provider, without the interactive checks done
+// when patching the chosen installation
+func (w *mainWindow) patchInstallation(dir string, provider providerCBOption[patch.Provider], dlls map[string]string) error {
+	selected := existingPatchables(w.patchablesFor(dir), dir)
+	if len(selected) == 0 {
+		return fmt.Errorf("none of the selected executables exist")
+	}
+
+	if err := clearWriteProtection(dir); err != nil {
+		return fmt.Errorf("failed to remove write protection: %w", err)
+	}
+
+	if unwritable := findUnwritable(selected, dir); len(unwritable) > 0 {
+		return fmt.Errorf("cannot write to %s", strings.Join(unwritable, ", "))
+	}
+
+	if processes, err := findLockingProcesses(selected, dir); err == nil && len(processes) > 0 {
+		return fmt.Errorf("executables are in use by %s", strings.ReplaceAll(describeLockingProcesses(processes), "\n", ", "))
+	}
+
+	if err := deployDLLs(dir, selectDLLs(dlls, selected), w.locateDLL(provider.Name)); err != nil {
+		return fmt.Errorf("failed to deploy DLLs: %w", err)
+	}
+
+	if err := patchable.PatchAll(selected, dir, provider.Value); err != nil {
+		return err
+	}
+	w.recordPatch(selected, dir)
+
+	if w.writeProtectCB.Checked() {
+		if err := writeProtect(selected, dir); err != nil {
+			return fmt.Errorf("patched, but failed to write-protect executables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// auditInstallation scans the executables and scripts of the chosen installation for provider-specific strings
+func (w *mainWindow) auditInstallation() {
+	dir := w.pathTE.Text()
+	if dir == "" {
+		walk.MsgBox(w.mw, "Warning", "Please detect or choose the installation folder first", walk.MsgBoxIconWarning)
+		return
+	}
+
+	report, err := patchable.Audit(dir, w.catalog)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to audit installation: %s", err.Error()), err)
+		return
+	}
+
+	walk.MsgBox(w.mw, "Audit", formatAuditReport(report), walk.MsgBoxIconInformation)
+}
+
+// redirectViaHosts redirects the GameSpy hostnames to the selected provider using the hosts file, as an alternative to
+// patching the executables
+func (w *mainWindow) redirectViaHosts() {
+	provider := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])[w.patchProviderCB.CurrentIndex()]
+	msg := fmt.Sprintf("Redirect the GameSpy hostnames to %s using the hosts file instead of patching the executables?\n\nThe executables must not be patched for another provider. This requires administrator privileges.", provider.Name)
+	if walk.MsgBox(w.mw, "Redirect via hosts file", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	entries, err := resolveRedirect(w.catalog, provider.Value)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to redirect to %s: %s", provider.Name, err.Error()), err, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+
+	if w.readOnly {
+		lines := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			lines = append(lines, fmt.Sprintf("%s %s", entry.IP, entry.Hostname))
+		}
+		msg := fmt.Sprintf("Would add the following hosts file entries:\n\n%s\n\nRead-only mode is enabled, so the hosts file was not modified", strings.Join(lines, "\n"))
+		walk.MsgBox(w.mw, "Read-only mode", msg, walk.MsgBoxIconInformation)
+		return
+	}
+
+	previous, installed, err := hosts.Installed(hosts.DefaultPath())
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to check hosts file: %s", err.Error()), err)
+		return
+	}
+
+	if err = applyRedirect(w.mw.Handle(), provider.Value, entries); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to redirect to %s: %s", provider.Name, err.Error()), err, errorDetail{Name: "Provider", Value: provider.Name})
+		return
+	}
+	w.pushUndo(fmt.Sprintf("redirecting to %s", provider.Name), w.restoreRedirect(previous, installed))
+
+	w.summary.record(fmt.Sprintf("Redirected GameSpy hostnames to %s via the hosts file", provider.Name))
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Redirected GameSpy hostnames to %s", provider.Name), walk.MsgBoxIconInformation)
+}
+
+// removeHostsRedirect removes the redirect added by redirectViaHosts from the hosts file
+func (w *mainWindow) removeHostsRedirect() {
+	if w.readOnly {
+		installed, ok, err := hosts.Installed(hosts.DefaultPath())
+		if err != nil {
+			showError(w.mw, fmt.Sprintf("Failed to check hosts file: %s", err.Error()), err)
+		} else if !ok {
+			walk.MsgBox(w.mw, "Skipped", "The hosts file does not contain a redirect", walk.MsgBoxIconInformation)
+		} else {
+			w.refuseInReadOnly(fmt.Sprintf("removing the redirect to %s", installed))
+		}
+		return
+	}
+
+	previous, _, err := hosts.Installed(hosts.DefaultPath())
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to check hosts file: %s", err.Error()), err)
+		return
+	}
+
+	removed, err := removeRedirect(w.mw.Handle())
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to remove redirect: %s", err.Error()), err)
+	} else if !removed {
+		walk.MsgBox(w.mw, "Skipped", "The hosts file does not contain a redirect", walk.MsgBoxIconInformation)
+	} else {
+		w.pushUndo(fmt.Sprintf("removing the redirect to %s", previous), w.restoreRedirect(previous, true))
+		w.summary.record("Removed redirect from the hosts file")
+		walk.MsgBox(w.mw, "Success", "Removed redirect from the hosts file", walk.MsgBoxIconInformation)
+	}
+}
+
+// patchAllInstallations patches the selected executables of all detected installations to use the selected provider
+func (w *mainWindow) patchAllInstallations() {
+	installations := findInstallations(w.r, w.patchables)
+	if len(installations) == 0 {
+		walk.MsgBox(w.mw, "Error", "Could not find any game or server installations, please choose the installation folder instead", walk.MsgBoxIconError)
+		return
+	}
+
+	provider := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])[w.patchProviderCB.CurrentIndex()]
+	labels := make([]string, 0, len(installations))
+	for _, i := range installations {
+		labels = append(labels, i.Label())
+	}
+	msg := fmt.Sprintf("Patch the selected executables of the following installations to use %s?\n\n%s", provider.Name, strings.Join(labels, "\n"))
+	if walk.MsgBox(w.mw, "Patch all installations", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	results := make([]installResult, 0, len(installations))
+	if w.readOnly {
+		for _, i := range installations {
+			err := patchable.DryRun(existingPatchables(w.patchablesFor(i.Dir), i.Dir), i.Dir, provider.Value)
+			results = append(results, installResult{Installation: i, Err: err})
+		}
+		described, _ := describeInstallResults(results)
+		walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("Read-only mode is enabled, so no files were modified. Patching to use %s would result in:\n\n%s", provider.Name, described), walk.MsgBoxIconInformation)
+		return
+	}
+
+	// Block any actions during patching
+	w.mw.SetEnabled(false)
+	defer w.mw.SetEnabled(true)
+
+	var all []patch.Patchable
+	var restores []func() error
+	for _, i := range installations {
+		all = append(all, w.patchablesFor(i.Dir)...)
+		if restore := w.snapshotPatch(w.patchablesFor(i.Dir), i.Dir); restore != nil {
+			restores = append(restores, restore)
+		}
+	}
+	err := prepareForPatch(w.r, all, func(killed map[int]string) error {
+		return waitWithProgress(w.mw, killed)
+	})
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to prepare for patching: %s", err.Error()), err)
+		return
+	}
+
+	dlls, err := w.catalog.RequiredDLLs(provider.Value)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to determine required DLLs: %s", err.Error()), err)
+		return
+	}
+
+	for _, i := range installations {
+		results = append(results, installResult{Installation: i, Err: w.patchInstallation(i.Dir, provider, dlls)})
+	}
+	w.pushUndo(fmt.Sprintf("patching all installations to use %s", provider.Name), func() error {
+		var err2 error
+		for _, restore := range restores {
+			err2 = multierr.Append(err2, restore())
+		}
+		return err2
+	})
+
+	described, succeeded := describeInstallResults(results)
+	w.summary.record(fmt.Sprintf("Patched all installations to use %s", provider.Name), strings.Split(described, "\n")...)
+	if !succeeded {
+		walk.MsgBox(w.mw, "Warning", fmt.Sprintf("Failed to patch some installations to use %s:\n\n%s", provider.Name, described), walk.MsgBoxIconWarning)
+		return
+	}
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Patched all installations to use %s:\n\n%s", provider.Name, described), walk.MsgBoxIconInformation)
+}
+
+// removeBF2HubClient removes the BF2Hub Client components which would otherwise re-patch the executables
+func (w *mainWindow) removeBF2HubClient() {
+	dir := w.pathTE.Text()
+	components, err := findBF2HubComponents(w.r, dir)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to look for BF2Hub Client components: %s", err.Error()), err)
+		return
+	}
+	if components.Empty() {
+		walk.MsgBox(w.mw, "Skipped", "Did not find any BF2Hub Client components", walk.MsgBoxIconInformation)
+		return
+	}
+
+	// Executables patched for BF2Hub do not start without the DLLs
+	if len(components.DLLs) > 0 {
+		for _, p := range w.patchablesFor(dir) {
+			if provider, err2 := patch.DetectProvider(p, dir); err2 == nil && provider == patchable.ProviderBF2Hub {
+				msg := fmt.Sprintf("%s is still patched for BF2Hub and would no longer start without the BF2Hub DLLs\n\nPatch it to use another provider first.", p.GetFileName())
+				walk.MsgBox(w.mw, "Warning", msg, walk.MsgBoxIconWarning)
+				return
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("Found the following BF2Hub Client components:\n\n%s\n\nRemove them? The BF2Hub Client will no longer start or re-patch the executables, but can still be uninstalled via the Windows settings.", components.Describe())
+	if walk.MsgBox(w.mw, "Remove BF2Hub Client", msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning) != walk.DlgCmdYes {
+		return
+	}
+
+	if w.refuseInReadOnly("removing the BF2Hub Client components") {
+		return
+	}
+
+	if err = removeBF2HubComponents(w.r, components); err != nil {
+		if errors.Is(err, os.ErrPermission) && w.offerElevation("Removing some of the components") {
+			return
+		}
+		showError(w.mw, fmt.Sprintf("Failed to remove some BF2Hub Client components: %s", err.Error()), err)
+		return
+	}
+
+	w.summary.record("Removed BF2Hub Client components", strings.Split(components.Describe(), "\n")...)
+	walk.MsgBox(w.mw, "Success", "Removed the BF2Hub Client components", walk.MsgBoxIconInformation)
+}
+
+// patchSpecificExecutable patches a (renamed) copy of the game or server executable chosen by the user
+func (w *mainWindow) patchSpecificExecutable() {
+	dlg := &walk.FileDialog{
+		Title:  "Choose (renamed) game or server executable",
+		Filter: "Executables (*.exe)|*.exe",
+	}
+
+	ok, err := dlg.ShowOpen(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose executable: %s", err.Error()), err)
+		return
+	} else if !ok {
+		// User canceled dialog
+		return
+	}
+
+	renamed, original, current, err := findRenamedPatchable(w.catalog, dlg.FilePath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to detect executable type: %s", err.Error()), err)
+		return
+	}
+
+	provider := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])[w.patchProviderCB.CurrentIndex()]
+	msg := fmt.Sprintf("%s is a copy of %s currently patched for %s\n\nPatch it to use %s?", renamed.GetFileName(), original, current, provider.Name)
+	if walk.MsgBox(w.mw, "Patch executable", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	dir := filepath.Dir(dlg.FilePath)
+	if w.readOnly {
+		if err = patchable.DryRun([]patch.Patchable{renamed}, dir, provider.Value); err != nil {
+			walk.MsgBox(w.mw, "Read-only mode", fmt.Sprintf("Patching %s to use %s would fail: %s", renamed.GetFileName(), provider.Name, err.Error()), walk.MsgBoxIconWarning)
+			return
+		}
+		w.refuseInReadOnly(fmt.Sprintf("patching %s", renamed.GetFileName()))
+		return
+	}
+
+	dlls, err := w.catalog.RequiredDLLs(provider.Value)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to determine required DLLs: %s", err.Error()), err)
+		return
+	}
+	if dll, ok := dlls[original]; ok {
+		err = deployDLLs(dir, map[string]string{renamed.GetFileName(): dll}, w.locateDLL(provider.Name))
+		if err != nil {
+			showError(w.mw, fmt.Sprintf("Failed to deploy DLLs: %s", err.Error()), err)
+			return
+		}
+	}
+
+	restore := w.snapshotPatch([]patch.Patchable{renamed}, dir)
+	if err = patch.Patch(renamed, dir, provider.Value); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to patch %s: %s", renamed.GetFileName(), err.Error()), err)
+		return
+	}
+
+	if restore != nil {
+		w.pushUndo(fmt.Sprintf("patching %s to use %s", renamed.GetFileName(), provider.Name), restore)
+	}
+	w.recordPatch([]patch.Patchable{renamed}, dir)
+	w.summary.record(fmt.Sprintf("Patched %s to use %s", renamed.GetFileName(), provider.Name), fmt.Sprintf("Folder: %s", dir))
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Patched %s to use %s", renamed.GetFileName(), provider.Name), walk.MsgBoxIconInformation)
+}
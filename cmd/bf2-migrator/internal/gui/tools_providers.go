@@ -0,0 +1,221 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lxn/walk"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// setCatalog replaces the provider catalog (e.g. after providers were added), offering the catalog's providers for
+// patching. The selected provider stays selected, falling back to OpenSpy if the catalog no longer contains it.
+func (w *mainWindow) setCatalog(catalog patchable.Catalog, selected patch.Provider) {
+	w.catalog = catalog
+	w.patchables = catalog.Patchables()
+
+	options := buildPatchProviderOptions(catalog)
+	index := 2 // Select OpenSpy as default
+	for i, option := range options {
+		if option.Value == selected {
+			index = i
+		}
+	}
+	_ = w.patchProviderCB.SetModel(options)
+	_ = w.patchProviderCB.SetCurrentIndex(index)
+}
+
+// selectedPatchProvider returns the provider currently selected for patching (if any)
+func (w *mainWindow) selectedPatchProvider() patch.Provider {
+	options := w.patchProviderCB.Model().([]providerCBOption[patch.Provider])
+	if i := w.patchProviderCB.CurrentIndex(); i >= 0 && i < len(options) {
+		return options[i].Value
+	}
+	return ""
+}
+
+// selectFastestProvider selects the provider with the lowest latency for both migrating and patching
+func (w *mainWindow) selectFastestProvider() {
+	latencies := measureLatencies(w.c, migrateProviderOptions)
+	fastest := latencies[0]
+	if fastest.Err != nil {
+		walk.MsgBox(w.mw, "Error", fmt.Sprintf("None of the providers are reachable\n\n%s", describeLatencies(latencies)), walk.MsgBoxIconError)
+		return
+	}
+
+	for i, option := range migrateProviderOptions {
+		if option.Name == fastest.Name {
+			_ = w.migrateProviderCB.SetCurrentIndex(i)
+		}
+	}
+	for i, option := range w.patchProviderCB.Model().([]providerCBOption[patch.Provider]) {
+		if option.Name == fastest.Name {
+			_ = w.patchProviderCB.SetCurrentIndex(i)
+		}
+	}
+
+	walk.MsgBox(w.mw, "Fastest provider", fmt.Sprintf("Selected %s\n\n%s", fastest.Name, describeLatencies(latencies)), walk.MsgBoxIconInformation)
+}
+
+// reportUnknownBinary saves a report on a binary the migrator does not recognize, which can be attached to an issue
+func (w *mainWindow) reportUnknownBinary() {
+	open := &walk.FileDialog{
+		Title:  "Choose unrecognized binary",
+		Filter: "Executables (*.exe)|*.exe",
+	}
+
+	ok, err := open.ShowOpen(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose binary: %s", err.Error()), err)
+		return
+	} else if !ok {
+		// User canceled dialog
+		return
+	}
+
+	b, err := os.ReadFile(open.FilePath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to read binary: %s", err.Error()), err)
+		return
+	}
+
+	report, err := patchable.DumpFingerprint(filepath.Base(open.FilePath), b, w.patchables)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to create report: %s", err.Error()), err)
+		return
+	}
+
+	if w.refuseInReadOnly("saving the report") {
+		return
+	}
+
+	save := &walk.FileDialog{
+		Title:    "Save report",
+		Filter:   "Text files (*.txt)|*.txt",
+		FilePath: filepath.Base(open.FilePath) + "-report.txt",
+	}
+
+	ok, err = save.ShowSave(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose report file: %s", err.Error()), err)
+		return
+	} else if !ok {
+		return
+	}
+
+	if err = os.WriteFile(save.FilePath, []byte(report), 0644); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to save report: %s", err.Error()), err)
+		return
+	}
+
+	walk.MsgBox(w.mw, "Success", "Saved report\n\nPlease attach it to a GitHub issue, it does not contain any personal data or the binary itself", walk.MsgBoxIconInformation)
+}
+
+// checkForNewProviders downloads the latest remote provider catalog
+func (w *mainWindow) checkForNewProviders() {
+	if w.refuseInReadOnly("updating the provider catalog") {
+		return
+	}
+
+	before := len(w.catalog.Providers)
+	if _, err := patchable.FetchRemoteCatalog(patchable.RemoteCatalogURL, w.remoteCatalogPath, w.o.Proxy); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to check for new providers: %s", err.Error()), err)
+		return
+	}
+
+	updated, err := patchable.LoadCatalog(w.remoteCatalogPath, w.catalogPath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to reload provider catalog: %s", err.Error()), err)
+		return
+	}
+
+	w.setCatalog(updated, w.selectedPatchProvider())
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Updated provider catalog (%d new providers)", len(updated.Providers)-before), walk.MsgBoxIconInformation)
+}
+
+// addCustomProvider saves a provider definition for a hostname entered by the user, selecting it for patching
+func (w *mainWindow) addCustomProvider() {
+	hostname, ok := promptText(w.mw, "Add custom provider", "Hostname of the provider (e.g. example.com)", "", false)
+	if !ok || hostname == "" {
+		return
+	}
+
+	definition, err := patchable.NewCustomDefinition(hostname)
+	if err == nil {
+		err = w.catalog.ValidateDefinition(definition)
+	}
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Cannot use %q: %s", hostname, err.Error()), err)
+		return
+	}
+
+	if w.refuseInReadOnly(fmt.Sprintf("saving provider %q", definition.Name)) {
+		return
+	}
+
+	if err = patchable.SaveDefinition(w.catalogPath, definition); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to save provider definition: %s", err.Error()), err)
+		return
+	}
+
+	updated, err := patchable.LoadCatalog(w.remoteCatalogPath, w.catalogPath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to reload provider catalog: %s", err.Error()), err)
+		return
+	}
+
+	w.setCatalog(updated, definition.Provider())
+
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Added custom provider %q, it is now selected as the patch target", definition.Name), walk.MsgBoxIconInformation)
+}
+
+// importProvider saves the definition of the unknown provider a binary is patched for, so it can be patched again
+func (w *mainWindow) importProvider() {
+	dlg := &walk.FileDialog{
+		Title:  "Choose binary patched by unknown patcher",
+		Filter: "Executables (*.exe)|*.exe",
+	}
+
+	ok, err := dlg.ShowOpen(w.mw)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to choose binary: %s", err.Error()), err)
+		return
+	} else if !ok {
+		// User canceled dialog
+		return
+	}
+
+	definition, err := proposeDefinition(w.patchables, dlg.FilePath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to extract provider definition: %s", err.Error()), err)
+		return
+	}
+
+	msg := fmt.Sprintf("Found strings of unknown provider\n\nHostname: %s\nHosts path: %s\n\nSave as provider %q?", definition.Hostname, definition.HostsPath, definition.Name)
+	if walk.MsgBox(w.mw, "Import provider", msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+
+	if w.refuseInReadOnly(fmt.Sprintf("saving provider %q", definition.Name)) {
+		return
+	}
+
+	if err = patchable.SaveDefinition(w.catalogPath, definition); err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to save provider definition: %s", err.Error()), err)
+		return
+	}
+
+	updated, err := patchable.LoadCatalog(w.remoteCatalogPath, w.catalogPath)
+	if err != nil {
+		showError(w.mw, fmt.Sprintf("Failed to reload provider catalog: %s", err.Error()), err)
+		return
+	}
+
+	w.setCatalog(updated, w.selectedPatchProvider())
+	walk.MsgBox(w.mw, "Success", fmt.Sprintf("Saved provider %q\n\nBinaries patched for it can now be reverted or patched to another provider", definition.Name), walk.MsgBoxIconInformation)
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/multierr"
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	bf2hubClientKeyPath = "SOFTWARE\\BF2Hub Systems\\BF2Hub Client"
+)
+
+// BF2Hub Client settings disabled by prepareForPatch to stop it from re-patching
+var bf2hubRepatchValueNames = []string{"hrpApplyOnStartup", "hrpInterval"}
+
+// undoAction reverts a single change made during the session
+type undoAction struct {
+	Description string
+	Undo        func() error
+}
+
+// undoStack contains the changes made during the session, with the most recent one last
+type undoStack []undoAction
+
+func (s *undoStack) push(description string, undo func() error) {
+	*s = append(*s, undoAction{Description: description, Undo: undo})
+}
+
+// trim drops the oldest changes, keeping at most limit changes (all if limit is 0)
+func (s *undoStack) trim(limit int) {
+	if limit > 0 && len(*s) > limit {
+		*s = append(undoStack(nil), (*s)[len(*s)-limit:]...)
+	}
+}
+
+func (s *undoStack) pop() (undoAction, bool) {
+	action, ok := s.peek()
+	if ok {
+		*s = (*s)[:len(*s)-1]
+	}
+	return action, ok
+}
+
+func (s *undoStack) peek() (undoAction, bool) {
+	if len(*s) == 0 {
+		return undoAction{}, false
+	}
+	return (*s)[len(*s)-1], true
+}
+
+// executableBackup is the content of an executable before it was modified
+type executableBackup struct {
+	path string
+	data []byte
+}
+
+// backupExecutables reads the patchables' executables in dir, skipping any which do not exist
+func backupExecutables(patchables []patch.Patchable, dir string) ([]executableBackup, error) {
+	backups := make([]executableBackup, 0, len(patchables))
+	for _, p := range patchables {
+		path := filepath.Join(dir, p.GetFileName())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to back up %s: %w", p.GetFileName(), err)
+		}
+		backups = append(backups, executableBackup{path: path, data: data})
+	}
+	return backups, nil
+}
+
+// restoreExecutables writes the backed up content back to each executable, removing any write protection first
+func restoreExecutables(backups []executableBackup) error {
+	var err error
+	cleared := map[string]bool{}
+	for _, b := range backups {
+		dir := filepath.Dir(b.path)
+		if !cleared[dir] {
+			if err2 := clearWriteProtection(dir); err2 != nil {
+				return fmt.Errorf("failed to remove write protection: %w", err2)
+			}
+			cleared[dir] = true
+		}
+
+		if err2 := os.WriteFile(b.path, b.data, 0644); err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to restore %s: %w", filepath.Base(b.path), err2))
+		}
+	}
+	return err
+}
+
+// readBF2HubRepatchSettings returns the BF2Hub Client's re-patching settings (nil if the client is not installed)
+func readBF2HubRepatchSettings(r registryRepository) (map[string]uint64, error) {
+	values := make(map[string]uint64, len(bf2hubRepatchValueNames))
+	err := r.OpenKey(registry.CURRENT_USER, bf2hubClientKeyPath, registry.QUERY_VALUE, func(key registry.Key) error {
+		for _, name := range bf2hubRepatchValueNames {
+			value, _, err := key.GetIntegerValue(name)
+			if err != nil {
+				if errors.Is(err, registry.ErrNotExist) {
+					continue
+				}
+				return err
+			}
+			values[name] = value
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return values, nil
+}
+
+// restoreBF2HubRepatchSettings writes back settings previously read by readBF2HubRepatchSettings
+func restoreBF2HubRepatchSettings(r registryRepository, values map[string]uint64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return r.OpenKey(registry.CURRENT_USER, bf2hubClientKeyPath, registry.SET_VALUE, func(key registry.Key) error {
+		for name, value := range values {
+			if err := key.SetDWordValue(name, uint32(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
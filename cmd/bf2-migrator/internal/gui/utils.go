@@ -1,3 +1,5 @@
+//go:build windows
+
 package gui
 
 import (
@@ -21,10 +23,16 @@ func killProcess(pid int) error {
 	return nil
 }
 
-func waitForProcessesToExit(processes map[int]string) error {
-	iterations := 0
-	for ; len(processes) > 0 && iterations < 5; iterations++ {
-		for pid := range processes {
+// waitForProcessesToExit waits for all processes to exit, calling progress with the processes still running and the
+// seconds elapsed once per second. Waiting stops early if progress returns false.
+func waitForProcessesToExit(processes map[int]string, progress func(running map[int]string, elapsed int) bool) error {
+	running := make(map[int]string, len(processes))
+	for pid, executable := range processes {
+		running[pid] = executable
+	}
+
+	for elapsed := 0; len(running) > 0; elapsed++ {
+		for pid := range running {
 			proc, err := ps.FindProcess(pid)
 			if err != nil {
 				return fmt.Errorf("failed to check if killed process is still running: %s", err)
@@ -32,15 +40,14 @@ func waitForProcessesToExit(processes map[int]string) error {
 
 			// Remove process from map if it exited (was no longer found)
 			if proc == nil {
-				delete(processes, pid)
+				delete(running, pid)
 			}
 		}
-		time.Sleep(1 * time.Second)
-	}
 
-	// Return error if not all processes exited yet
-	if len(processes) > 0 {
-		return fmt.Errorf("timed out waiting for killed processes to exit")
+		if len(running) == 0 || !progress(running, elapsed) {
+			break
+		}
+		time.Sleep(1 * time.Second)
 	}
 
 	return nil
@@ -0,0 +1,104 @@
+//go:build windows
+
+package gui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-ps"
+)
+
+const (
+	steamExecutableName = "steam.exe"
+	eaAppExecutableName = "EADesktop.exe"
+
+	// Relevant EAppState flags as written to appmanifest_*.acf files
+	steamStateUpdateRunning = 0x100
+	steamStateValidating    = 0x20000
+
+	// Folder containing EA App's install metadata (used to repair/verify files)
+	eaAppInstallerDirName = "__Installer"
+)
+
+var (
+	acfInstallDirRegex  = regexp.MustCompile(`"installdir"\s+"([^"]+)"`)
+	acfStateFlagsRegex  = regexp.MustCompile(`"StateFlags"\s+"(\d+)"`)
+	steamCommonDirRegex = regexp.MustCompile(`(?i)^(.+\\steamapps)\\common\\([^\\]+)`)
+)
+
+// detectFileVerification returns a description of any launcher currently verifying (or able to silently repair)
+// the files in dir, returning an empty string if none was found
+func detectFileVerification(dir string) (string, error) {
+	processes, err := ps.Processes()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve process list: %s", err)
+	}
+
+	running := map[string]bool{}
+	for _, process := range processes {
+		running[strings.ToLower(process.Executable())] = true
+	}
+
+	if running[strings.ToLower(steamExecutableName)] {
+		verifying, err := isSteamVerifying(dir)
+		if err != nil {
+			return "", err
+		}
+		if verifying {
+			return "Steam is currently verifying/updating the game files", nil
+		}
+	}
+
+	if running[strings.ToLower(eaAppExecutableName)] {
+		if _, err = os.Stat(filepath.Join(dir, eaAppInstallerDirName)); err == nil {
+			return "The EA App is running and manages this installation, it may verify and revert patched files", nil
+		}
+	}
+
+	return "", nil
+}
+
+func isSteamVerifying(dir string) (bool, error) {
+	// Steam installs are always located in <library>\steamapps\common\<installdir>
+	matches := steamCommonDirRegex.FindStringSubmatch(filepath.Clean(dir))
+	if matches == nil {
+		return false, nil
+	}
+	steamapps, installDir := matches[1], matches[2]
+
+	manifests, err := filepath.Glob(filepath.Join(steamapps, "appmanifest_*.acf"))
+	if err != nil {
+		return false, err
+	}
+
+	for _, manifest := range manifests {
+		content, err := os.ReadFile(manifest)
+		if err != nil {
+			return false, fmt.Errorf("failed to read Steam app manifest: %w", err)
+		}
+
+		dirMatch := acfInstallDirRegex.FindSubmatch(content)
+		if dirMatch == nil || !strings.EqualFold(string(dirMatch[1]), installDir) {
+			continue
+		}
+
+		flagsMatch := acfStateFlagsRegex.FindSubmatch(content)
+		if flagsMatch == nil {
+			return false, nil
+		}
+
+		flags, err := strconv.ParseUint(string(flagsMatch[1]), 10, 32)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse Steam app state flags: %w", err)
+		}
+
+		return flags&(steamStateValidating|steamStateUpdateRunning) != 0, nil
+	}
+
+	return false, nil
+}
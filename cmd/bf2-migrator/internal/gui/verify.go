@@ -0,0 +1,247 @@
+//go:build windows
+
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// patchRecord is the state of an executable right after the migrator patched it, allowing to detect later changes
+// (e.g. by other patchers)
+type patchRecord struct {
+	Path     string    `json:"path"`
+	Provider string    `json:"provider"`
+	Size     int       `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Patched  time.Time `json:"patched"`
+}
+
+// verification is the result of checking which provider an installation will connect to
+type verification struct {
+	// Provider the game will connect to (empty if it cannot be determined)
+	Provider string
+	Lines    []string
+	Problems []string
+}
+
+func (v verification) OK() bool {
+	return v.Provider != "" && len(v.Problems) == 0
+}
+
+func (v verification) String() string {
+	var sb strings.Builder
+	switch {
+	case v.OK():
+		sb.WriteString(fmt.Sprintf("Your game will connect to %s\n\n", v.Provider))
+	case v.Provider != "":
+		sb.WriteString(fmt.Sprintf("Your game may not connect to %s\n\n", v.Provider))
+	default:
+		sb.WriteString("Your game will not connect to any provider\n\n")
+	}
+
+	sb.WriteString(strings.Join(v.Lines, "\n"))
+	if len(v.Problems) > 0 {
+		sb.WriteString("\n\nProblems:\n")
+		sb.WriteString(strings.Join(v.Problems, "\n"))
+	}
+	return sb.String()
+}
+
+// verifyInstallation re-reads the patchables' executables in dir, checking which provider they are patched for, whether
+// they changed since being patched, whether the provider's DLLs work and whether the hosts file redirects them elsewhere
+func verifyInstallation(catalog patchable.Catalog, patchables []patch.Patchable, dir string, hostsPath string) (verification, error) {
+	records, err := readPatchRecords()
+	if err != nil {
+		return verification{}, fmt.Errorf("failed to read patch records: %w", err)
+	}
+
+	var v verification
+	var game, server patch.Provider
+	var checked []string
+	for _, p := range patchables {
+		name := p.GetFileName()
+		if containsPath(checked, name) {
+			continue
+		}
+
+		inspection, err2 := patch.Inspect(p, dir)
+		if errors.Is(err2, patch.ErrNotExist) {
+			if !patchable.IsOptional(p) {
+				v.Problems = append(v.Problems, fmt.Sprintf("%s does not exist", name))
+			}
+			checked = append(checked, name)
+			continue
+		}
+		if err2 != nil {
+			return verification{}, fmt.Errorf("failed to read %s: %w", name, err2)
+		}
+		if inspection.DetectErr != nil {
+			// Other build variants of the executable may still match
+			continue
+		}
+		checked = append(checked, name)
+
+		v.Lines = append(v.Lines, fmt.Sprintf("%s: patched for %s", name, inspection.Provider))
+		if patchable.IsServer(p) {
+			server = inspection.Provider
+		} else {
+			game = inspection.Provider
+		}
+
+		path := filepath.Join(dir, name)
+		for _, r := range records {
+			if !strings.EqualFold(r.Path, path) {
+				continue
+			}
+			if r.Size != len(inspection.Data) || !strings.EqualFold(r.SHA256, inspection.SHA256) {
+				v.Problems = append(v.Problems, fmt.Sprintf("%s changed since it was patched for %s on %s (e.g. by another patcher)", name, r.Provider, r.Patched.Format("2006-01-02 15:04")))
+			}
+		}
+	}
+
+	for _, p := range patchables {
+		if !containsPath(checked, p.GetFileName()) {
+			checked = append(checked, p.GetFileName())
+			v.Problems = append(v.Problems, fmt.Sprintf("%s contains unknown/mixed modifications", p.GetFileName()))
+		}
+	}
+
+	if game != "" && server != "" && game != server {
+		v.Problems = append(v.Problems, fmt.Sprintf("Game and server executables are patched for different providers (%s and %s)", game, server))
+	}
+
+	provider := game
+	if provider == "" {
+		provider = server
+	}
+
+	checks, err := catalog.CheckDLLs(dir)
+	if err != nil {
+		return verification{}, fmt.Errorf("failed to check provider DLLs: %w", err)
+	}
+	for _, check := range checks {
+		if check.Broken() {
+			v.Problems = append(v.Problems, fmt.Sprintf("%s is %s (%s)", check.FileName, check.Status, check.Reason))
+		}
+	}
+
+	conflicts, err := hosts.FindConflicts(hostsPath, catalog.Domains())
+	if err != nil {
+		return verification{}, fmt.Errorf("failed to check hosts file: %w", err)
+	}
+	for _, conflict := range conflicts {
+		v.Problems = append(v.Problems, fmt.Sprintf("Hosts file line %d redirects provider hostnames: %s", conflict.Line, conflict.Text))
+	}
+
+	// Executables still using the GameSpy hostnames only connect anywhere if they are redirected via the hosts file
+	if provider == patchable.ProviderGameSpy {
+		redirect, ok, err2 := hosts.Installed(hostsPath)
+		if err2 != nil {
+			return verification{}, fmt.Errorf("failed to check hosts file: %w", err2)
+		}
+		if ok {
+			v.Lines = append(v.Lines, fmt.Sprintf("Hosts file redirects GameSpy hostnames to %s", redirect))
+			v.Provider = redirect
+		} else {
+			v.Problems = append(v.Problems, "Executables use the GameSpy hostnames, but GameSpy has been shut down")
+		}
+	} else if provider != "" {
+		v.Provider = string(provider)
+	}
+
+	return v, nil
+}
+
+// recordPatched stores the current state of the patchables' executables in dir, so verifyInstallation can detect
+// later changes
+func recordPatched(patchables []patch.Patchable, dir string) error {
+	records, err := readPatchRecords()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range patchables {
+		inspection, err2 := patch.Inspect(p, dir)
+		if err2 != nil || inspection.DetectErr != nil {
+			// Missing executables and other build variants are not recorded
+			continue
+		}
+
+		path := filepath.Join(dir, p.GetFileName())
+		remaining := make([]patchRecord, 0, len(records)+1)
+		for _, r := range records {
+			if !strings.EqualFold(r.Path, path) {
+				remaining = append(remaining, r)
+			}
+		}
+		records = append(remaining, patchRecord{
+			Path:     path,
+			Provider: string(inspection.Provider),
+			Size:     len(inspection.Data),
+			SHA256:   inspection.SHA256,
+			Patched:  time.Now(),
+		})
+	}
+
+	return writePatchRecords(records)
+}
+
+func readPatchRecords() ([]patchRecord, error) {
+	path, err := getPatchRecordsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Nothing has been patched yet
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []patchRecord
+	if err = json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func writePatchRecords(records []patchRecord) error {
+	path, err := getPatchRecordsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func getPatchRecordsPath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "patched.json"), nil
+}
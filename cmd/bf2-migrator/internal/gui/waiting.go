@@ -0,0 +1,149 @@
+//go:build windows
+
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/walk/declarative"
+)
+
+const (
+	// Seconds to wait for killed processes to exit before offering to wait longer/continue anyway
+	processExitTimeout = 5
+)
+
+var errWaitCanceled = errors.New("canceled waiting for processes to exit")
+
+// waitWithProgress waits for the processes to exit while showing which of them are still running. Once the timeout is
+// reached, the user can choose to wait longer, continue anyway or cancel.
+func waitWithProgress(owner walk.Form, processes map[int]string) error {
+	var dlg *walk.Dialog
+	var statusLB *walk.Label
+	var waitPB, continuePB, cancelPB *walk.PushButton
+
+	var mu sync.Mutex
+	deadline := processExitTimeout
+	stopped := false
+	var waitErr error
+
+	if err := (declarative.Dialog{
+		AssignTo:     &dlg,
+		Title:        "Waiting for processes to exit",
+		CancelButton: &cancelPB,
+		MinSize:      declarative.Size{Width: 360},
+		Layout:       declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				AssignTo: &statusLB,
+				Text:     describeRunning(processes, 0, false),
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						AssignTo: &waitPB,
+						Text:     "&Wait longer",
+						Enabled:  false,
+						OnClicked: func() {
+							mu.Lock()
+							deadline += processExitTimeout
+							mu.Unlock()
+							waitPB.SetEnabled(false)
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &continuePB,
+						Text:     "&Continue anyway",
+						OnClicked: func() {
+							dlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						AssignTo: &cancelPB,
+						Text:     "Cancel",
+						OnClicked: func() {
+							dlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}).Create(owner); err != nil {
+		return err
+	}
+
+	showKeyboardCues(dlg)
+
+	go func() {
+		err := waitForProcessesToExit(processes, func(running map[int]string, elapsed int) bool {
+			mu.Lock()
+			timedOut := elapsed >= deadline
+			stop := stopped
+			mu.Unlock()
+
+			description := describeRunning(running, elapsed, timedOut)
+			dlg.Synchronize(func() {
+				mu.Lock()
+				closed := stopped
+				mu.Unlock()
+				// Dialog may have been closed in the meantime
+				if closed {
+					return
+				}
+
+				_ = statusLB.SetText(description)
+				if timedOut {
+					waitPB.SetEnabled(true)
+				}
+			})
+
+			// Keep checking after the timeout, so the dialog still closes once the processes exit
+			return !stop
+		})
+
+		dlg.Synchronize(func() {
+			mu.Lock()
+			waitErr = err
+			stop := stopped
+			mu.Unlock()
+			if !stop {
+				dlg.Accept()
+			}
+		})
+	}()
+
+	result := dlg.Run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	stopped = true
+
+	if waitErr != nil {
+		return waitErr
+	}
+	if result != walk.DlgCmdOK {
+		return errWaitCanceled
+	}
+
+	return nil
+}
+
+func describeRunning(running map[int]string, elapsed int, timedOut bool) string {
+	names := make([]string, 0, len(running))
+	for pid, executable := range running {
+		names = append(names, fmt.Sprintf("%s (PID %d)", executable, pid))
+	}
+	sort.Strings(names)
+
+	if timedOut {
+		return fmt.Sprintf("Still running after %d seconds:\n\n%s\n\nWait longer, continue anyway (patching may fail) or cancel?", elapsed, strings.Join(names, "\n"))
+	}
+	return fmt.Sprintf("Waiting for processes to exit (%d seconds):\n\n%s", elapsed, strings.Join(names, "\n"))
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package gui
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	runningUnderWine     bool
+	runningUnderWineOnce sync.Once
+)
+
+// isWine returns whether the migrator is running under Wine (including Proton) rather than on Windows. Wine's ntdll
+// exports wine_get_version, which does not exist on Windows.
+func isWine() bool {
+	runningUnderWineOnce.Do(func() {
+		runningUnderWine = windows.NewLazySystemDLL("ntdll.dll").NewProc("wine_get_version").Find() == nil
+	})
+	return runningUnderWine
+}
@@ -0,0 +1,304 @@
+// Package hosts manages hosts file entries redirecting GameSpy hostnames to a provider, as a non-invasive alternative
+// to patching the executables
+package hosts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+)
+
+const (
+	blockStart = "# BEGIN bf2-migrator"
+	blockEnd   = "# END bf2-migrator"
+	// Appended to the hosts file's path to get the path of the backup made before each change
+	backupSuffix = ".bf2-migrator.bak"
+
+	lookupTimeout = 10 * time.Second
+)
+
+// Entry is a single hosts file entry
+type Entry struct {
+	IP       net.IP
+	Hostname string
+}
+
+// Resolve returns an entry for each redirect, pointing the GameSpy hostname to the (first IPv4) address of the
+// provider hostname replacing it
+func Resolve(redirects []patchable.Redirect, lookup func(host string) ([]net.IP, error)) ([]Entry, error) {
+	// Resolve each provider hostname only once (e.g. PlayBF2 uses a single master server hostname)
+	resolved := map[string]net.IP{}
+	entries := make([]Entry, 0, len(redirects))
+	var err error
+	for _, r := range redirects {
+		key := strings.ToLower(r.To)
+		ip, ok := resolved[key]
+		if !ok {
+			ips, err2 := lookup(r.To)
+			if err2 != nil {
+				err = multierr.Append(err, fmt.Errorf("failed to resolve %s: %w", r.To, err2))
+				continue
+			}
+			ip = firstIPv4(ips)
+			if ip == nil {
+				err = multierr.Append(err, fmt.Errorf("%s has no IPv4 address", r.To))
+				continue
+			}
+			resolved[key] = ip
+		}
+
+		entries = append(entries, Entry{IP: ip, Hostname: r.From})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// LookupIPv4 looks up the IPv4 addresses of host using the system's resolver
+func LookupIPv4(host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupIP(ctx, "ip4", host)
+}
+
+func firstIPv4(ips []net.IP) net.IP {
+	for _, ip := range ips {
+		if v4 := ip.To4(); v4 != nil {
+			return v4
+		}
+	}
+	return nil
+}
+
+// Apply writes the entries to the hosts file at path, replacing any entries previously written by the migrator
+func Apply(path string, provider string, entries []Entry) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	newline := detectNewline(content)
+	lines, _, _ := stripBlock(content)
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	lines = append(lines, "", fmt.Sprintf("%s (%s)", blockStart, provider))
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("%s %s", e.IP, e.Hostname))
+	}
+	lines = append(lines, blockEnd, "")
+
+	return write(path, lines, newline)
+}
+
+// Remove removes the entries written by the migrator from the hosts file at path, returning whether any were found
+func Remove(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	lines, _, found := stripBlock(content)
+	if !found {
+		return false, nil
+	}
+
+	return true, write(path, lines, detectNewline(content))
+}
+
+// Installed returns the provider the hosts file at path currently redirects to (if any)
+func Installed(path string) (string, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	_, provider, found := stripBlock(content)
+	return provider, found, nil
+}
+
+// Conflict is a hosts file entry (not written by the migrator) redirecting a GameSpy/provider hostname
+type Conflict struct {
+	// Line number (starting at 1)
+	Line int
+	Text string
+}
+
+// FindConflicts returns the entries of the hosts file at path which redirect any of the domains or their subdomains,
+// ignoring the migrator's own entries. Such entries are usually left behind by old patchers and make patched games
+// connect to the wrong backend.
+func FindConflicts(path string, domains []string) ([]Conflict, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var conflicts []Conflict
+	inBlock := false
+	for i, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, blockStart):
+			inBlock = true
+		case inBlock && trimmed == blockEnd:
+			inBlock = false
+		case !inBlock && redirectsAny(line, domains):
+			conflicts = append(conflicts, Conflict{Line: i + 1, Text: trimmed})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// RemoveConflicts removes the entries found by FindConflicts from the hosts file at path, returning how many were removed
+func RemoveConflicts(path string, domains []string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var lines []string
+	removed := 0
+	inBlock := false
+	for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, blockStart):
+			inBlock = true
+		case inBlock && trimmed == blockEnd:
+			inBlock = false
+		case !inBlock && redirectsAny(line, domains):
+			removed++
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// Splitting content ending in a newline results in a trailing empty line, which write adds back
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return removed, write(path, lines, detectNewline(content))
+}
+
+// redirectsAny returns whether the hosts file line is an entry for any of the domains or their subdomains
+func redirectsAny(line string, domains []string) bool {
+	if i := strings.Index(line, "#"); i != -1 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	// First field is the address, followed by one or more hostnames
+	if len(fields) < 2 {
+		return false
+	}
+
+	for _, hostname := range fields[1:] {
+		hostname = strings.ToLower(hostname)
+		for _, domain := range domains {
+			domain = strings.ToLower(domain)
+			if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// stripBlock returns the lines of content without the migrator's block, along with the provider the block redirected to
+func stripBlock(content []byte) ([]string, string, bool) {
+	var lines []string
+	var provider string
+	inBlock, found := false, false
+	for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, blockStart):
+			inBlock, found = true, true
+			provider = strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, blockStart)), "()")
+		case inBlock && trimmed == blockEnd:
+			inBlock = false
+		case !inBlock:
+			lines = append(lines, line)
+		}
+	}
+
+	// Splitting content ending in a newline results in a trailing empty line, which write adds back
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines, provider, found
+}
+
+func detectNewline(content []byte) string {
+	if bytes.Contains(content, []byte("\r\n")) {
+		return "\r\n"
+	}
+	return defaultNewline
+}
+
+func write(path string, lines []string, newline string) error {
+	content := strings.Join(lines, newline)
+	if !strings.HasSuffix(content, newline) {
+		content += newline
+	}
+
+	// Back up the current content first, so it is not lost should writing the new content fail halfway
+	backup := path + backupSuffix
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+	if err == nil {
+		if err = os.WriteFile(backup, original, 0o644); err != nil {
+			return fmt.Errorf("failed to back up hosts file: %w", err)
+		}
+	}
+
+	// Write in place rather than replacing the file, which would lose its owner/ACL (and fails for bind mounts)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	_, err = f.WriteString(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err = multierr.Append(err, f.Close()); err != nil {
+		return fmt.Errorf("failed to write hosts file (previous content is backed up to %s): %w", backup, err)
+	}
+
+	return nil
+}
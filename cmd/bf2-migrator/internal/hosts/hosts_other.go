@@ -0,0 +1,12 @@
+//go:build !windows
+
+package hosts
+
+const (
+	defaultNewline = "\n"
+)
+
+// DefaultPath returns the path of the system's hosts file
+func DefaultPath() string {
+	return "/etc/hosts"
+}
@@ -0,0 +1,19 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultNewline = "\r\n"
+)
+
+// DefaultPath returns the path of the system's hosts file
+func DefaultPath() string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = "C:\\Windows"
+	}
+	return filepath.Join(root, "System32", "drivers", "etc", "hosts")
+}
@@ -0,0 +1,124 @@
+// Package logfile writes log output to files with size-based rotation, so logs can be attached to support requests
+// (the GUI build has no console output)
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	fileName = "bf2-migrator.log"
+	// Rotate once the current file would exceed this size
+	DefaultMaxSize = 1 << 20
+	// Number of rotated files to keep in addition to the current one
+	DefaultMaxBackups = 3
+)
+
+// DefaultDir returns the logs folder in the user's local app data folder (%LOCALAPPDATA% on Windows)
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+	}
+
+	return filepath.Join(dir, "bf2-migrator", "logs"), nil
+}
+
+// Writer appends to the log file in dir, rotating it to <name>.1 (.2, ...) once it would exceed maxSize
+type Writer struct {
+	dir        string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens (creating if required) the log file in dir for appending
+func Open(dir string, maxSize int64, maxBackups int) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log folder: %w", err)
+	}
+
+	w := &Writer{
+		dir:        dir,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Dir returns the folder the log files are written to
+func (w *Writer) Dir() string {
+	return w.dir
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Never rotate an empty file, even if a single write exceeds the maximum size
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(filepath.Join(w.dir, fileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// rotate shifts the existing files up by one (dropping the oldest one) and starts a new, empty file
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	path := filepath.Join(w.dir, fileName)
+	for i := w.maxBackups - 1; i > 0; i-- {
+		// Missing backups just mean the files have not been rotated that often yet
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if w.maxBackups > 0 {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove log file: %w", err)
+	}
+
+	return w.open()
+}
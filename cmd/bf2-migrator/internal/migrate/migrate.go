@@ -0,0 +1,141 @@
+// Package migrate contains the profile migration flow shared by all frontends
+package migrate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/prompt"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/readonly"
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+type Client interface {
+	GetNicks(provider gamespy.Provider, email, password string) ([]gamespy.NickDTO, error)
+	CreateUser(provider gamespy.Provider, email, password, nick string) error
+	Login(provider gamespy.Provider, uniqueNick, password string) (gamespy.LoginDTO, error)
+}
+
+// Step is a stage of migrating a profile
+type Step string
+
+const (
+	StepLogIn  Step = "log-in"
+	StepCreate Step = "create"
+	StepVerify Step = "verify"
+)
+
+// Steps lists the steps in the order they are reached while migrating a profile
+var Steps = []Step{StepLogIn, StepCreate, StepVerify}
+
+const (
+	// Number of times the provider password is prompted for before giving up (prompters may not be interactive, e.g.
+	// when taking the password from an environment variable, so never retry indefinitely)
+	maxPasswordAttempts = 3
+)
+
+type Credentials struct {
+	Nick     string
+	Email    string
+	Password string
+	// ID of the profile created on the provider, set once the profile has been verified
+	ProfileID string
+}
+
+// Profile creates the profile's nick on the provider, unless it already exists. If the provider rejects the
+// profile's password (e.g. since the account was created with a different one), the provider password is prompted for
+// (until the provider accepts it or the user declines) and stored in creds.
+func Profile(c Client, p prompt.CredentialPrompter, provider gamespy.Provider, creds *Credentials) (bool, error) {
+	return ProfileWithProgress(c, p, provider, creds, nil)
+}
+
+// ProfileWithProgress migrates the profile like Profile, calling progress (if set) whenever a new step is reached
+func ProfileWithProgress(c Client, p prompt.CredentialPrompter, provider gamespy.Provider, creds *Credentials, progress func(step Step)) (bool, error) {
+	report := func(step Step) {
+		if progress != nil {
+			progress(step)
+		}
+	}
+
+	report(StepLogIn)
+	nicks, err := c.GetNicks(provider, creds.Email, creds.Password)
+	message := fmt.Sprintf("The account %s uses a different password on the provider, enter it to continue", creds.Email)
+	for attempt := 0; attempt < maxPasswordAttempts && isBadPassword(err); attempt++ {
+		password, ok := p.PromptPassword("Password mismatch", message)
+		if !ok {
+			return false, err
+		}
+		creds.Password = password
+		nicks, err = c.GetNicks(provider, creds.Email, creds.Password)
+		message = fmt.Sprintf("The provider rejected the password entered for %s, enter it again to continue", creds.Email)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get OpenSpy account profiles: %w", err)
+	}
+
+	// Don't use slices package here to maintain compatibility with go 1.20 (and thus Windows 7)
+	if ContainsNick(nicks, creds.Nick) {
+		return false, nil
+	}
+
+	report(StepCreate)
+	// Providers reject some invalid values with generic errors and silently truncate others, so check them first
+	if err = gamespy.ValidateNewUser(creds.Email, creds.Password, creds.Nick); err != nil {
+		return false, fmt.Errorf("cannot create OpenSpy profile: %w", err)
+	}
+	err = c.CreateUser(provider, creds.Email, creds.Password, creds.Nick)
+	if err != nil {
+		return false, fmt.Errorf("failed to create OpenSpy profile: %w", err)
+	}
+
+	// Log in again to verify the profile has actually been created
+	report(StepVerify)
+	nicks, err = c.GetNicks(provider, creds.Email, creds.Password)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify OpenSpy profile: %w", err)
+	}
+	if !ContainsNick(nicks, creds.Nick) {
+		return false, fmt.Errorf("failed to verify OpenSpy profile: profile is missing from account after creation")
+	}
+
+	// Also log in using the profile (as the game will), since being listed does not mean the profile can be used
+	login, err := c.Login(provider, creds.Nick, creds.Password)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify OpenSpy profile: failed to log in: %w", err)
+	}
+	if login.UniqueNick != creds.Nick {
+		return false, fmt.Errorf("failed to verify OpenSpy profile: logged in as %q instead of %q", login.UniqueNick, creds.Nick)
+	}
+	creds.ProfileID = login.ProfileID
+
+	return true, nil
+}
+
+// ReadOnly wraps the client, refusing to create users (while still allowing to log in and list nicks)
+func ReadOnly(c Client) Client {
+	return readOnlyClient{Client: c}
+}
+
+type readOnlyClient struct {
+	Client
+}
+
+func (c readOnlyClient) CreateUser(_ gamespy.Provider, _, _, nick string) error {
+	return fmt.Errorf("would create %q: %w", nick, readonly.ErrReadOnly)
+}
+
+// isBadPassword returns whether the provider rejected the login since the password is wrong
+func isBadPassword(err error) bool {
+	var providerErr *gamespy.ProviderError
+	return errors.As(err, &providerErr) && providerErr.Code == gamespy.ErrCodeLoginBadPassword
+}
+
+// ContainsNick returns whether the uniquenick is among the account's nicks
+func ContainsNick(nicks []gamespy.NickDTO, nick string) bool {
+	for _, n := range nicks {
+		if n.UniqueNick == nick {
+			return true
+		}
+	}
+	return false
+}
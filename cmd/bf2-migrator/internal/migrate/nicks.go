@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+)
+
+const (
+	// Number of numbered variants suggested per base nick
+	numberedSuggestions = 3
+)
+
+// Characters clan tags are commonly enclosed in/separated from the nick by, e.g. "[TAG]Nick", "=TAG=Nick" or "TAG|Nick"
+var clanTagDelimiters = []struct {
+	open, close string
+}{
+	{"[", "]"},
+	{"(", ")"},
+	{"{", "}"},
+	{"<", ">"},
+	{"=", "="},
+	{"-", "-"},
+	{"|", "|"},
+	{"", "|"},
+}
+
+// IsUniqueNickUsed returns whether the provider rejected creating a nick since another account uses it already
+func IsUniqueNickUsed(err error) bool {
+	var providerErr *gamespy.ProviderError
+	return errors.As(err, &providerErr) && providerErr.Code == gamespy.ErrCodeNewUserUniqueNickUsed
+}
+
+// SuggestNicks returns variants of the nick to use in case it is already taken: the nick without its clan tag (if it
+// has one) and numbered variants of both, without any duplicates or variants providers would not accept
+func SuggestNicks(nick string) []string {
+	maxLength := gamespy.MaxNickLength
+	bases := []string{nick}
+	if stripped := StripClanTag(nick); stripped != "" && stripped != nick {
+		bases = append(bases, stripped)
+	}
+
+	seen := map[string]bool{nick: true}
+	var suggestions []string
+	add := func(candidate string) {
+		if seen[candidate] || gamespy.ValidateNick(candidate) != nil {
+			return
+		}
+		seen[candidate] = true
+		suggestions = append(suggestions, candidate)
+	}
+
+	// Suggest the nick without its clan tag first, since it is the most recognizable alternative
+	for _, base := range bases[1:] {
+		add(base)
+	}
+	for _, base := range bases {
+		for i := 1; i <= numberedSuggestions; i++ {
+			suffix := strconv.Itoa(i)
+			// Shorten long nicks to make room for the suffix
+			if len(base)+len(suffix) > maxLength {
+				add(base[:maxLength-len(suffix)] + suffix)
+			} else {
+				add(base + suffix)
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// StripClanTag returns the nick without a leading clan tag, or the nick as-is if it does not seem to have one
+func StripClanTag(nick string) string {
+	for _, d := range clanTagDelimiters {
+		if !strings.HasPrefix(nick, d.open) {
+			continue
+		}
+		i := strings.Index(nick[len(d.open):], d.close)
+		// Tag must not be empty and must be followed by the actual nick
+		if i <= 0 {
+			continue
+		}
+		if stripped := strings.TrimSpace(nick[len(d.open)+i+len(d.close):]); stripped != "" {
+			return stripped
+		}
+	}
+	return nick
+}
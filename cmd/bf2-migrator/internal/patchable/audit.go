@@ -0,0 +1,173 @@
+package patchable
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Skip large files (e.g. archives), they never contain relevant provider strings
+	maxAuditFileSize = 64 * 1024 * 1024
+)
+
+var auditExtensions = map[string]bool{
+	".exe": true,
+	".dll": true,
+	".py":  true,
+	".con": true,
+	".cfg": true,
+	".ini": true,
+	".txt": true,
+}
+
+// Provider variables containing strings specific to a provider
+var auditVariables = []string{"hostname", "hostsPath", "gameDLL", "serverDLL"}
+
+type AuditReport struct {
+	// Total number of provider-specific strings found per provider
+	Totals map[patch.Provider]int
+	// Files containing provider-specific strings, mapped to the number of strings found per provider
+	Files map[string]map[patch.Provider]int
+	// Number of files scanned
+	Scanned int
+}
+
+// Percentages returns each provider's share of all provider-specific strings found, sorted by descending share
+func (r AuditReport) Percentages() []ProviderShare {
+	total := 0
+	for _, count := range r.Totals {
+		total += count
+	}
+
+	shares := make([]ProviderShare, 0, len(r.Totals))
+	for provider, count := range r.Totals {
+		shares = append(shares, ProviderShare{
+			Provider: provider,
+			Count:    count,
+			Percent:  float64(count) / float64(total) * 100,
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Count != shares[j].Count {
+			return shares[i].Count > shares[j].Count
+		}
+		return shares[i].Provider < shares[j].Provider
+	})
+
+	return shares
+}
+
+type ProviderShare struct {
+	Provider patch.Provider
+	Count    int
+	Percent  float64
+}
+
+// Audit scans all executables and scripts in dir for provider-specific strings
+func Audit(dir string, catalog Catalog) (AuditReport, error) {
+	markers := getAuditMarkers(catalog)
+	report := AuditReport{
+		Totals: map[patch.Provider]int{},
+		Files:  map[string]map[patch.Provider]int{},
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !auditExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxAuditFileSize {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		report.Scanned++
+
+		for provider, ms := range markers {
+			count := 0
+			for _, m := range ms {
+				count += bytes.Count(b, m)
+			}
+			if count == 0 {
+				continue
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				rel = path
+			}
+			if report.Files[rel] == nil {
+				report.Files[rel] = map[patch.Provider]int{}
+			}
+			report.Files[rel][provider] += count
+			report.Totals[provider] += count
+		}
+
+		return nil
+	})
+	if err != nil {
+		return AuditReport{}, err
+	}
+
+	return report, nil
+}
+
+// getAuditMarkers returns strings unique to each provider. Hostnames shared by multiple providers
+// (e.g. BF2Hub uses the original GameSpy hostname) are attributed to GameSpy, since they are original strings.
+// Any other shared strings (e.g. the default DLL names) are not specific to a provider and thus ignored.
+func getAuditMarkers(catalog Catalog) map[patch.Provider][][]byte {
+	type owner struct {
+		provider patch.Provider
+		variable string
+	}
+
+	owners := map[string][]owner{}
+	for _, d := range catalog.Providers {
+		for _, variable := range auditVariables {
+			s, err := catalog.expand("{"+variable+"}", d)
+			if err != nil {
+				continue
+			}
+
+			// Strings using wildcards cannot be counted reliably
+			p := patch.ParsePattern(s)
+			if p.Len() == 0 || !p.IsExact() {
+				continue
+			}
+			owners[string(p.Bytes())] = append(owners[string(p.Bytes())], owner{provider: d.Provider(), variable: variable})
+		}
+	}
+
+	markers := map[patch.Provider][][]byte{}
+	for m, ownedBy := range owners {
+		if len(ownedBy) == 1 {
+			markers[ownedBy[0].provider] = append(markers[ownedBy[0].provider], []byte(m))
+			continue
+		}
+
+		for _, o := range ownedBy {
+			if o.provider == ProviderGameSpy && o.variable == "hostname" {
+				markers[o.provider] = append(markers[o.provider], []byte(m))
+			}
+		}
+	}
+
+	return markers
+}
@@ -0,0 +1,82 @@
+package patchable
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+//go:embed builds.json
+var buildsJSON []byte
+
+type build struct {
+	Name       string `json:"name"`
+	Executable string `json:"executable"`
+	Major      uint16 `json:"major"`
+	Minor      uint16 `json:"minor"`
+}
+
+// BuildInfo describes an executable's version. Builds are told apart by the major/minor version of their version
+// information only, so a modified binary reporting a known version is not distinguished from the original.
+type BuildInfo struct {
+	Version    patch.Version
+	HasVersion bool
+	// SHA-256 hash of the binary, for reference only (builds are identified by their version)
+	Hash string
+	// Name of the known version (empty if the version is unknown)
+	Name string
+}
+
+func (i BuildInfo) Known() bool {
+	return i.Name != ""
+}
+
+func (i BuildInfo) String() string {
+	if !i.HasVersion {
+		return "unknown (no version information)"
+	}
+	if !i.Known() {
+		return fmt.Sprintf("unknown (%s)", i.Version)
+	}
+	return fmt.Sprintf("%s (%s)", i.Name, i.Version)
+}
+
+// IdentifyBuild determines the game version of the given executable based on its version information
+func IdentifyBuild(fileName string, b []byte) (BuildInfo, error) {
+	sum := sha256.Sum256(b)
+	return identifyBuild(fileName, b, hex.EncodeToString(sum[:]))
+}
+
+// IdentifyInspection determines the game version of an inspected executable, reusing the hash calculated while reading
+func IdentifyInspection(fileName string, inspection patch.Inspection) (BuildInfo, error) {
+	return identifyBuild(fileName, inspection.Data, inspection.SHA256)
+}
+
+func identifyBuild(fileName string, b []byte, hash string) (BuildInfo, error) {
+	var builds []build
+	if err := json.Unmarshal(buildsJSON, &builds); err != nil {
+		return BuildInfo{}, fmt.Errorf("failed to parse embedded builds: %w", err)
+	}
+
+	info := BuildInfo{
+		Hash: hash,
+	}
+	info.Version, info.HasVersion = patch.ReadFileVersion(b)
+
+	for _, candidate := range builds {
+		if !strings.EqualFold(candidate.Executable, fileName) {
+			continue
+		}
+
+		if info.HasVersion && candidate.Major == info.Version.Major && candidate.Minor == info.Version.Minor {
+			info.Name = candidate.Name
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,306 @@
+package patchable
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Guards against variables referencing each other in a loop
+	maxVariableDepth = 5
+)
+
+//go:embed catalog.json
+var catalogJSON []byte
+
+// Catalog contains the executables and providers supported for patching. The embedded catalog can be extended
+// (or overridden) by a local catalog file, so new providers can be added without a new release.
+type Catalog struct {
+	// Incremented for every published catalog, so remote catalogs cannot be replaced with older (signed) ones
+	Version int `json:"version,omitempty"`
+	// Variable values used for any provider which does not set them itself
+	Defaults    map[string]string      `json:"defaults,omitempty"`
+	Executables []ExecutableDefinition `json:"executables,omitempty"`
+	Providers   []Definition           `json:"providers,omitempty"`
+	// Provider DLLs loaded by patched executables, used to detect outdated/corrupt copies
+	DLLs []DLLDefinition `json:"dlls,omitempty"`
+}
+
+// ExecutableDefinition describes the strings to fingerprint/modify in an executable. Templates may reference
+// provider variables using "{name}", with "{hostname}" and "{hostsPath}" always being available.
+type ExecutableDefinition struct {
+	FileName string `json:"fileName"`
+	// Name of the build variant, for executables with builds containing different strings (empty for the default build).
+	// Variants are tried in order until one matches the binary.
+	Variant string `json:"variant,omitempty"`
+	// Executable is a dedicated server rather than the game client
+	Server bool `json:"server,omitempty"`
+	// Executable is not part of every installation, so it missing is not an error
+	Optional      bool                   `json:"optional,omitempty"`
+	Comment       string                 `json:"comment,omitempty"`
+	Fingerprint   []string               `json:"fingerprint"`
+	Modifications []ModificationTemplate `json:"modifications"`
+}
+
+type ModificationTemplate struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+	Length   int    `json:"length"`
+	Count    int    `json:"count"`
+	// Skip the modification if old and new provider result in the same string, rather than requiring Count matches
+	SkipUnchanged bool   `json:"skipUnchanged,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// LoadCatalog loads the embedded catalog, merged with the catalogs at paths (if they exist) in the given order
+func LoadCatalog(paths ...string) (Catalog, error) {
+	var catalog Catalog
+	if err := json.Unmarshal(catalogJSON, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("failed to parse embedded catalog: %w", err)
+	}
+
+	for _, path := range paths {
+		other, err := readCatalogFile(path)
+		if err != nil {
+			return catalog, err
+		}
+		catalog = catalog.merge(other)
+	}
+
+	return catalog, nil
+}
+
+// SaveDefinition adds the definition to the local catalog at path, replacing any provider with the same name
+func SaveDefinition(path string, definition Definition) error {
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+
+	local, err := readCatalogFile(path)
+	if err != nil {
+		return err
+	}
+
+	local = local.merge(Catalog{Providers: []Definition{definition}})
+
+	data, err := json.MarshalIndent(local, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func readCatalogFile(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// No local catalog has been saved yet
+		if errors.Is(err, os.ErrNotExist) {
+			return Catalog{}, nil
+		}
+		return Catalog{}, err
+	}
+
+	var catalog Catalog
+	if err = json.Unmarshal(data, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("failed to parse catalog file: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// merge returns a copy of c with other's defaults, executables and providers taking precedence
+func (c Catalog) merge(other Catalog) Catalog {
+	merged := Catalog{
+		Version:  c.Version,
+		Defaults: make(map[string]string, len(c.Defaults)+len(other.Defaults)),
+	}
+	if other.Version > merged.Version {
+		merged.Version = other.Version
+	}
+	for k, v := range c.Defaults {
+		merged.Defaults[k] = v
+	}
+	for k, v := range other.Defaults {
+		merged.Defaults[k] = v
+	}
+
+	merged.Executables = append(merged.Executables, c.Executables...)
+	for _, e := range other.Executables {
+		replaced := false
+		for i, existing := range merged.Executables {
+			if strings.EqualFold(existing.FileName, e.FileName) && existing.Variant == e.Variant {
+				merged.Executables[i] = e
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Executables = append(merged.Executables, e)
+		}
+	}
+
+	merged.Providers = append(merged.Providers, c.Providers...)
+	for _, p := range other.Providers {
+		replaced := false
+		for i, existing := range merged.Providers {
+			if existing.Name == p.Name {
+				merged.Providers[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Providers = append(merged.Providers, p)
+		}
+	}
+
+	merged.DLLs = append(merged.DLLs, c.DLLs...)
+	for _, d := range other.DLLs {
+		replaced := false
+		for i, existing := range merged.DLLs {
+			if strings.EqualFold(existing.FileName, d.FileName) {
+				merged.DLLs[i] = d
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.DLLs = append(merged.DLLs, d)
+		}
+	}
+
+	return merged
+}
+
+// Patchables returns a patchable for each executable in the catalog
+func (c Catalog) Patchables() []patch.Patchable {
+	patchables := make([]patch.Patchable, 0, len(c.Executables))
+	for _, e := range c.Executables {
+		patchables = append(patchables, Executable{
+			definition: e,
+			catalog:    c,
+		})
+	}
+
+	return patchables
+}
+
+// RenamedPatchables returns a patchable for each executable in the catalog, all targeting the renamed copy fileName
+// instead (e.g. "PRBF2.exe")
+func (c Catalog) RenamedPatchables(fileName string) []patch.Patchable {
+	patchables := make([]patch.Patchable, 0, len(c.Executables))
+	for _, e := range c.Executables {
+		patchables = append(patchables, Executable{
+			definition: e,
+			catalog:    c,
+			fileName:   fileName,
+		})
+	}
+
+	return patchables
+}
+
+// Provider returns the definition of the provider with the given name
+func (c Catalog) Provider(name patch.Provider) (Definition, bool) {
+	for _, d := range c.Providers {
+		if d.Provider() == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// ValidateDefinition checks whether all strings of the provider fit into the space available in each executable
+func (c Catalog) ValidateDefinition(d Definition) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	for _, e := range c.Executables {
+		for _, t := range e.Modifications {
+			s, err := c.expand(t.Template, d)
+			if err != nil {
+				return err
+			}
+
+			if l := patch.ParsePattern(s).Len(); l > t.Length {
+				return fmt.Errorf("%s string %q of %s is %d characters too long", t.Name, s, e.FileName, l-t.Length)
+			}
+		}
+	}
+
+	return nil
+}
+
+// expand replaces all variable references in template with the provider's values. The result uses the patch.Pattern
+// syntax: literal text of templates is escaped, while variable values may contain wildcards.
+func (c Catalog) expand(template string, d Definition) (string, error) {
+	return c.expandDepth(template, d, 0, true)
+}
+
+func (c Catalog) expandDepth(template string, d Definition, depth int, escape bool) (string, error) {
+	if depth > maxVariableDepth {
+		return "", fmt.Errorf("variables nested too deeply for provider %s", d.Name)
+	}
+
+	var sb strings.Builder
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start == -1 || end < start {
+			sb.WriteString(escapeIf(template, escape))
+			break
+		}
+
+		sb.WriteString(escapeIf(template[:start], escape))
+
+		name := template[start+1 : end]
+		value, ok := c.lookup(d, name)
+		if !ok {
+			return "", fmt.Errorf("provider %s does not define variable %q", d.Name, name)
+		}
+
+		// Values may reference other variables, but are already in pattern syntax (so never escaped)
+		expanded, err := c.expandDepth(value, d, depth+1, false)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(expanded)
+
+		template = template[end+1:]
+	}
+
+	return sb.String(), nil
+}
+
+func (c Catalog) lookup(d Definition, name string) (string, bool) {
+	switch name {
+	case "hostname":
+		return d.Hostname, true
+	case "hostsPath":
+		return d.HostsPath, true
+	}
+
+	if value, ok := d.Variables[name]; ok {
+		return value, true
+	}
+
+	value, ok := c.Defaults[name]
+	return value, ok
+}
+
+func escapeIf(s string, escape bool) string {
+	if !escape {
+		return s
+	}
+	return strings.ReplaceAll(s, "?", "??")
+}
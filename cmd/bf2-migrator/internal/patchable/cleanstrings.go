@@ -0,0 +1,159 @@
+package patchable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+//go:embed cleanstrings.json
+var cleanStringsJSON []byte
+
+// ErrNoCleanTable is returned if no clean string table is embedded for an executable
+var ErrNoCleanTable = errors.New("no clean GameSpy string table for executable")
+
+// cleanTable contains the original GameSpy strings of an executable, allowing to revert binaries patched for providers
+// which are not part of the catalog (and thus cannot be used to compute modifications)
+type cleanTable struct {
+	Executable string        `json:"executable"`
+	Strings    []cleanString `json:"strings"`
+}
+
+// cleanString is located by the anchor any provider leaves intact at the start of the string (e.g. "gpcm."). The whole
+// string is replaced, so the anchor must be followed by a nil-terminator within Length bytes.
+type cleanString struct {
+	Name   string `json:"name"`
+	Anchor string `json:"anchor"`
+	Value  string `json:"value"`
+	Length int    `json:"length"`
+	Count  int    `json:"count"`
+	// SHA-256 hash of Value nil-padded to Length, which each restored string must match
+	SHA256 string `json:"sha256"`
+}
+
+// RevertClean restores the original GameSpy strings of the executable, regardless of which provider the binary is
+// patched for. Strings outside the table (e.g. DLL names) are left as they are.
+func RevertClean(fileName string, b []byte) ([]byte, error) {
+	var tables []cleanTable
+	if err := json.Unmarshal(cleanStringsJSON, &tables); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded clean string tables: %w", err)
+	}
+
+	for _, table := range tables {
+		if strings.EqualFold(table.Executable, fileName) {
+			return table.apply(b)
+		}
+	}
+
+	return nil, ErrNoCleanTable
+}
+
+func (t cleanTable) apply(b []byte) ([]byte, error) {
+	reverted := append([]byte(nil), b...)
+	for _, s := range t.Strings {
+		if len(s.Value) > s.Length {
+			return nil, fmt.Errorf("clean string %q is longer than %d bytes", s.Name, s.Length)
+		}
+
+		// Strings are nil-padded to their full length, wiping any remains of longer provider strings
+		padded := make([]byte, s.Length)
+		copy(padded, s.Value)
+		sum := sha256.Sum256(padded)
+		if hash := hex.EncodeToString(sum[:]); !strings.EqualFold(hash, s.SHA256) {
+			return nil, fmt.Errorf("clean string %q does not match its hash %s", s.Name, s.SHA256)
+		}
+
+		offsets := findAnchored(reverted, []byte(s.Anchor), s.Length)
+		if len(offsets) != s.Count {
+			return nil, fmt.Errorf("cannot revert %q strings (found %d, expected %d), binary likely contains other modifications", s.Name, len(offsets), s.Count)
+		}
+
+		for _, offset := range offsets {
+			copy(reverted[offset:], padded)
+		}
+	}
+
+	patch.UpdateChecksum(reverted)
+
+	return reverted, nil
+}
+
+// findAnchored returns the offsets of strings in b starting with anchor, which are nil-terminated within length bytes
+func findAnchored(b []byte, anchor []byte, length int) []int {
+	var offsets []int
+	for offset := 0; offset < len(b); {
+		i := bytes.Index(b[offset:], anchor)
+		if i == -1 {
+			break
+		}
+
+		start := offset + i
+		offset = start + 1
+
+		// Anchor must be at the start of a string, not part of another one (e.g. "BF2Web." in "http://BF2Web.")
+		if start > 0 && b[start-1] != 0 {
+			continue
+		}
+
+		end := start + length
+		if end >= len(b) {
+			continue
+		}
+		if bytes.IndexByte(b[start:end+1], 0) != -1 {
+			offsets = append(offsets, start)
+		}
+	}
+
+	return offsets
+}
+
+// revertClean reverts the patchable's binary in dir using the clean string table of its executable. The result is only
+// written if the patchable detects it as a GameSpy binary.
+func revertClean(p patch.Patchable, dir string) (err error) {
+	path := patch.LongPath(filepath.Join(dir, p.GetFileName()))
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer multierr.AppendInvoke(&err, multierr.Close(f))
+
+	stats, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, stats.Size())
+	if _, err = f.ReadAt(b, 0); err != nil {
+		return err
+	}
+
+	// Tables are specific to the executable, so a renamed copy uses the one of the original
+	fileName := p.GetFileName()
+	if e, ok := p.(Executable); ok {
+		fileName = e.definition.FileName
+	}
+
+	reverted, err := RevertClean(fileName, b)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, ok := p.GetFingerprints()[ProviderGameSpy]
+	if !ok || !fingerprint.Matches(reverted) {
+		return fmt.Errorf("reverted binary is not a clean GameSpy binary, it likely contains other modifications")
+	}
+
+	_, err = f.WriteAt(reverted, 0)
+	return err
+}
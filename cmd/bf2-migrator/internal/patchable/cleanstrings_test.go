@@ -0,0 +1,75 @@
+package patchable
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRevertClean(t *testing.T) {
+	for _, fileName := range []string{GameExecutableName, ServerExecutableName} {
+		clean, err := os.ReadFile(filepath.Join(FixturesDir, fileName, "GameSpy.bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// BF2Hub also changes DLL names, which the clean string table does not cover
+		for _, provider := range []string{"GameSpy", "OpenSpy", "PlayBF2"} {
+			b, err := os.ReadFile(filepath.Join(FixturesDir, fileName, provider+".bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reverted, err := RevertClean(fileName, b)
+			if err != nil {
+				t.Errorf("%s/%s: %s", fileName, provider, err)
+				continue
+			}
+			if !bytes.Equal(reverted, clean) {
+				t.Errorf("%s/%s: reverted bytes do not match GameSpy fixture", fileName, provider)
+			}
+		}
+	}
+}
+
+func TestRevertCleanUnknownProvider(t *testing.T) {
+	clean, err := os.ReadFile(filepath.Join(FixturesDir, GameExecutableName, "GameSpy.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a provider missing from the catalog, using shorter strings than GameSpy
+	b := bytes.ReplaceAll(clean, []byte("gamespy.com\x00"), []byte("example.io\x00\x00"))
+	b = bytes.Replace(b, []byte("\\etc\\hosts"), []byte("\\etc\\hostz"), 1)
+
+	reverted, err := RevertClean(GameExecutableName, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reverted, clean) {
+		t.Error("reverted bytes do not match GameSpy fixture")
+	}
+}
+
+func TestRevertCleanCountMismatch(t *testing.T) {
+	clean, err := os.ReadFile(filepath.Join(FixturesDir, GameExecutableName, "GameSpy.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Second gamestats string no longer starts with the anchor
+	b := bytes.Replace(clean, []byte("\x00gamestats."), []byte("\x00gamestatz."), 2)
+	b = bytes.Replace(b, []byte("gamestatz."), []byte("gamestats."), 1)
+
+	if _, err = RevertClean(GameExecutableName, b); err == nil {
+		t.Error("expected error for unexpected number of strings")
+	}
+}
+
+func TestRevertCleanNoTable(t *testing.T) {
+	if _, err := RevertClean("unknown.exe", nil); !errors.Is(err, ErrNoCleanTable) {
+		t.Errorf("expected ErrNoCleanTable, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package patchable
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Longest hostname that fits into all padded hostname strings (limited by "gpcm.%s"/"gpsp.%s" at 16 bytes)
+	maxHostnameLength = 11
+	hostsPathLength   = 18
+)
+
+var (
+	hostnameAnchors = [][]byte{
+		[]byte("gpcm."),
+		[]byte("gpsp."),
+		[]byte("gamestats."),
+		[]byte("%s.available."),
+		[]byte("%s.master."),
+	}
+	hostsPathAnchor = []byte("\\drivers\\")
+	hostnameRegex   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+)
+
+// Definition describes a provider, either as part of the embedded catalog or added by the user
+// (e.g. by importing it from a binary patched by a third-party patcher)
+type Definition struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	// May use the patch.Pattern syntax to match variations, in which case the provider can only be patched from
+	HostsPath string `json:"hostsPath"`
+	// Values for any additional variables referenced by executable templates (overriding the catalog defaults)
+	Variables map[string]string `json:"variables,omitempty"`
+	// Steps users need to take after migrating their profile to the provider (e.g. confirming their email address)
+	NextSteps []string `json:"nextSteps,omitempty"`
+	Comment   string   `json:"comment,omitempty"`
+}
+
+func (d Definition) Provider() patch.Provider {
+	return patch.Provider(d.Name)
+}
+
+func (d Definition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if d.Hostname == "" || len(d.Hostname) > maxHostnameLength {
+		return fmt.Errorf("hostname must be between 1 and %d characters long", maxHostnameLength)
+	}
+	if patch.ParsePattern(d.HostsPath).Len() != hostsPathLength {
+		return fmt.Errorf("hosts path must be exactly %d characters long", hostsPathLength)
+	}
+	return nil
+}
+
+// NewCustomDefinition creates a definition for a provider using the given hostname, keeping the original hosts path
+// (so the hosts file can still be used to redirect the provider's hostnames)
+func NewCustomDefinition(hostname string) (Definition, error) {
+	hostname = strings.ToLower(strings.TrimSpace(hostname))
+	if !hostnameRegex.MatchString(hostname) {
+		return Definition{}, fmt.Errorf("%q is not a valid hostname", hostname)
+	}
+
+	d := Definition{
+		Name:      hostname,
+		Hostname:  hostname,
+		HostsPath: "\\drivers\\etc\\hosts",
+	}
+	if err := d.Validate(); err != nil {
+		return Definition{}, err
+	}
+
+	return d, nil
+}
+
+// ProposeDefinition extracts the hostname and hosts path strings from a binary patched by an unknown patcher
+func ProposeDefinition(b []byte) (Definition, error) {
+	// Count candidates across all anchors, since patchers don't always replace every hostname
+	votes := map[string]int{}
+	for _, anchor := range hostnameAnchors {
+		for _, candidate := range extractAfter(b, anchor) {
+			votes[string(candidate)]++
+		}
+	}
+
+	var hostname string
+	for candidate, count := range votes {
+		if count > votes[hostname] || (count == votes[hostname] && candidate < hostname) {
+			hostname = candidate
+		}
+	}
+	if hostname == "" {
+		return Definition{}, fmt.Errorf("binary does not contain any known hostname strings")
+	}
+
+	hostsPaths := extractAfter(b, hostsPathAnchor)
+	if len(hostsPaths) != 1 {
+		return Definition{}, fmt.Errorf("binary contains %d hosts paths, expected exactly 1", len(hostsPaths))
+	}
+
+	d := Definition{
+		Name:      hostname,
+		Hostname:  hostname,
+		HostsPath: string(hostsPathAnchor) + string(hostsPaths[0]),
+	}
+	if err := d.Validate(); err != nil {
+		return Definition{}, fmt.Errorf("extracted definition is invalid: %w", err)
+	}
+
+	return d, nil
+}
+
+// extractAfter returns the printable, nil-terminated strings following each occurrence of anchor
+func extractAfter(b []byte, anchor []byte) [][]byte {
+	var results [][]byte
+	for offset := 0; offset < len(b); {
+		i := bytes.Index(b[offset:], anchor)
+		if i == -1 {
+			break
+		}
+
+		start := offset + i + len(anchor)
+		end := start
+		for end < len(b) && b[end] >= 0x20 && b[end] < 0x7f {
+			end++
+		}
+		if end > start && end < len(b) && b[end] == 0 {
+			results = append(results, b[start:end])
+		}
+
+		offset = start
+	}
+
+	return results
+}
@@ -0,0 +1,152 @@
+package patchable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/pe"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// DLLDefinition describes the known-good copies of a provider DLL
+type DLLDefinition struct {
+	FileName string `json:"fileName"`
+	// Oldest version which works with the patched executables (empty if any version works)
+	MinVersion string `json:"minVersion,omitempty"`
+	// SHA-256 hashes of known-good copies (empty if unknown)
+	Hashes  []string `json:"hashes,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+type DLLStatus string
+
+const (
+	DLLStatusOK       DLLStatus = "ok"
+	DLLStatusUnknown  DLLStatus = "unknown"
+	DLLStatusOutdated DLLStatus = "outdated"
+	DLLStatusCorrupt  DLLStatus = "corrupt"
+)
+
+type DLLCheck struct {
+	FileName   string
+	Version    patch.Version
+	HasVersion bool
+	Hash       string
+	Status     DLLStatus
+	// Reason the DLL is not ok
+	Reason string
+}
+
+// Broken returns whether the DLL is known to crash the executables loading it
+func (c DLLCheck) Broken() bool {
+	return c.Status == DLLStatusOutdated || c.Status == DLLStatusCorrupt
+}
+
+func (c DLLCheck) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s: %s", c.FileName, c.Status)
+	}
+	return fmt.Sprintf("%s: %s (%s)", c.FileName, c.Status, c.Reason)
+}
+
+// CheckDLLs checks the catalog's provider DLLs present in dir. DLLs which do not exist are skipped.
+func (c Catalog) CheckDLLs(dir string) ([]DLLCheck, error) {
+	checks := make([]DLLCheck, 0, len(c.DLLs))
+	for _, d := range c.DLLs {
+		b, err := os.ReadFile(filepath.Join(dir, d.FileName))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", d.FileName, err)
+		}
+
+		check, err := checkDLL(d, b)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+func checkDLL(d DLLDefinition, b []byte) (DLLCheck, error) {
+	sum := sha256.Sum256(b)
+	check := DLLCheck{
+		FileName: d.FileName,
+		Hash:     hex.EncodeToString(sum[:]),
+		Status:   DLLStatusOK,
+	}
+
+	for _, hash := range d.Hashes {
+		// Known-good hashes are authoritative
+		if strings.EqualFold(hash, check.Hash) {
+			check.Version, check.HasVersion = patch.ReadFileVersion(b)
+			return check, nil
+		}
+	}
+
+	if reason := validateDLL(b); reason != "" {
+		check.Status = DLLStatusCorrupt
+		check.Reason = reason
+		return check, nil
+	}
+
+	check.Version, check.HasVersion = patch.ReadFileVersion(b)
+	if d.MinVersion != "" {
+		required, err := patch.ParseVersion(d.MinVersion)
+		if err != nil {
+			return DLLCheck{}, fmt.Errorf("invalid minimum version for %s: %w", d.FileName, err)
+		}
+		if !check.HasVersion {
+			check.Status = DLLStatusUnknown
+			check.Reason = "no version information"
+			return check, nil
+		}
+		if check.Version.Less(required) {
+			check.Status = DLLStatusOutdated
+			check.Reason = fmt.Sprintf("version %s, requires %s or later", check.Version, required)
+			return check, nil
+		}
+	}
+
+	if len(d.Hashes) > 0 {
+		check.Status = DLLStatusUnknown
+		check.Reason = "hash does not match any known-good copy"
+	}
+
+	return check, nil
+}
+
+// validateDLL returns why the binary is not a loadable 32-bit DLL (empty if it is)
+func validateDLL(b []byte) string {
+	f, err := pe.NewFile(bytes.NewReader(b))
+	if err != nil {
+		return "not a valid DLL"
+	}
+	defer f.Close()
+
+	// Battlefield 2 is a 32-bit game, so it cannot load 64-bit DLLs
+	if f.Machine != pe.IMAGE_FILE_MACHINE_I386 {
+		return "not a 32-bit DLL"
+	}
+	if f.Characteristics&pe.IMAGE_FILE_DLL == 0 {
+		return "not a DLL"
+	}
+
+	// Truncated copies (e.g. from interrupted downloads) are missing the end of their last section(s)
+	for _, s := range f.Sections {
+		if int64(s.Offset)+int64(s.Size) > int64(len(b)) {
+			return "file is truncated"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,42 @@
+package patchable
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	dllModificationName = "dll"
+	// Loaded from the Windows system folder, so never needs to be deployed to the install folder
+	systemDLL = "WS2_32.dll"
+)
+
+// RequiredDLLs returns the non-system DLLs loaded by the executables once patched for the provider, mapped by executable
+func (c Catalog) RequiredDLLs(provider patch.Provider) (map[string]string, error) {
+	d, ok := c.Provider(provider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not defined in catalog", provider)
+	}
+
+	dlls := map[string]string{}
+	for _, e := range c.Executables {
+		for _, t := range e.Modifications {
+			if t.Name != dllModificationName {
+				continue
+			}
+
+			dll, err := c.expand(t.Template, d)
+			if err != nil {
+				return nil, err
+			}
+
+			if !strings.EqualFold(dll, systemDLL) {
+				dlls[e.FileName] = dll
+			}
+		}
+	}
+
+	return dlls, nil
+}
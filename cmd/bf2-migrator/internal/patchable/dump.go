@@ -0,0 +1,113 @@
+package patchable
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	minDumpStringLength = 6
+)
+
+var (
+	hostnameLikeRegex = regexp.MustCompile(`(?i)[a-z0-9%-]+(\.[a-z0-9%-]+)*\.[a-z]{2,}`)
+	// Strings containing any of these are relevant for provider fingerprints
+	dumpKeywords = []string{
+		"gamespy", "gpcm", "gpsp", "master", "available", "gamestats", "bf2web", "stage-net", ".ms",
+		"\\drivers\\", ".dll",
+	}
+)
+
+// DumpFingerprint creates a report of all provider-relevant strings in an unrecognized binary. The report only contains
+// strings related to hostnames, hosts paths and DLL names, so it can safely be shared without sharing the binary itself.
+func DumpFingerprint(fileName string, b []byte, patchables []patch.Patchable) (string, error) {
+	info, err := IdentifyBuild(fileName, b)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("File: %s\n", fileName))
+	sb.WriteString(fmt.Sprintf("Size: %d bytes\n", len(b)))
+	sb.WriteString(fmt.Sprintf("SHA-256: %s\n", info.Hash))
+	sb.WriteString(fmt.Sprintf("Version: %s\n", info))
+
+	sb.WriteString("\nFingerprint scores:\n")
+	for _, p := range patchables {
+		if !strings.EqualFold(p.GetFileName(), fileName) {
+			continue
+		}
+
+		if variant := VariantOf(p); variant != "" {
+			sb.WriteString(fmt.Sprintf(" %s variant:\n", variant))
+		}
+
+		scores := make([]string, 0)
+		for provider, fingerprint := range p.GetFingerprints() {
+			matched, total := patch.Score(fingerprint, b)
+			scores = append(scores, fmt.Sprintf("%s: %d/%d", provider, matched, total))
+		}
+		sort.Strings(scores)
+		for _, score := range scores {
+			sb.WriteString(fmt.Sprintf("  %s\n", score))
+		}
+	}
+
+	sb.WriteString("\nRelevant strings (offset: value):\n")
+	for _, s := range extractRelevantStrings(b) {
+		sb.WriteString(fmt.Sprintf("  %#08x: %q\n", s.offset, s.value))
+	}
+
+	return sb.String(), nil
+}
+
+type offsetString struct {
+	offset int
+	value  string
+}
+
+func extractRelevantStrings(b []byte) []offsetString {
+	var results []offsetString
+	start := -1
+	for i := 0; i <= len(b); i++ {
+		printable := i < len(b) && b[i] >= 0x20 && b[i] < 0x7f
+		if printable {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+
+		if start != -1 && i-start >= minDumpStringLength {
+			value := b[start:i]
+			if isRelevantString(value) {
+				results = append(results, offsetString{offset: start, value: string(value)})
+			}
+		}
+		start = -1
+	}
+
+	return results
+}
+
+func isRelevantString(s []byte) bool {
+	lower := bytes.ToLower(s)
+	for _, keyword := range dumpKeywords {
+		if !bytes.Contains(lower, []byte(keyword)) {
+			continue
+		}
+
+		// Hosts paths and DLL names are relevant as is, anything else needs to look like a hostname
+		if keyword == "\\drivers\\" || keyword == ".dll" {
+			return len(s) <= 32
+		}
+		return hostnameLikeRegex.Match(s)
+	}
+
+	return false
+}
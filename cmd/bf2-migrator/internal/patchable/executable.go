@@ -0,0 +1,142 @@
+package patchable
+
+import (
+	"fmt"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	GameExecutableName   = "BF2.exe"
+	ServerExecutableName = "bf2_w32ded.exe"
+)
+
+// Executable is a patchable executable as described by a catalog
+type Executable struct {
+	definition ExecutableDefinition
+	catalog    Catalog
+	// File name of a renamed copy of the executable (empty to use the definition's file name)
+	fileName string
+}
+
+func (e Executable) GetFileName() string {
+	if e.fileName != "" {
+		return e.fileName
+	}
+	return e.definition.FileName
+}
+
+// IsOptional returns whether the patchable is an executable not included with every installation
+func IsOptional(p patch.Patchable) bool {
+	e, ok := p.(Executable)
+	return ok && e.definition.Optional
+}
+
+// VariantOf returns the name of the build variant the patchable describes (empty for the default build)
+func VariantOf(p patch.Patchable) string {
+	if e, ok := p.(Executable); ok {
+		return e.definition.Variant
+	}
+	return ""
+}
+
+// IsServer returns whether the patchable is a dedicated server executable
+func IsServer(p patch.Patchable) bool {
+	e, ok := p.(Executable)
+	return ok && e.definition.Server
+}
+
+func (e Executable) GetFingerprints() map[patch.Provider]patch.Fingerprint {
+	fingerprints := make(map[patch.Provider]patch.Fingerprint, len(e.catalog.Providers))
+	for _, d := range e.catalog.Providers {
+		fingerprint, err := e.getFingerprint(d)
+		if err != nil {
+			// Providers missing variables cannot be detected for this executable
+			continue
+		}
+		fingerprints[d.Provider()] = fingerprint
+	}
+
+	return fingerprints
+}
+
+func (e Executable) GetModifications(old, new patch.Provider) ([]patch.Modification, error) {
+	wipe, ok := e.catalog.Provider(old)
+	if !ok {
+		return nil, fmt.Errorf("missing definition for old provider: %s", old)
+	}
+
+	apply, ok := e.catalog.Provider(new)
+	if !ok {
+		return nil, fmt.Errorf("missing definition for new provider: %s", new)
+	}
+
+	modifications := make([]patch.Modification, 0, len(e.definition.Modifications))
+	for _, t := range e.definition.Modifications {
+		o, err := e.catalog.expand(t.Template, wipe)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := e.catalog.expand(t.Template, apply)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.SkipUnchanged && o == n {
+			continue
+		}
+
+		np := patch.ParsePattern(n)
+		if !np.IsExact() {
+			return nil, fmt.Errorf("cannot patch to provider with variable strings: %s", new)
+		}
+
+		m := patch.Modification{
+			Name:   t.Name,
+			New:    np.Bytes(),
+			Length: t.Length,
+			Count:  t.Count,
+		}
+
+		// Strings of the old provider may contain wildcards to tolerate variations between patcher versions
+		if op := patch.ParsePattern(o); op.IsExact() {
+			m.Old = op.Bytes()
+		} else {
+			m.Pattern = &op
+		}
+
+		modifications = append(modifications, m)
+	}
+
+	return modifications, nil
+}
+
+func (e Executable) getFingerprint(d Definition) (executableFingerprint, error) {
+	fingerprint := make(executableFingerprint, 0, len(e.definition.Fingerprint))
+	for _, t := range e.definition.Fingerprint {
+		s, err := e.catalog.expand(t, d)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint = append(fingerprint, patch.ParsePattern(s))
+	}
+
+	return fingerprint, nil
+}
+
+type executableFingerprint []patch.Pattern
+
+func (f executableFingerprint) Matches(b []byte) bool {
+	return patch.ContainsAllPatterns(b, f)
+}
+
+func (f executableFingerprint) Score(b []byte) (int, int) {
+	matched := 0
+	for _, p := range f {
+		if p.Matches(b) {
+			matched++
+		}
+	}
+	return matched, len(f)
+}
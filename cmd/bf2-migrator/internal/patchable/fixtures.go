@@ -0,0 +1,33 @@
+package patchable
+
+import (
+	"os"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+	"github.com/cetteup/bf2-migrator/pkg/patch/patchtest"
+)
+
+const (
+	FixturesDir = "testdata/fixtures"
+)
+
+// CheckFixtures verifies the embedded catalog against the golden-byte fixtures in dir,
+// patching every fixture to every provider and back
+func CheckFixtures(dir string) []error {
+	catalog, err := LoadCatalog()
+	if err != nil {
+		return []error{err}
+	}
+
+	fixtures, err := patchtest.LoadFixtures(os.DirFS(dir), ".")
+	if err != nil {
+		return []error{err}
+	}
+
+	targets := make([]patch.Provider, 0, len(catalog.Providers))
+	for _, d := range catalog.Providers {
+		targets = append(targets, d.Provider())
+	}
+
+	return patchtest.Check(catalog.Patchables(), fixtures, targets)
+}
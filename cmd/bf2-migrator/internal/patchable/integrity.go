@@ -0,0 +1,34 @@
+package patchable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// VerifyEmbedded checks that the embedded catalog and builds are complete and consistent, which they
+// are not if the binary has been truncated or modified after it was built
+func VerifyEmbedded() error {
+	catalog, err := LoadCatalog()
+	if err != nil {
+		return err
+	}
+	if len(catalog.Executables) == 0 || len(catalog.Providers) == 0 {
+		return errors.New("embedded catalog is empty")
+	}
+	for _, d := range catalog.Providers {
+		if err = catalog.ValidateDefinition(d); err != nil {
+			return fmt.Errorf("embedded catalog contains an invalid definition of %s: %w", d.Name, err)
+		}
+	}
+
+	var builds []build
+	if err = json.Unmarshal(buildsJSON, &builds); err != nil {
+		return fmt.Errorf("failed to parse embedded builds: %w", err)
+	}
+	if len(builds) == 0 {
+		return errors.New("embedded builds are empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package patchable
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+func TestCheckFixtures(t *testing.T) {
+	for _, err := range CheckFixtures(FixturesDir) {
+		t.Error(err)
+	}
+}
+
+func TestPatchAllCountMismatch(t *testing.T) {
+	catalog, err := LoadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile(filepath.Join(FixturesDir, GameExecutableName, "GameSpy.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Full build with one of the gamestats strings missing must not be patched partially
+	b := bytes.Replace(original, []byte("gamestats.gamespy.com"), []byte("gamestats.gamespy.org"), 1)
+	dir := t.TempDir()
+	path := filepath.Join(dir, GameExecutableName)
+	if err = os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var patchables []patch.Patchable
+	for _, p := range catalog.Patchables() {
+		if p.GetFileName() == GameExecutableName {
+			patchables = append(patchables, p)
+		}
+	}
+
+	if err = PatchAll(patchables, dir, ProviderOpenSpy); !errors.Is(err, patch.ErrUnknownModifications) {
+		t.Errorf("expected ErrUnknownModifications, got %v", err)
+	}
+
+	patched, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(patched, b) {
+		t.Error("binary was modified")
+	}
+}
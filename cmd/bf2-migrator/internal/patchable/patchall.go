@@ -0,0 +1,252 @@
+package patchable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+// PatchAll patches each executable in dir (trying its build variants in order) to use the new provider. Optional
+// executables which do not exist are skipped.
+func PatchAll(patchables []patch.Patchable, dir string, new patch.Provider) error {
+	return PatchAllWithProgress(patchables, dir, new, nil)
+}
+
+// PatchAllWithProgress patches the executables like PatchAll, calling progress (if set) whenever patching one of them
+// reaches a new step
+func PatchAllWithProgress(patchables []patch.Patchable, dir string, new patch.Provider, progress patch.ProgressFunc) error {
+	return PatchAllInspected(patchables, dir, new, progress, nil)
+}
+
+// PatchAllInspected patches the executables like PatchAllWithProgress, using the given inspections (keyed by file name,
+// made using the executable's first variant) instead of reading those executables again. Executables which changed
+// since they were inspected are not patched (see patch.ErrChanged).
+func PatchAllInspected(
+	patchables []patch.Patchable,
+	dir string,
+	new patch.Provider,
+	progress patch.ProgressFunc,
+	inspections map[string]patch.Inspection,
+) error {
+	byName := make(map[string]patch.Inspection, len(inspections))
+	for name, inspection := range inspections {
+		byName[strings.ToLower(name)] = inspection
+	}
+
+	// Patch as many executables as possible, rather than stopping at the first one which fails
+	var err error
+	for _, variants := range groupByFileName(patchables) {
+		var inspection *patch.Inspection
+		if i, ok := byName[strings.ToLower(variants[0].GetFileName())]; ok {
+			inspection = &i
+		}
+		err = multierr.Append(err, patchVariants(variants, dir, new, progress, inspection))
+	}
+
+	return err
+}
+
+// FileNames returns the names of the patchables' executables, once per executable (regardless of build variants)
+func FileNames(patchables []patch.Patchable) []string {
+	groups := groupByFileName(patchables)
+	names := make([]string, 0, len(groups))
+	for _, variants := range groups {
+		names = append(names, variants[0].GetFileName())
+	}
+	return names
+}
+
+// DryRun patches copies of the executables in dir to use the new provider, verifying that PatchAll would succeed
+// without modifying them
+func DryRun(patchables []patch.Patchable, dir string, new patch.Provider) error {
+	tmp, err := os.MkdirTemp("", "bf2-migrator-dry-run-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary folder: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, variants := range groupByFileName(patchables) {
+		name := variants[0].GetFileName()
+		b, err2 := os.ReadFile(patch.LongPath(filepath.Join(dir, name)))
+		if err2 != nil {
+			// Missing executables are reported by the patch itself
+			if errors.Is(err2, os.ErrNotExist) {
+				continue
+			}
+			return err2
+		}
+
+		target := filepath.Join(tmp, name)
+		if err2 = os.MkdirAll(filepath.Dir(target), 0755); err2 != nil {
+			return err2
+		}
+		if err2 = os.WriteFile(target, b, 0644); err2 != nil {
+			return err2
+		}
+	}
+
+	return PatchAll(patchables, tmp, new)
+}
+
+// AdoptAll normalizes each executable in dir patched by another patcher (trying its build variants in order) to the
+// layout used when patching, returning the names of the executables which were changed
+func AdoptAll(patchables []patch.Patchable, dir string) ([]string, error) {
+	var adopted []string
+	var err error
+	for _, variants := range groupByFileName(patchables) {
+		changed, err2 := adoptVariants(variants, dir)
+		if err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("%s: %w", variants[0].GetFileName(), err2))
+			continue
+		}
+		if changed {
+			adopted = append(adopted, variants[0].GetFileName())
+		}
+	}
+
+	return adopted, err
+}
+
+// adoptVariants adopts the executable using the first build variant whose strings match the binary
+func adoptVariants(variants []patch.Patchable, dir string) (bool, error) {
+	var err error
+	var mismatched patch.Patchable
+	for _, p := range variants {
+		if mismatched != nil && !coversModifications(p, mismatched) {
+			continue
+		}
+
+		var changed bool
+		_, changed, err = patch.Adopt(p, dir)
+		if err == nil {
+			return changed, nil
+		}
+
+		if errors.Is(err, patch.ErrNotExist) && IsOptional(p) {
+			return false, nil
+		}
+
+		if !errors.Is(err, patch.ErrUnknownModifications) && !errors.Is(err, patch.ErrNotPatchable) {
+			break
+		}
+
+		// The binary matched the variant's fingerprint, so any variant modifying fewer strings would only patch it
+		// partially (e.g. a variant lacking the stats strings leaving those of a full build untouched)
+		if mismatched == nil && errors.Is(err, patch.ErrUnknownModifications) {
+			mismatched = p
+		}
+	}
+
+	return false, err
+}
+
+// patchVariants patches the executable using the first build variant whose strings match the binary, reading the binary
+// only once (unless inspection is set, in which case it is not read again at all)
+func patchVariants(
+	variants []patch.Patchable,
+	dir string,
+	new patch.Provider,
+	progress patch.ProgressFunc,
+	inspection *patch.Inspection,
+) error {
+	if inspection == nil {
+		i, err := patch.Inspect(variants[0], dir)
+		if err != nil {
+			// Some executables (e.g. the server executable) are not included with every installation
+			if errors.Is(err, patch.ErrNotExist) && IsOptional(variants[0]) {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", variants[0].GetFileName(), err)
+		}
+		inspection = &i
+	}
+
+	var err error
+	var mismatched patch.Patchable
+	for i, p := range variants {
+		if mismatched != nil && !coversModifications(p, mismatched) {
+			continue
+		}
+
+		// Variants differ in the strings they look for, so only the first one can use the inspection as it is
+		inspected := *inspection
+		if i > 0 {
+			inspected = inspection.Reinspect(p)
+		}
+		err = patch.PatchContext(context.Background(), p, dir, new, patch.Options{Progress: progress, Inspection: &inspected})
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, patch.ErrUnknownModifications) && !errors.Is(err, patch.ErrNotPatchable) {
+			break
+		}
+
+		// The binary matched the variant's fingerprint, so any variant modifying fewer strings would only patch it
+		// partially (e.g. a variant lacking the stats strings leaving those of a full build untouched)
+		if mismatched == nil && errors.Is(err, patch.ErrUnknownModifications) {
+			mismatched = p
+		}
+	}
+
+	// Binaries patched for providers missing from the catalog can still be reverted using the original strings
+	if new == ProviderGameSpy && (errors.Is(err, patch.ErrUnknownModifications) || errors.Is(err, patch.ErrNotPatchable)) {
+		err2 := revertClean(variants[0], dir)
+		if err2 == nil {
+			return nil
+		}
+		if !errors.Is(err2, ErrNoCleanTable) {
+			err = multierr.Append(err, fmt.Errorf("failed to revert using clean string table: %w", err2))
+		}
+	}
+
+	return fmt.Errorf("%s: %w", variants[0].GetFileName(), err)
+}
+
+// coversModifications returns whether variant modifies (at least) every string modified by other
+func coversModifications(variant patch.Patchable, other patch.Patchable) bool {
+	v, ok := variant.(Executable)
+	if !ok {
+		return false
+	}
+	o, ok := other.(Executable)
+	if !ok {
+		return false
+	}
+
+	names := make(map[string]bool, len(v.definition.Modifications))
+	for _, t := range v.definition.Modifications {
+		names[t.Name] = true
+	}
+	for _, t := range o.definition.Modifications {
+		if !names[t.Name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// groupByFileName groups the patchables by their executable, keeping the order of both executables and variants
+func groupByFileName(patchables []patch.Patchable) [][]patch.Patchable {
+	var groups [][]patch.Patchable
+	indices := map[string]int{}
+	for _, p := range patchables {
+		key := strings.ToLower(p.GetFileName())
+		if i, ok := indices[key]; ok {
+			groups[i] = append(groups[i], p)
+			continue
+		}
+		indices[key] = len(groups)
+		groups = append(groups, []patch.Patchable{p})
+	}
+
+	return groups
+}
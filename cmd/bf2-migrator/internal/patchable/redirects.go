@@ -0,0 +1,118 @@
+package patchable
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	// Number of master servers ("<game>.ms<n>.gamespy.com") the GameSpy SDK's server browsing code picks from, using
+	// a hash of the game name (NUM_MASTER_SERVERS in the SDK). Redirect all of them rather than replicating the hash.
+	masterServerCount = 20
+	// Game name used in GameSpy hostnames (e.g. "battlefield2.available.gamespy.com")
+	gamespyGameName = "battlefield2"
+)
+
+// Redirect maps a GameSpy hostname to the provider hostname replacing it
+type Redirect struct {
+	From string
+	To   string
+}
+
+// Redirects returns the GameSpy hostnames used by the catalog's executables, mapped to the provider's replacements
+func (c Catalog) Redirects(provider patch.Provider) ([]Redirect, error) {
+	gamespy, ok := c.Provider(ProviderGameSpy)
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not defined in catalog", ProviderGameSpy)
+	}
+
+	d, ok := c.Provider(provider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not defined in catalog", provider)
+	}
+
+	// Providers keeping the GameSpy hostnames (e.g. BF2Hub) redirect via their DLLs instead
+	if strings.EqualFold(d.Hostname, gamespy.Hostname) {
+		return nil, fmt.Errorf("provider %s uses the original GameSpy hostnames", provider)
+	}
+
+	var redirects []Redirect
+	seen := map[string]bool{}
+	for _, e := range c.Executables {
+		for _, t := range e.Modifications {
+			from, err := c.expand(t.Template, gamespy)
+			if err != nil {
+				return nil, err
+			}
+			// Only hostname modifications are relevant (not e.g. the hosts path or DLL)
+			if !strings.Contains(strings.ToLower(from), strings.ToLower(gamespy.Hostname)) {
+				continue
+			}
+
+			to, err := c.expand(t.Template, d)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, r := range expandRedirect(hostnameOf(from), hostnameOf(to)) {
+				key := strings.ToLower(r.From)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				redirects = append(redirects, r)
+			}
+		}
+	}
+
+	return redirects, nil
+}
+
+// Domains returns the (unique) hostnames of all providers in the catalog, e.g. "gamespy.com"
+func (c Catalog) Domains() []string {
+	domains := make([]string, 0, len(c.Providers))
+	seen := map[string]bool{}
+	for _, d := range c.Providers {
+		key := strings.ToLower(d.Hostname)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		domains = append(domains, d.Hostname)
+	}
+	return domains
+}
+
+// hostnameOf returns the hostname of a url, or the string itself if it is not a url
+func hostnameOf(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	return u.Hostname()
+}
+
+// expandRedirect replaces the format verbs used in hostnames with the game name/master server numbers
+func expandRedirect(from, to string) []Redirect {
+	from = strings.ReplaceAll(from, "%s", gamespyGameName)
+	to = strings.ReplaceAll(to, "%s", gamespyGameName)
+	if !strings.Contains(from, "%d") {
+		return []Redirect{{From: from, To: to}}
+	}
+
+	// Some providers (e.g. PlayBF2) use a single master server hostname without a number
+	redirects := make([]Redirect, 0, masterServerCount)
+	for i := 0; i < masterServerCount; i++ {
+		n := strconv.Itoa(i)
+		redirects = append(redirects, Redirect{
+			From: strings.ReplaceAll(from, "%d", n),
+			To:   strings.ReplaceAll(to, "%d", n),
+		})
+	}
+
+	return redirects
+}
@@ -0,0 +1,124 @@
+package patchable
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cetteup/bf2-migrator/pkg/sysproxy"
+)
+
+const (
+	// Signed catalog (and its signature) attached to the latest release by the release workflow
+	RemoteCatalogURL = "https://github.com/cetteup/bf2-migrator/releases/latest/download/catalog.json"
+
+	remoteCatalogTimeout = 10 * time.Second
+	// Catalogs are tiny, anything larger is not a catalog
+	maxRemoteCatalogSize = 1024 * 1024
+)
+
+// Base64-encoded ed25519 public key used to verify remote catalogs, set at build time by the release workflow via
+// -ldflags "-X github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable.remoteCatalogPublicKey=..."
+var remoteCatalogPublicKey = ""
+
+// FetchRemoteCatalog downloads the catalog (and its detached signature at catalogURL + ".sig") from catalogURL, verifies
+// the signature and stores the catalog at path, so it is merged into the embedded catalog on the next load. Catalogs
+// older than the embedded/stored one or containing invalid definitions are rejected. Requests are sent via the proxy
+// returned by proxy (nil to use the system proxy).
+func FetchRemoteCatalog(catalogURL string, path string, proxy func(*http.Request) (*url.URL, error)) (Catalog, error) {
+	if remoteCatalogPublicKey == "" {
+		return Catalog{}, fmt.Errorf("remote catalog updates are not available in this build")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(remoteCatalogPublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return Catalog{}, fmt.Errorf("invalid remote catalog public key")
+	}
+
+	// Corporate/school networks often only allow traffic via the system's proxy
+	if proxy == nil {
+		proxy = sysproxy.FromSystem
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	client := &http.Client{Timeout: remoteCatalogTimeout, Transport: transport}
+	data, err := download(client, catalogURL)
+	if err != nil {
+		return Catalog{}, fmt.Errorf("failed to download catalog: %w", err)
+	}
+
+	encoded, err := download(client, catalogURL+".sig")
+	if err != nil {
+		return Catalog{}, fmt.Errorf("failed to download catalog signature: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return Catalog{}, fmt.Errorf("failed to decode catalog signature: %w", err)
+	}
+
+	if !ed25519.Verify(key, data, signature) {
+		return Catalog{}, fmt.Errorf("catalog signature is invalid")
+	}
+
+	var catalog Catalog
+	if err = json.Unmarshal(data, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+
+	embedded, err := LoadCatalog()
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	// Signatures do not expire, so an outdated catalog could be served to undo fixed definitions
+	installed, err := LoadCatalog(path)
+	if err != nil {
+		// A corrupt stored catalog is simply replaced
+		installed = embedded
+	}
+	if catalog.Version < installed.Version {
+		return Catalog{}, fmt.Errorf("catalog version %d is older than the installed version %d", catalog.Version, installed.Version)
+	}
+
+	merged := embedded.merge(catalog)
+	for _, d := range merged.Providers {
+		if err = merged.ValidateDefinition(d); err != nil {
+			return Catalog{}, fmt.Errorf("catalog contains an invalid definition of %s: %w", d.Name, err)
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Catalog{}, err
+	}
+
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return Catalog{}, err
+	}
+
+	return catalog, nil
+}
+
+func download(client *http.Client, url string) ([]byte, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, maxRemoteCatalogSize))
+}
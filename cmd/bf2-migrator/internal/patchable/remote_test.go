@@ -0,0 +1,157 @@
+package patchable
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func noProxy(*http.Request) (*url.URL, error) {
+	return nil, nil
+}
+
+// serveCatalog serves the catalog signed with key at /catalog.json (and the signature at /catalog.json.sig)
+func serveCatalog(t *testing.T, key ed25519.PrivateKey, catalog Catalog) string {
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/catalog.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/catalog.json.sig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(signature))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server.URL + "/catalog.json"
+}
+
+func TestFetchRemoteCatalog(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := remoteCatalogPublicKey
+	remoteCatalogPublicKey = base64.StdEncoding.EncodeToString(public)
+	t.Cleanup(func() {
+		remoteCatalogPublicKey = original
+	})
+
+	embedded, err := LoadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := Definition{Name: "Example", Hostname: "example.com", HostsPath: `\drivers\etc\hosts`}
+
+	type test struct {
+		name      string
+		key       ed25519.PrivateKey
+		catalog   Catalog
+		installed *Catalog
+		wantErr   bool
+	}
+
+	tests := []test{
+		{
+			name:    "stores catalog with new provider",
+			key:     private,
+			catalog: Catalog{Version: embedded.Version + 1, Providers: []Definition{valid}},
+		},
+		{
+			name:    "stores catalog of current version",
+			key:     private,
+			catalog: Catalog{Version: embedded.Version, Providers: []Definition{valid}},
+		},
+		{
+			name:    "errors for catalog signed with other key",
+			key:     other,
+			catalog: Catalog{Version: embedded.Version + 1, Providers: []Definition{valid}},
+			wantErr: true,
+		},
+		{
+			name:    "errors for catalog older than embedded catalog",
+			key:     private,
+			catalog: Catalog{Version: embedded.Version - 1, Providers: []Definition{valid}},
+			wantErr: true,
+		},
+		{
+			name:      "errors for catalog older than installed catalog",
+			key:       private,
+			catalog:   Catalog{Version: embedded.Version + 1, Providers: []Definition{valid}},
+			installed: &Catalog{Version: embedded.Version + 2},
+			wantErr:   true,
+		},
+		{
+			name: "errors for catalog with invalid definition",
+			key:  private,
+			catalog: Catalog{Version: embedded.Version + 1, Providers: []Definition{
+				{Name: "TooLong", Hostname: "too-long.example.com", HostsPath: `\drivers\etc\hosts`},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			path := filepath.Join(t.TempDir(), "remote.json")
+			if tt.installed != nil {
+				data, err := json.Marshal(tt.installed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			catalogURL := serveCatalog(t, tt.key, tt.catalog)
+
+			// WHEN
+			catalog, err := FetchRemoteCatalog(catalogURL, path, noProxy)
+
+			// THEN
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if tt.installed == nil {
+					if _, err2 := os.Stat(path); err2 == nil {
+						t.Errorf("expected rejected catalog not to be stored")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if catalog.Version != tt.catalog.Version {
+				t.Errorf("expected version %d, got %d", tt.catalog.Version, catalog.Version)
+			}
+			loaded, err := LoadCatalog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := loaded.Provider(valid.Provider()); !ok {
+				t.Errorf("expected stored catalog to contain %s", valid.Name)
+			}
+		})
+	}
+}
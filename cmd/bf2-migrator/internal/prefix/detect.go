@@ -0,0 +1,141 @@
+package prefix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type registryValue struct {
+	file string
+	key  string
+	name string
+}
+
+// Same sources as used to detect the install folder on Windows, in the same order
+var installDirValues = []registryValue{
+	{file: "system.reg", key: "Software\\Wow6432Node\\Electronic Arts\\EA Games\\Battlefield 2", name: "InstallDir"},
+	// 32-bit prefixes do not have a WOW6432Node
+	{file: "system.reg", key: "Software\\Electronic Arts\\EA Games\\Battlefield 2", name: "InstallDir"},
+	{file: "user.reg", key: "Software\\BF2Hub Systems\\BF2Hub Client", name: "bf2Dir"},
+}
+
+// FindPrefixes returns the Wine/Proton prefixes found in the usual locations, starting with the one set via WINEPREFIX
+func FindPrefixes() []string {
+	var candidates []string
+	if p := os.Getenv("WINEPREFIX"); p != "" {
+		candidates = append(candidates, p)
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		candidates = append(candidates, filepath.Join(home, ".wine"))
+
+		// Proton prefixes are stored per game in Steam's compatdata folder (native, symlinked and Flatpak installs)
+		steamDirs := []string{
+			filepath.Join(home, ".steam", "steam"),
+			filepath.Join(home, ".local", "share", "Steam"),
+			filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		}
+		for _, steamDir := range steamDirs {
+			matches, err2 := filepath.Glob(filepath.Join(steamDir, "steamapps", "compatdata", "*", "pfx"))
+			if err2 != nil {
+				continue
+			}
+			candidates = append(candidates, matches...)
+		}
+	}
+
+	prefixes := make([]string, 0, len(candidates))
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		// ~/.steam/steam usually is a symlink to ~/.local/share/Steam
+		resolved, err2 := filepath.EvalSymlinks(candidate)
+		if err2 != nil || seen[resolved] || !IsPrefix(resolved) {
+			continue
+		}
+		seen[resolved] = true
+		prefixes = append(prefixes, candidate)
+	}
+
+	return prefixes
+}
+
+// IsPrefix returns whether the folder is a Wine/Proton prefix
+func IsPrefix(dir string) bool {
+	stat, err := os.Stat(filepath.Join(dir, "drive_c"))
+	return err == nil && stat.IsDir()
+}
+
+// FindInstallDir returns the Battlefield 2 install folder registered in the prefix, translated to a host path
+func FindInstallDir(prefix string) (string, error) {
+	for _, v := range installDirValues {
+		value, ok, err := readRegistryValue(filepath.Join(prefix, v.file), v.key, v.name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read prefix registry: %w", err)
+		}
+		if !ok || value == "" {
+			continue
+		}
+
+		dir, err := ToHostPath(prefix, value)
+		if err != nil {
+			continue
+		}
+		if _, err = os.Stat(dir); err != nil {
+			continue
+		}
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("found no Battlefield 2 install folder in prefix %s", prefix)
+}
+
+// ToHostPath translates a Windows path inside the prefix (e.g. "C:\Games\Battlefield 2") to a host path
+func ToHostPath(prefix string, path string) (string, error) {
+	if len(path) < 2 || path[1] != ':' {
+		return "", fmt.Errorf("not an absolute windows path: %s", path)
+	}
+
+	// Drives are mapped via symlinks in dosdevices, "c:" pointing to "../drive_c" by default
+	drive := filepath.Join(prefix, "dosdevices", strings.ToLower(path[:2]))
+	if _, err := os.Stat(drive); err != nil {
+		if !strings.EqualFold(path[:1], "c") {
+			return "", fmt.Errorf("drive %s is not mapped in prefix %s", path[:2], prefix)
+		}
+		drive = filepath.Join(prefix, "drive_c")
+	}
+
+	parts := strings.FieldsFunc(path[2:], func(r rune) bool {
+		return r == '\\' || r == '/'
+	})
+
+	return filepath.Join(append([]string{drive}, parts...)...), nil
+}
+
+// Detect returns the first detected prefix containing Battlefield 2 profiles
+func Detect() (string, error) {
+	for _, p := range FindPrefixes() {
+		if _, err := FindProfilesDir(p); err == nil {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("found no Wine/Proton prefix containing Battlefield 2 profiles")
+}
+
+// DetectInstallDir returns the Battlefield 2 install folder of the first detected prefix which has one
+func DetectInstallDir() (string, error) {
+	for _, p := range FindPrefixes() {
+		if dir, err := FindInstallDir(p); err == nil {
+			return dir, nil
+		}
+	}
+
+	return "", fmt.Errorf("found no Wine/Proton prefix containing a Battlefield 2 install")
+}
@@ -0,0 +1,108 @@
+// Package prefix detects Wine/Proton prefixes and reads Battlefield 2 installs/profiles from them, without requiring Windows
+package prefix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cetteup/conman/pkg/config"
+)
+
+const (
+	defaultProfileKey  = "Default"
+	profileConFileName = "Profile.con"
+
+	profileConKeyName        = "LocalProfile.setName"
+	profileConKeyGamespyNick = "LocalProfile.setGamespyNick"
+	profileConKeyEmail       = "LocalProfile.setEmail"
+)
+
+// Profile is a Battlefield 2 profile. Passwords are encrypted using Windows' data protection API, so they cannot be
+// read outside of Windows/the prefix and have to be provided by the user instead.
+type Profile struct {
+	Key   string
+	Name  string
+	Nick  string
+	Email string
+}
+
+// Multiplayer returns whether the profile is a multiplayer (online) profile
+func (p Profile) Multiplayer() bool {
+	return p.Email != ""
+}
+
+// FindProfilesDir returns the Battlefield 2 profiles folder of the first user in the prefix which has one
+func FindProfilesDir(prefix string) (string, error) {
+	patterns := []string{
+		filepath.Join(prefix, "drive_c", "users", "*", "Documents", "Battlefield 2", "Profiles"),
+		filepath.Join(prefix, "drive_c", "users", "*", "My Documents", "Battlefield 2", "Profiles"),
+	}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("found no Battlefield 2 profiles folder in prefix %s", prefix)
+}
+
+// ReadProfiles reads all profiles from the profiles folder
+func ReadProfiles(dir string) ([]Profile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == defaultProfileKey {
+			continue
+		}
+
+		profile, err2 := ReadProfile(dir, entry.Name())
+		if err2 != nil {
+			// Not a profile folder
+			if errors.Is(err2, os.ErrNotExist) {
+				continue
+			}
+			return nil, err2
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// ReadProfile reads the profile with the given key from the profiles folder
+func ReadProfile(dir string, key string) (Profile, error) {
+	path := filepath.Join(dir, key, profileConFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	profileCon := config.FromBytes(path, data)
+	profile := Profile{
+		Key:   key,
+		Name:  getValue(profileCon, profileConKeyName),
+		Nick:  getValue(profileCon, profileConKeyGamespyNick),
+		Email: getValue(profileCon, profileConKeyEmail),
+	}
+
+	return profile, nil
+}
+
+func getValue(c *config.Config, key string) string {
+	value, err := c.GetValue(key)
+	if err != nil {
+		return ""
+	}
+	return value.String()
+}
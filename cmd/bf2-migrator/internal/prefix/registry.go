@@ -0,0 +1,57 @@
+package prefix
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// readRegistryValue reads a string value from one of Wine's registry files (system.reg/user.reg), which store keys
+// relative to their hive (e.g. "Software\\Electronic Arts" for HKEY_LOCAL_MACHINE\Software\Electronic Arts)
+func readRegistryValue(path string, key string, name string) (string, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	header := "[" + strings.ToLower(escapeRegistryString(key)) + "]"
+	prefix := strings.ToLower("\"" + escapeRegistryString(name) + "\"=")
+
+	inKey := false
+	scanner := bufio.NewScanner(f)
+	// Wine writes some (binary) values on very long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "[") {
+			// Key lines are followed by a timestamp, e.g. "[Software\\Wine] 1700000000"
+			if i := strings.LastIndex(line, "]"); i != -1 {
+				line = line[:i+1]
+			}
+			inKey = strings.ToLower(line) == header
+			continue
+		}
+
+		if !inKey || !strings.HasPrefix(strings.ToLower(line), prefix) {
+			continue
+		}
+
+		value := line[len(prefix):]
+		// Only plain string values are supported (no hex(2)/dword etc.)
+		if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+			return "", false, nil
+		}
+		return unescapeRegistryString(value[1 : len(value)-1]), true, nil
+	}
+
+	return "", false, scanner.Err()
+}
+
+func escapeRegistryString(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(s)
+}
+
+func unescapeRegistryString(s string) string {
+	return strings.NewReplacer("\\\\", "\\", "\\\"", "\"").Replace(s)
+}
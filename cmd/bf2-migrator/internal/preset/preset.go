@@ -0,0 +1,127 @@
+// Package preset stores named sets of options (e.g. "Clan server -> BF2Hub"), so repeated operations only require
+// selecting the preset
+package preset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Preset struct {
+	Name string `json:"name"`
+	// Game or server installation folder
+	Dir             string `json:"dir,omitempty"`
+	PatchProvider   string `json:"patchProvider,omitempty"`
+	MigrateProvider string `json:"migrateProvider,omitempty"`
+	PatchGame       bool   `json:"patchGame"`
+	PatchServer     bool   `json:"patchServer"`
+	WriteProtect    bool   `json:"writeProtect,omitempty"`
+}
+
+func (p Preset) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if !p.PatchGame && !p.PatchServer {
+		return fmt.Errorf("at least one of game or server executable must be selected")
+	}
+	return nil
+}
+
+// DefaultPath returns the path of the presets file in the user's config folder
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "bf2-migrator", "presets.json"), nil
+}
+
+// Load reads all presets from path
+func Load(path string) ([]Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// No preset has been saved yet
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var presets []Preset
+	if err = json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("failed to parse presets file: %w", err)
+	}
+
+	return presets, nil
+}
+
+// Find returns the preset with the given name (case-insensitive)
+func Find(presets []Preset, name string) (Preset, bool) {
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Save adds the preset to the presets at path, replacing any preset with the same name
+func Save(path string, preset Preset) error {
+	if err := preset.Validate(); err != nil {
+		return err
+	}
+
+	presets, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, p := range presets {
+		if strings.EqualFold(p.Name, preset.Name) {
+			presets[i] = preset
+			replaced = true
+		}
+	}
+	if !replaced {
+		presets = append(presets, preset)
+	}
+
+	return write(path, presets)
+}
+
+// Delete removes the preset with the given name from the presets at path
+func Delete(path string, name string) error {
+	presets, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]Preset, 0, len(presets))
+	for _, p := range presets {
+		if !strings.EqualFold(p.Name, name) {
+			remaining = append(remaining, p)
+		}
+	}
+
+	return write(path, remaining)
+}
+
+func write(path string, presets []Preset) error {
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
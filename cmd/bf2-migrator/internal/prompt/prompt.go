@@ -0,0 +1,49 @@
+// Package prompt abstracts asking the user for credentials, so flows requiring them work the same in every frontend
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CredentialPrompter asks the user for credentials, returning false if the user declined to provide them
+type CredentialPrompter interface {
+	PromptPassword(title, message string) (string, bool)
+}
+
+// Terminal prompts for credentials on a terminal, unless they are provided via environment variables
+type Terminal struct {
+	in  *bufio.Reader
+	out io.Writer
+	// Name of the environment variable to take the password from instead of prompting
+	passwordEnv string
+}
+
+func NewTerminal(in io.Reader, out io.Writer, passwordEnv string) *Terminal {
+	return &Terminal{
+		in:          bufio.NewReader(in),
+		out:         out,
+		passwordEnv: passwordEnv,
+	}
+}
+
+func (t *Terminal) PromptPassword(title, message string) (string, bool) {
+	if password, ok := os.LookupEnv(t.passwordEnv); ok && password != "" {
+		return password, true
+	}
+
+	if _, err := fmt.Fprintf(t.out, "%s\n%s: ", title, message); err != nil {
+		return "", false
+	}
+
+	line, err := t.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	return password, password != ""
+}
@@ -0,0 +1,10 @@
+// Package readonly supports the read-only mode, under which actions only run their detection/diagnostic parts and
+// refuse to modify files, the registry or provider accounts
+package readonly
+
+import (
+	"errors"
+)
+
+// ErrReadOnly is returned (wrapped) by actions refusing to make a change in read-only mode
+var ErrReadOnly = errors.New("refusing to make changes in read-only mode")
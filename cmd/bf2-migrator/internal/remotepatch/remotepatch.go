@@ -0,0 +1,286 @@
+// Package remotepatch patches the server executables of remote (e.g. rented) game servers by downloading them via
+// SCP, patching them locally and uploading them again. The system's OpenSSH client is used for transfers, so
+// authentication (keys, agents, password prompts, known hosts) works just like it does for ssh/scp. Prompts are shown
+// on the terminal, so it is meant for use from the command line (see SCP.BatchMode for non-interactive use).
+package remotepatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/multierr"
+
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/patchable"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/readonly"
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	defaultPort = 22
+)
+
+// Target is a server installation folder on a remote host
+type Target struct {
+	User string
+	Host string
+	Port int
+	Dir  string
+}
+
+// ParseTarget parses a target in scp's [user@]host[:port]:dir notation, e.g. "admin@203.0.113.1:2222:/home/bf2/server".
+// IPv6 addresses need to be enclosed in brackets, e.g. "admin@[2001:db8::1]:2222:/home/bf2/server".
+func ParseTarget(s string) (Target, error) {
+	t := Target{Port: defaultPort}
+	if user, rest, found := strings.Cut(s, "@"); found {
+		t.User = user
+		s = rest
+	}
+
+	var host, rest string
+	var found bool
+	if strings.HasPrefix(s, "[") {
+		var after string
+		host, after, found = strings.Cut(s[1:], "]")
+		if !found || !strings.HasPrefix(after, ":") {
+			return Target{}, fmt.Errorf("invalid target, expected [user@][ipv6][:port]:dir: %s", s)
+		}
+		rest = after[1:]
+	} else {
+		host, rest, found = strings.Cut(s, ":")
+	}
+	if !found || host == "" {
+		return Target{}, fmt.Errorf("invalid target, expected [user@]host[:port]:dir: %s", s)
+	}
+	t.Host = host
+
+	if port, dir, found := strings.Cut(rest, ":"); found {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return Target{}, fmt.Errorf("invalid target port: %s", port)
+		}
+		t.Port = p
+		rest = dir
+	}
+
+	if rest == "" {
+		return Target{}, fmt.Errorf("invalid target, missing server folder: %s", s)
+	}
+	t.Dir = rest
+
+	return t, nil
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s:%d:%s", t.address(), t.Port, t.Dir)
+}
+
+func (t Target) address() string {
+	host := t.Host
+	if strings.Contains(host, ":") {
+		// IPv6 addresses would otherwise be mistaken for the host and path separator
+		host = "[" + host + "]"
+	}
+	if t.User == "" {
+		return host
+	}
+	return t.User + "@" + host
+}
+
+// file returns the path of a file (name) in the server folder
+func (t Target) file(name string) string {
+	return path.Join(t.Dir, filepath.ToSlash(name))
+}
+
+// remotePath returns the scp argument for a file (name) in the server folder, escaped for the remote shell
+func (t Target) remotePath(name string) string {
+	return t.address() + ":" + escapeRemotePath(t.file(name))
+}
+
+// escapeRemotePath escapes any characters in p the remote shell would interpret (e.g. spaces), keeping a leading "~"
+// so it still refers to the home folder
+func escapeRemotePath(p string) string {
+	var sb strings.Builder
+	for i, r := range p {
+		if !isSafePathRune(r) && (r != '~' || i != 0) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isSafePathRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+,:@%=", r)
+}
+
+// Transport copies files from/to a remote target
+type Transport interface {
+	Download(t Target, name string, local string) error
+	Upload(t Target, local string, name string) error
+}
+
+// SCP transfers files using the system's scp binary. Unless BatchMode is set, scp is attached to the terminal, so ssh
+// can prompt for passwords, key passphrases and host key confirmations.
+type SCP struct {
+	// Path to the scp binary, defaults to scp in PATH
+	Binary string
+	// Identity (private key) file to use instead of the default ones/ssh-agent
+	IdentityFile string
+	// Fail instead of prompting (e.g. when not running in a terminal), so only keys/ssh-agent and known hosts are used
+	BatchMode bool
+	// Time after which downloads are aborted (or not started), e.g. to keep unreachable hosts from stalling a batch.
+	// Uploads are always completed, since aborting them would leave the remote executable partially written.
+	Deadline time.Time
+}
+
+func (s SCP) Download(t Target, name string, local string) error {
+	ctx := context.Background()
+	if !s.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, s.Deadline)
+		defer cancel()
+	}
+	return s.run(ctx, t, name, t.remotePath(name), local)
+}
+
+func (s SCP) Upload(t Target, local string, name string) error {
+	return s.run(context.Background(), t, name, local, t.remotePath(name))
+}
+
+func (s SCP) run(ctx context.Context, t Target, name string, src, dst string) error {
+	binary := s.Binary
+	if binary == "" {
+		binary = "scp"
+	}
+
+	args := []string{"-q", "-P", strconv.Itoa(t.Port)}
+	if s.IdentityFile != "" {
+		args = append(args, "-i", s.IdentityFile)
+	}
+	if s.BatchMode {
+		args = append(args, "-o", "BatchMode=yes")
+	}
+	if file := t.file(name); escapeRemotePath(file) != file {
+		// Escaped paths are only unescaped by the remote shell, which the SFTP protocol (used by default since OpenSSH
+		// 9.0) does not use, so use the original protocol
+		args = append(args, "-O")
+	}
+	args = append(args, src, dst)
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = os.Stderr
+	if !s.BatchMode {
+		// Attach to the terminal, so ssh can prompt for passwords/passphrases and host key confirmations
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+	}
+	if err := cmd.Run(); err != nil {
+		// Report the deadline rather than scp being killed
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to copy %s to %s: %w", src, dst, ctx.Err())
+		}
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+
+	return nil
+}
+
+// ReadOnly wraps the transport, refusing to upload files (while still allowing to download them)
+func ReadOnly(tr Transport) Transport {
+	return readOnlyTransport{Transport: tr}
+}
+
+type readOnlyTransport struct {
+	Transport
+}
+
+func (tr readOnlyTransport) Upload(t Target, _ string, name string) error {
+	return fmt.Errorf("would upload %s to %s: %w", name, t, readonly.ErrReadOnly)
+}
+
+// Patch downloads the executables of the given (server) patchables from the target, patches them to use the new
+// provider and uploads the patched executables. Executables which cannot be downloaded are skipped, since servers
+// do not necessarily contain every executable. Returns the names of the patched executables.
+func Patch(tr Transport, t Target, patchables []patch.Patchable, new patch.Provider) ([]string, error) {
+	tmp, err := os.MkdirTemp("", "bf2-migrator-remote-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary folder: %w", err)
+	}
+	defer func() {
+		if err2 := os.RemoveAll(tmp); err2 != nil {
+			log.Error().
+				Err(err2).
+				Str("path", tmp).
+				Msg("Failed to remove temporary folder")
+		}
+	}()
+
+	var downloaded []patch.Patchable
+	var names []string
+	var downloadErr error
+	seen := map[string]bool{}
+	for _, p := range patchables {
+		name := p.GetFileName()
+		key := strings.ToLower(name)
+		if seen[key] {
+			// Build variants share the executable, so only the first one needs to download it
+			if containsName(names, name) {
+				downloaded = append(downloaded, p)
+			}
+			continue
+		}
+		seen[key] = true
+
+		local := filepath.Join(tmp, filepath.FromSlash(name))
+		if err2 := os.MkdirAll(filepath.Dir(local), 0o755); err2 != nil {
+			return nil, fmt.Errorf("failed to create temporary folder: %w", err2)
+		}
+
+		if err2 := tr.Download(t, name, local); err2 != nil {
+			log.Debug().
+				Err(err2).
+				Str("target", t.String()).
+				Str("executable", name).
+				Msg("Failed to download executable, skipping")
+			downloadErr = multierr.Append(downloadErr, err2)
+			continue
+		}
+
+		downloaded = append(downloaded, p)
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("failed to download any server executable from %s: %w", t, downloadErr)
+	}
+
+	if err = patchable.PatchAll(downloaded, tmp, new); err != nil {
+		return nil, err
+	}
+
+	// Only upload after all executables were patched, so servers are never left half-patched
+	for _, name := range names {
+		if err = tr.Upload(t, filepath.Join(tmp, filepath.FromSlash(name)), name); err != nil {
+			return nil, fmt.Errorf("failed to upload patched executable: %w", err)
+		}
+	}
+
+	return names, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// Package settings stores the user's preferences, so they are kept across launches
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultTimeout is the network timeout (in seconds) used if none is set
+	DefaultTimeout = 10
+	// ProxyDirect is the proxy setting to connect directly, even if a system/environment proxy is configured
+	ProxyDirect = "direct"
+)
+
+type Settings struct {
+	// Name of the provider to select by default for patching and migrating (empty to use the built-in default)
+	Provider string `json:"provider,omitempty"`
+	// Installation folder last opened, to open again on the next launch
+	LastDir string `json:"lastDir,omitempty"`
+	// Network timeout in seconds (0 to use DefaultTimeout)
+	Timeout int `json:"timeout,omitempty"`
+	// Time limits in seconds for connecting, each read and each write (0 to use the network timeout)
+	ConnectTimeout int `json:"connectTimeout,omitempty"`
+	ReadTimeout    int `json:"readTimeout,omitempty"`
+	WriteTimeout   int `json:"writeTimeout,omitempty"`
+	// HTTP or SOCKS5 proxy URL (e.g. socks5://127.0.0.1:1080) to use for all network requests, ProxyDirect to not use
+	// any proxy (empty to use the system/environment proxy)
+	Proxy string `json:"proxy,omitempty"`
+	// DNS server ("host" or "host:port") or DNS-over-HTTPS endpoint (https://...) to resolve provider host names with
+	// (empty to use the system resolver)
+	Resolver string `json:"resolver,omitempty"`
+	// Log every packet sent to/received from providers (with passwords redacted) to the log file
+	DebugPackets bool `json:"debugPackets,omitempty"`
+	// Number of changes to keep backups for (to undo them), 0 to keep all changes made during a session
+	UndoLimit int `json:"undoLimit,omitempty"`
+	// Language of translated messages (empty to use the Windows display language)
+	Language string `json:"language,omitempty"`
+}
+
+// GetTimeout returns the network timeout in seconds
+func (s Settings) GetTimeout() int {
+	if s.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return s.Timeout
+}
+
+// GetConnectTimeout returns the time limit for connecting in seconds
+func (s Settings) GetConnectTimeout() int {
+	return timeoutOrDefault(s.ConnectTimeout, s.GetTimeout())
+}
+
+// GetReadTimeout returns the time limit for each read in seconds
+func (s Settings) GetReadTimeout() int {
+	return timeoutOrDefault(s.ReadTimeout, s.GetTimeout())
+}
+
+// GetWriteTimeout returns the time limit for each write in seconds
+func (s Settings) GetWriteTimeout() int {
+	return timeoutOrDefault(s.WriteTimeout, s.GetTimeout())
+}
+
+func timeoutOrDefault(timeout, fallback int) int {
+	if timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+func (s Settings) Validate() error {
+	if s.Timeout < 0 || s.ConnectTimeout < 0 || s.ReadTimeout < 0 || s.WriteTimeout < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+	if s.UndoLimit < 0 {
+		return fmt.Errorf("undo limit must not be negative")
+	}
+	if _, err := ParseProxy(s.Proxy); err != nil {
+		return err
+	}
+	if err := ValidateResolver(s.Resolver); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseProxy parses a proxy URL as set in the settings or via flags. Returns nil for an empty value and ProxyDirect,
+// which are valid as well.
+func ParseProxy(value string) (*url.URL, error) {
+	if value == "" || value == ProxyDirect {
+		return nil, nil
+	}
+
+	proxy, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch proxy.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy URL must start with http://, socks5:// or socks5h://")
+	}
+	if proxy.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL must contain a host")
+	}
+
+	return proxy, nil
+}
+
+// DefaultPath returns the path of the settings file in the user's config folder
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user config directory: %w", err)
+	}
+
+	return filepath.Join(dir, "bf2-migrator", "settings.json"), nil
+}
+
+// Load reads the settings from path
+func Load(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Settings have not been saved yet
+		if errors.Is(err, os.ErrNotExist) {
+			return Settings{}, nil
+		}
+		return Settings{}, err
+	}
+
+	var s Settings
+	if err = json.Unmarshal(data, &s); err != nil {
+		return Settings{}, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save writes the settings to path
+func Save(path string, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// ValidateResolver checks a resolver as set in the settings or via flags. DNS servers must be given by IP address,
+// since resolving their host name would require a DNS server in the first place.
+func ValidateResolver(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(value, "https://") {
+		endpoint, err := url.Parse(value)
+		if err != nil || endpoint.Hostname() == "" {
+			return fmt.Errorf("invalid DNS-over-HTTPS URL: %q", value)
+		}
+		return nil
+	}
+
+	host := value
+	if h, port, err := net.SplitHostPort(value); err == nil {
+		if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("invalid DNS server port: %q", port)
+		}
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("DNS server must be an IP address (optionally with port) or https:// URL: %q", value)
+	}
+	return nil
+}
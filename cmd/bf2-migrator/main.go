@@ -3,35 +3,281 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
-	filerepo "github.com/cetteup/filerepo/pkg"
-	"github.com/cetteup/joinme.click-launcher/pkg/registry_repository"
-	"github.com/cetteup/joinme.click-launcher/pkg/software_finder"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
-	"github.com/cetteup/conman/pkg/handler"
-
-	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/gui"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/hosts"
+	"github.com/cetteup/bf2-migrator/cmd/bf2-migrator/internal/settings"
 	"github.com/cetteup/bf2-migrator/pkg/gamespy"
+	"github.com/cetteup/bf2-migrator/pkg/sysproxy"
+)
+
+const (
+	// Number of times requests to providers are retried after transient network errors (e.g. connection resets)
+	clientRetries = 2
 )
 
+type options struct {
+	patchGame   bool
+	patchServer bool
+	// Provider to patch to without opening the GUI
+	patch   string
+	detect  bool
+	dir     string
+	catalog string
+	// Wine/Proton prefix to read profiles from
+	prefix   string
+	profiles bool
+	// Provider to migrate the profile to without opening the GUI
+	migrate string
+	profile string
+	// Remote servers to patch via SCP instead of a local folder
+	remote targets
+	sshKey string
+	// Provider to redirect to via the hosts file instead of patching
+	redirect   string
+	unredirect bool
+	cleanHosts bool
+	hosts      string
+	// Named preset to patch with
+	preset string
+	// Only run detection/diagnostics, refusing to make any changes
+	readOnly bool
+	// How to recover patches interrupted while writing a binary
+	recover string
+	// Normalize executables patched by other patchers before patching
+	adopt bool
+	// Overall time limit for patching multiple remote servers
+	deadline time.Duration
+	// Network timeouts overriding the settings (0 to use the settings)
+	timeout        time.Duration
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	// Proxy URL overriding the settings (empty to use the settings)
+	proxy string
+	// DNS server or DNS-over-HTTPS endpoint overriding the settings (empty to use the settings)
+	resolver string
+	// Log every packet sent to/received from providers
+	debugPackets bool
+}
+
+// targets collects the values of a repeatable flag
+type targets []string
+
+func (t *targets) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *targets) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
 func init() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 }
 
 func main() {
-	fileRepository := filerepo.New()
-	registryRepository := registry_repository.New()
-	h := handler.New(fileRepository)
+	var o options
+	flag.BoolVar(&o.patchGame, "game", true, "select the game executable (BF2.exe) for patching")
+	flag.BoolVar(&o.patchServer, "server", true, "select the dedicated server executable (bf2_w32ded.exe) for patching")
+	flag.StringVar(&o.patch, "patch", "", "patch the executables in -dir to use the given provider (e.g. OpenSpy) instead of opening the GUI")
+	flag.BoolVar(&o.detect, "detect", false, "print the provider the executables in -dir are patched for instead of opening the GUI")
+	flag.StringVar(&o.dir, "dir", "", "game or server installation folder to use with -patch/-detect or to open in the GUI (default: detected from -prefix or Wine/Proton prefixes)")
+	flag.StringVar(&o.catalog, "catalog", "", "path to a local provider catalog to use with -patch/-detect")
+	flag.StringVar(&o.prefix, "prefix", "", "Wine/Proton prefix to read profiles/the install folder from (default: detected from WINEPREFIX, ~/.wine and Steam compatdata)")
+	flag.BoolVar(&o.profiles, "profiles", false, "list the profiles in -prefix instead of opening the GUI")
+	flag.StringVar(&o.migrate, "migrate", "", "migrate -profile in -prefix to the given provider (e.g. OpenSpy) instead of opening the GUI, reading the password from "+passwordEnv+" or stdin")
+	flag.StringVar(&o.profile, "profile", "", "key of the profile to migrate with -migrate (e.g. 0001)")
+	flag.Var(&o.remote, "remote", "patch the server executables on the remote host ([user@]host[:port]:dir, with IPv6 addresses in brackets) via scp instead of -dir, can be repeated")
+	flag.StringVar(&o.sshKey, "ssh-key", "", "SSH private key file to use with -remote (default: ssh's default keys/agent)")
+	flag.StringVar(&o.redirect, "redirect", "", "redirect the GameSpy hostnames to the given provider (e.g. OpenSpy) via the hosts file instead of patching the executables")
+	flag.BoolVar(&o.unredirect, "unredirect", false, "remove the hosts file entries added by -redirect")
+	flag.BoolVar(&o.cleanHosts, "clean-hosts", false, "remove hosts file entries for GameSpy/provider hostnames left behind by other patchers")
+	flag.StringVar(&o.hosts, "hosts", hosts.DefaultPath(), "hosts file to use with -redirect/-unredirect/-clean-hosts/-detect")
+	flag.StringVar(&o.preset, "preset", "", "patch using the folder, provider and options of the given saved preset instead of opening the GUI (flags take precedence)")
+	flag.BoolVar(&o.readOnly, "read-only", false, "only run the detection/diagnostic parts of any action, refusing to modify files, the registry or provider accounts")
+	flag.StringVar(&o.recover, "recover", "", "resume or roll back (\""+recoverResume+"\" or \""+recoverRollBack+"\") patches which were interrupted while writing an executable")
+	flag.BoolVar(&o.adopt, "adopt", false, "clean up leftovers of the original strings in executables patched by other patchers before patching with -patch")
+	flag.DurationVar(&o.deadline, "deadline", 0, "overall time limit for patching all -remote servers (e.g. 10m), after which the remaining servers are skipped and reported")
+	flag.DurationVar(&o.timeout, "timeout", 0, "network timeout for provider requests (e.g. 30s), overriding the settings")
+	flag.DurationVar(&o.connectTimeout, "connect-timeout", 0, "time limit for connecting to providers (default: -timeout)")
+	flag.DurationVar(&o.readTimeout, "read-timeout", 0, "time limit for each read from providers (default: -timeout)")
+	flag.DurationVar(&o.writeTimeout, "write-timeout", 0, "time limit for each write to providers (default: -timeout)")
+	flag.StringVar(&o.proxy, "proxy", "", "HTTP or SOCKS5 proxy to use for all network requests (e.g. socks5://127.0.0.1:1080), or \""+settings.ProxyDirect+"\" to not use any proxy, overriding the settings (default: system/environment proxy)")
+	flag.StringVar(&o.resolver, "resolver", "", "DNS server (e.g. 1.1.1.1) or DNS-over-HTTPS endpoint (e.g. https://cloudflare-dns.com/dns-query) to resolve provider host names with, overriding the settings (default: system resolver)")
+	flag.BoolVar(&o.debugPackets, "debug-packets", false, "log every packet sent to/received from providers (with passwords redacted) to diagnose provider issues")
+	flag.Parse()
+
+	if _, err := settings.ParseProxy(o.proxy); err != nil {
+		log.Fatal().Err(err).Msg("Invalid -proxy")
+	}
+	if err := settings.ValidateResolver(o.resolver); err != nil {
+		log.Fatal().Err(err).Msg("Invalid -resolver")
+	}
+
+	if err := setUpJournal(); err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to set up patch journal, interrupted patches will not be recoverable")
+	}
+	removeOrphanedTempDirs()
+
+	if o.recover != "" {
+		if err := recoverInterrupted(o.recover); err != nil {
+			log.Fatal().Err(err).Msg("Failed to recover interrupted patches")
+		}
+	}
+
+	if o.preset != "" {
+		if err := applyPreset(&o, o.preset); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load preset")
+		}
+	}
+
+	if o.patch != "" || o.detect || o.profiles || o.migrate != "" || o.redirect != "" || o.unredirect || o.cleanHosts {
+		if err := runCLI(o); err != nil {
+			e := log.Fatal().Err(err)
+			// Raw provider messages rarely tell users what to do, so add the description of the error code
+			var providerErr *gamespy.ProviderError
+			if errors.As(err, &providerErr) {
+				e = e.Str("hint", providerErr.Describe(gamespy.LanguageEnglish))
+			}
+			e.Msg("Failed to run command")
+		}
+		return
+	}
+
+	// Recovering does not require the GUI
+	if o.recover != "" {
+		return
+	}
+
+	runGUI(o)
+}
+
+// loadSettings returns the saved settings, ignoring any errors loading them (the main window reports them)
+func loadSettings() settings.Settings {
+	var s settings.Settings
+	if path, err := settings.DefaultPath(); err == nil {
+		if loaded, err2 := settings.Load(path); err2 == nil {
+			s = loaded
+		}
+	}
+	return s
+}
 
-	f := software_finder.New(registryRepository, fileRepository)
-	c := gamespy.NewClient(10)
-	mw, err := gui.CreateMainWindow(h, f, registryRepository, c)
+// networkProxy returns the proxy to send requests via, using the proxy from the flags or else the settings and
+// falling back to the system/environment proxy
+func networkProxy(o options, s settings.Settings) func(*http.Request) (*url.URL, error) {
+	value := o.proxy
+	if value == "" {
+		value = s.Proxy
+	}
+
+	if value == "" {
+		return sysproxy.FromSystem
+	}
+
+	// Saved settings have been validated already
+	proxy, err := settings.ParseProxy(value)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create main window")
+		log.Error().Err(err).Msg("Failed to parse proxy setting, using system proxy")
+		return sysproxy.FromSystem
+	}
+
+	return func(*http.Request) (*url.URL, error) {
+		return proxy, nil
+	}
+}
+
+// networkResolver returns the resolver for provider host names, using the resolver from the flags or else the settings
+// (nil to use the system resolver). DNS-over-HTTPS requests are sent via proxy.
+func networkResolver(o options, s settings.Settings, proxy func(*http.Request) (*url.URL, error)) gamespy.Resolver {
+	value := o.resolver
+	if value == "" {
+		value = s.Resolver
+	}
+
+	if value == "" {
+		return nil
 	}
 
-	mw.Run()
+	if strings.HasPrefix(value, "https://") {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		return gamespy.NewDoHResolver(value, &http.Client{
+			Timeout:   durationOrSeconds(o.timeout, s.GetTimeout()),
+			Transport: transport,
+		})
+	}
+
+	return gamespy.NewDNSResolver(value)
+}
+
+// clientOptions returns the options of the provider client, using the timeouts, proxy and resolver from the flags or
+// else the settings
+func clientOptions(o options, s settings.Settings) gamespy.ClientOptions {
+	proxy := networkProxy(o, s)
+
+	// The overall timeout flag also overrides the per-operation timeouts from the settings
+	connectTimeout, readTimeout, writeTimeout := s.GetConnectTimeout(), s.GetReadTimeout(), s.GetWriteTimeout()
+	if o.timeout > 0 {
+		connectTimeout, readTimeout, writeTimeout = 0, 0, 0
+	}
+
+	return gamespy.ClientOptions{
+		Timeout:        durationOrSeconds(o.timeout, s.GetTimeout()),
+		ConnectTimeout: durationOrSeconds(o.connectTimeout, connectTimeout),
+		ReadTimeout:    durationOrSeconds(o.readTimeout, readTimeout),
+		WriteTimeout:   durationOrSeconds(o.writeTimeout, writeTimeout),
+		Retries:        clientRetries,
+		Resolver:       networkResolver(o, s, proxy),
+		LogPacket:      packetLogger(o, s),
+		// Connections are tunneled like HTTPS requests
+		Proxy: func(addr string) (*url.URL, error) {
+			return proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+		},
+	}
+}
+
+// packetLogger returns the function logging provider packets at debug level if enabled via flag or the settings.
+// Dropped packets are always logged (as warnings), since they may have been responses which were waited for in vain.
+func packetLogger(o options, s settings.Settings) func(gamespy.Provider, gamespy.PacketDirection, string) {
+	debug := o.debugPackets || s.DebugPackets
+
+	return func(provider gamespy.Provider, direction gamespy.PacketDirection, packet string) {
+		if direction == gamespy.PacketDropped {
+			log.Warn().
+				Str("provider", string(provider)).
+				Str("packet", packet).
+				Msg("Dropped GameSpy packet nobody waited for")
+			return
+		}
+		if !debug {
+			return
+		}
+
+		log.Debug().
+			Str("provider", string(provider)).
+			Str("direction", string(direction)).
+			Str("packet", packet).
+			Msg("GameSpy packet")
+	}
+}
+
+func durationOrSeconds(d time.Duration, seconds int) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return time.Duration(seconds) * time.Second
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	recoverResume   = "resume"
+	recoverRollBack = "rollback"
+
+	// Temporary folders older than this are no longer in use by another (running) instance
+	orphanedTempDirAge = time.Hour
+)
+
+// Prefixes of the temporary folders used for dry runs and patching remote servers
+var tempDirPrefixes = []string{"bf2-migrator-dry-run-", "bf2-migrator-remote-"}
+
+// setUpJournal enables journaling of patches in the user config folder
+func setUpJournal() error {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to determine user config directory: %w", err)
+	}
+
+	patch.JournalDir = filepath.Join(dir, "bf2-migrator", "journal")
+	return nil
+}
+
+// removeOrphanedTempDirs removes temporary folders left behind by interrupted runs
+func removeOrphanedTempDirs() {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !hasAnyPrefix(entry.Name(), tempDirPrefixes) {
+			continue
+		}
+
+		info, err2 := entry.Info()
+		if err2 != nil || time.Since(info.ModTime()) < orphanedTempDirAge {
+			continue
+		}
+
+		path := filepath.Join(os.TempDir(), entry.Name())
+		if err2 = os.RemoveAll(path); err2 != nil {
+			log.Error().
+				Err(err2).
+				Str("path", path).
+				Msg("Failed to remove orphaned temporary folder")
+		}
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// recoverInterrupted resumes or rolls back (depending on mode) any patch interrupted while writing a binary
+func recoverInterrupted(mode string) error {
+	if mode != recoverResume && mode != recoverRollBack {
+		return fmt.Errorf("unknown recovery mode %q (expected %s or %s)", mode, recoverResume, recoverRollBack)
+	}
+
+	interrupted, err := patch.RecoverJournal()
+	if err != nil {
+		return fmt.Errorf("failed to read patch journal: %w", err)
+	}
+
+	for _, p := range interrupted {
+		if mode == recoverResume {
+			err = p.Resume()
+		} else {
+			err = p.RollBack()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s interrupted patch of %s: %w", mode, p.Path, err)
+		}
+
+		log.Info().
+			Str("path", p.Path).
+			Str("mode", mode).
+			Msg("Recovered interrupted patch")
+	}
+
+	return nil
+}
+
+// ensureNotInterrupted returns an error if any binary was left partially written by an interrupted patch
+func ensureNotInterrupted() error {
+	interrupted, err := patch.RecoverJournal()
+	if err != nil {
+		return fmt.Errorf("failed to read patch journal: %w", err)
+	}
+	if len(interrupted) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(interrupted))
+	for _, p := range interrupted {
+		paths = append(paths, p.Path)
+	}
+	return fmt.Errorf("%s left partially written by an interrupted patch, use -recover %s or -recover %s first", strings.Join(paths, ", "), recoverResume, recoverRollBack)
+}
@@ -1,15 +1,21 @@
 package gamespy
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dogclan/dumbspy/pkg/gamespy"
 	"go.uber.org/multierr"
 )
 
+// Provider is the base hostname of a provider, with the servers of each service being subdomains (e.g. gpcm.openspy.net)
 type Provider string
 
 const (
@@ -17,7 +23,6 @@ const (
 	ProviderPlayBF2 Provider = "playbf2.ru"
 	ProviderOpenSpy Provider = "openspy.net"
 
-	network     = "tcp4"
 	serviceGPCM = "gpcm"
 	serviceGPSP = "gpsp"
 	portGPCM    = "29900"
@@ -26,27 +31,128 @@ const (
 	namespaceID = "12"
 	gameName    = "battlefield2"
 	productID   = "10493"
+	partnerID   = "0"
+	sdkRevision = "3"
+
+	// Length of the challenge the client sends when logging in
+	clientChallengeLength = 32
+	// Number of providers looked up concurrently by GetNicksAll
+	maxLookupWorkers = 4
 )
 
+// DefaultRetryDelay is the delay before the first retry if ClientOptions.RetryDelay is not set
+const DefaultRetryDelay = 500 * time.Millisecond
+
+// ClientOptions configure a Client
+type ClientOptions struct {
+	// Time limit for connecting and for each read/write (a context's deadline still applies if it is earlier)
+	Timeout time.Duration
+	// Time limits for connecting, each read and each write, overriding Timeout (0 to use Timeout)
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	// Resolves provider (and proxy) host names instead of the system resolver (nil to use the system resolver), see
+	// NewDNSResolver and NewDoHResolver
+	Resolver Resolver
+	// Called with every packet sent to/received from a provider, with passwords (and values derived from them)
+	// redacted, e.g. to diagnose incompatibilities with a provider (nil to not log packets)
+	LogPacket func(provider Provider, direction PacketDirection, packet string)
+	// Returns the HTTP (CONNECT) or SOCKS5 proxy to connect to an address ("host:port") through, with a nil URL meaning
+	// no proxy should be used (nil to always connect directly), see ProxyFromEnvironment
+	Proxy func(addr string) (*url.URL, error)
+	// Number of times to retry requests failing due to transient network errors (e.g. connection resets or timeouts),
+	// 0 to not retry. Creating users is only retried if the request has not been sent yet.
+	Retries int
+	// Delay before the first retry, doubled for every further retry (0 to use DefaultRetryDelay)
+	RetryDelay time.Duration
+	// Minimum time between creating users on the same provider, so creating many users in a row does not trigger the
+	// provider's anti-abuse protections (0 to use DefaultCreateUserInterval, negative to not limit)
+	CreateUserInterval time.Duration
+	// Time to wait before creating users on a provider again after it throttled creating a user (0 to use
+	// DefaultThrottleCooldown)
+	ThrottleCooldown time.Duration
+}
+
+// Client talks to a provider's presence (gpcm) and search (gpsp) servers. Methods without a context argument use
+// context.Background. Presence connections are reused by consecutive operations (e.g. creating several users) for a
+// while, use Close to close them right away.
 type Client struct {
-	timeout time.Duration
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	retries        int
+	retryDelay     time.Duration
+	proxy          func(addr string) (*url.URL, error)
+	resolver       Resolver
+	logPacket      func(provider Provider, direction PacketDirection, packet string)
+
+	// Spaces out creating users on each provider
+	creates          *rateLimiter
+	throttleCooldown time.Duration
+
+	// Idle presence sessions kept for reuse by the next operation
+	sessions   map[Provider]*idleSession
+	sessionsMu sync.Mutex
 }
 
+// NewClient returns a client using a timeout of the given number of seconds
 func NewClient(timeout int) *Client {
+	return NewClientWithOptions(ClientOptions{Timeout: time.Duration(timeout) * time.Second})
+}
+
+// NewClientWithOptions returns a client configured using opts
+func NewClientWithOptions(opts ClientOptions) *Client {
+	retryDelay := opts.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = DefaultRetryDelay
+	}
+
+	createUserInterval := opts.CreateUserInterval
+	if createUserInterval == 0 {
+		createUserInterval = DefaultCreateUserInterval
+	}
+
+	throttleCooldown := opts.ThrottleCooldown
+	if throttleCooldown <= 0 {
+		throttleCooldown = DefaultThrottleCooldown
+	}
+
 	return &Client{
-		timeout: time.Duration(timeout) * time.Second,
+		connectTimeout:   timeoutOrDefault(opts.ConnectTimeout, opts.Timeout),
+		readTimeout:      timeoutOrDefault(opts.ReadTimeout, opts.Timeout),
+		writeTimeout:     timeoutOrDefault(opts.WriteTimeout, opts.Timeout),
+		retries:          opts.Retries,
+		retryDelay:       retryDelay,
+		proxy:            opts.Proxy,
+		resolver:         opts.Resolver,
+		logPacket:        opts.LogPacket,
+		creates:          newRateLimiter(createUserInterval),
+		throttleCooldown: throttleCooldown,
+		sessions:         make(map[Provider]*idleSession),
 	}
 }
 
-func (c *Client) GetNicks(provider Provider, email, password string) ([]NickDTO, error) {
-	conn, err := connect(getHostname(provider, serviceGPSP), portGPSP)
-	if err != nil {
-		return nil, err
+func timeoutOrDefault(timeout, fallback time.Duration) time.Duration {
+	if timeout <= 0 {
+		return fallback
 	}
-	defer func() {
-		err = multierr.Append(err, disconnect(conn))
-	}()
+	return timeout
+}
+
+// GetNicks returns the nicks of the account with the given email address and password
+func (c *Client) GetNicks(provider Provider, email, password string) ([]NickDTO, error) {
+	return c.GetNicksContext(context.Background(), provider, email, password)
+}
+
+func (c *Client) GetNicksContext(ctx context.Context, provider Provider, email, password string) (nicks []NickDTO, err error) {
+	err = c.retry(ctx, func() error {
+		nicks, err = c.getNicks(ctx, provider, email, password)
+		return err
+	})
+	return nicks, err
+}
 
+func (c *Client) getNicks(ctx context.Context, provider Provider, email, password string) ([]NickDTO, error) {
 	req := new(gamespy.Packet)
 	req.Add("nicks", "")
 	req.Add("email", email)
@@ -55,108 +161,483 @@ func (c *Client) GetNicks(provider Provider, email, password string) ([]NickDTO,
 	req.Add("namespaceid", namespaceID)
 	req.Add("gamename", gameName)
 
-	if err = write(conn, c.timeout, req); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	res, err := c.searchRequest(ctx, provider, req)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := read(conn, c.timeout)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	return decodeNicksResponse(res)
+}
+
+// GetNicksAll looks up the nicks of the account with the given email address and password on all providers
+// concurrently, returning the result for each provider
+func (c *Client) GetNicksAll(providers []Provider, email, password string) map[Provider]NicksResult {
+	return c.GetNicksAllContext(context.Background(), providers, email, password)
+}
+
+func (c *Client) GetNicksAllContext(ctx context.Context, providers []Provider, email, password string) map[Provider]NicksResult {
+	jobs := make(chan Provider, len(providers))
+	for _, provider := range providers {
+		jobs <- provider
+	}
+	close(jobs)
+
+	workers := maxLookupWorkers
+	if len(providers) < workers {
+		workers = len(providers)
+	}
+
+	results := make(map[Provider]NicksResult, len(providers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for provider := range jobs {
+				nicks, err := c.GetNicksContext(ctx, provider, email, password)
+				mu.Lock()
+				results[provider] = NicksResult{Nicks: nicks, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// CreateUser creates an account with the given email address and password, using nick as both nick and uniquenick.
+// Users are created no more often than ClientOptions.CreateUserInterval per provider. If the provider throttles
+// creating the user, CreateUser waits for ClientOptions.ThrottleCooldown and tries again.
+func (c *Client) CreateUser(provider Provider, email, password, nick string) error {
+	return c.CreateUserContext(context.Background(), provider, email, password, nick)
+}
+
+func (c *Client) CreateUserContext(ctx context.Context, provider Provider, email, password, nick string) error {
+	_, err := c.NewUserContext(ctx, provider, email, password, nick)
+	return err
+}
+
+// NewUser creates the user like CreateUser, returning the IDs of the created account and profile (if the provider
+// returns them)
+func (c *Client) NewUser(provider Provider, email, password, nick string) (NewUserDTO, error) {
+	return c.NewUserContext(context.Background(), provider, email, password, nick)
+}
+
+func (c *Client) NewUserContext(ctx context.Context, provider Provider, email, password, nick string) (NewUserDTO, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.creates.wait(ctx, provider); err != nil {
+			return NewUserDTO{}, err
+		}
+
+		var user NewUserDTO
+		err := c.retry(ctx, func() error {
+			var err2 error
+			user, err2 = c.createUser(ctx, provider, email, password, nick)
+			return err2
+		})
+
+		var providerErr *ProviderError
+		if attempt >= maxThrottledRetries || !errors.As(err, &providerErr) || !providerErr.Throttled() {
+			return user, err
+		}
+
+		// Keep any other users from being created on the provider until the cooldown is over, too
+		c.creates.cooldown(provider, c.throttleCooldown)
 	}
+}
 
-	if errmsg, exists := res.Lookup("errmsg"); exists {
-		return nil, fmt.Errorf("%s (code: %s)", errmsg, res.Get("err"))
+func (c *Client) createUser(ctx context.Context, provider Provider, email, password, nick string) (NewUserDTO, error) {
+	s, err := c.takeSession(ctx, provider)
+	if err != nil {
+		return NewUserDTO{}, err
 	}
 
-	var nicks []NickDTO
-	current := NickDTO{}
-	keys := make(map[string]struct{})
-	res.Do(func(element gamespy.KeyValuePair) {
-		// Start building a new result when we reach a key we saw before
-		_, seen := keys[element.Key]
-		if seen {
-			nicks = append(nicks, current)
-			current = NickDTO{}
-			keys = make(map[string]struct{}, len(keys))
+	user, sent, err := s.createUser(ctx, email, password, nick)
+	if err != nil && !isProviderError(err) {
+		_ = s.Close()
+		// The user may have been created even if sending the request or reading the response fails, so never retry
+		// once the request has been sent
+		if sent {
+			return NewUserDTO{}, &permanentError{err: err}
 		}
+		return NewUserDTO{}, err
+	}
 
-		switch element.Key {
-		case "nick":
-			current.Nick = element.Value
-		case "uniquenick":
-			current.UniqueNick = element.Value
-		default:
-			// Skip irrelevant keys
-			return
+	// Sessions are still usable after the provider rejected the request
+	c.releaseSession(s)
+	return user, err
+}
+
+// Login performs a full presence login (as the game does when logging in) using the uniquenick and password, verifying
+// the proof sent by the provider
+func (c *Client) Login(provider Provider, uniqueNick, password string) (LoginDTO, error) {
+	return c.LoginContext(context.Background(), provider, uniqueNick, password)
+}
+
+func (c *Client) LoginContext(ctx context.Context, provider Provider, uniqueNick, password string) (login LoginDTO, err error) {
+	err = c.retry(ctx, func() error {
+		login, err = c.login(ctx, provider, uniqueNick, password)
+		return err
+	})
+	return login, err
+}
+
+func (c *Client) login(ctx context.Context, provider Provider, uniqueNick, password string) (LoginDTO, error) {
+	s, err := c.takeSession(ctx, provider)
+	if err != nil {
+		return LoginDTO{}, err
+	}
+	// Logged in sessions cannot be used for anything else (and failed logins may leave sessions in any state)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	return s.LoginContext(ctx, uniqueNick, password)
+}
+
+// ChangePassword changes the password of the account with the given email address from oldPassword to newPassword,
+// logging in using one of the account's nicks. The change is verified by looking up the account's nicks using the new
+// password.
+func (c *Client) ChangePassword(provider Provider, email, oldPassword, newPassword string) error {
+	return c.ChangePasswordContext(context.Background(), provider, email, oldPassword, newPassword)
+}
+
+func (c *Client) ChangePasswordContext(ctx context.Context, provider Provider, email, oldPassword, newPassword string) error {
+	return c.updateAccount(ctx, provider, email, oldPassword, func(s *Session) error {
+		return s.ChangePasswordContext(ctx, newPassword)
+	}, func() error {
+		if _, err := c.GetNicksContext(ctx, provider, email, newPassword); err != nil {
+			return fmt.Errorf("failed to verify new password: %w", err)
 		}
+		return nil
+	})
+}
+
+// ChangeEmail changes the email address of the account from email to newEmail, logging in using one of the account's
+// nicks. Not all providers allow changing the email address. The change is verified by looking up the account's nicks
+// using the new email address.
+func (c *Client) ChangeEmail(provider Provider, email, password, newEmail string) error {
+	return c.ChangeEmailContext(context.Background(), provider, email, password, newEmail)
+}
 
-		keys[element.Key] = struct{}{}
+func (c *Client) ChangeEmailContext(ctx context.Context, provider Provider, email, password, newEmail string) error {
+	return c.updateAccount(ctx, provider, email, password, func(s *Session) error {
+		return s.ChangeEmailContext(ctx, newEmail)
+	}, func() error {
+		if _, err := c.GetNicksContext(ctx, provider, newEmail, password); err != nil {
+			return fmt.Errorf("failed to verify new email address: %w", err)
+		}
+		return nil
 	})
+}
 
-	// Add current result if we found (some) keys, but never found another nick
-	// (we only "flush" current to nicks on the n+1st result)
-	if len(keys) != 0 {
-		nicks = append(nicks, current)
+// updateAccount logs in to the account using one of its nicks, runs update and then verify
+func (c *Client) updateAccount(ctx context.Context, provider Provider, email, password string, update func(s *Session) error, verify func() error) error {
+	nicks, err := c.GetNicksContext(ctx, provider, email, password)
+	if err != nil {
+		return err
+	}
+	if len(nicks) == 0 {
+		return fmt.Errorf("account has no nicks to log in with")
+	}
+
+	// Only attempt the update once, since retrying after it succeeded would fail due to the credentials having changed
+	if err = c.runLoggedIn(ctx, provider, nicks[0].Nick, email, password, update); err != nil {
+		return err
 	}
 
-	return nicks, nil
+	return verify()
 }
 
-func (c *Client) CreateUser(provider Provider, email, password, nick string) (err error) {
-	conn, err := connect(getHostname(provider, serviceGPCM), portGPCM)
+// runLoggedIn runs do using a session logged in to the account by its nick and email address
+func (c *Client) runLoggedIn(ctx context.Context, provider Provider, nick, email, password string, do func(s *Session) error) error {
+	s, err := c.takeSession(ctx, provider)
 	if err != nil {
 		return err
 	}
+	// Logged in sessions cannot be used for anything else
 	defer func() {
-		err = multierr.Append(err, disconnect(conn))
+		_ = s.Close()
 	}()
 
-	// Read login challenge prompt first, as it is sent immediately upon connecting
-	// TODO Login later to verify?
-	_, err = read(conn, c.timeout)
+	if _, err = s.login(ctx, "user", nick+"@"+email, password); err != nil {
+		return err
+	}
+
+	return do(s)
+}
+
+// UniqueNickExists checks whether the uniquenick is registered on the provider, without requiring the credentials
+// of the account it belongs to
+func (c *Client) UniqueNickExists(provider Provider, uniqueNick string) (bool, error) {
+	return c.UniqueNickExistsContext(context.Background(), provider, uniqueNick)
+}
+
+func (c *Client) UniqueNickExistsContext(ctx context.Context, provider Provider, uniqueNick string) (exists bool, err error) {
+	err = c.retry(ctx, func() error {
+		exists, err = c.uniqueNickExists(ctx, provider, uniqueNick)
+		return err
+	})
+	return exists, err
+}
+
+func (c *Client) uniqueNickExists(ctx context.Context, provider Provider, uniqueNick string) (bool, error) {
+	profiles, err := c.search(ctx, provider, "uniquenick", uniqueNick)
 	if err != nil {
-		return fmt.Errorf("failed to read login challenge prompt: %w", err)
+		return false, err
 	}
 
-	signup := new(gamespy.Packet)
-	signup.Add("newuser", "")
-	signup.Add("email", email)
-	signup.Add("nick", nick)
-	signup.Add("passwordenc", gamespy.EncodePassword(password))
-	signup.Add("productid", productID)
-	signup.Add("gamename", gameName)
-	signup.Add("namespaceid", namespaceID)
-	signup.Add("uniquenick", nick)
-	signup.Add("id", "1")
+	// Search results may contain similar nicks as well, so look for an exact (case-insensitive) match
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.UniqueNick, uniqueNick) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SearchProfiles searches the provider for profiles with the nick, without requiring the credentials of the accounts
+// they belong to. Results may include profiles with similar nicks, so callers looking for a specific nick need to
+// compare the nicks themselves.
+func (c *Client) SearchProfiles(provider Provider, nick string) ([]ProfileDTO, error) {
+	return c.SearchProfilesContext(context.Background(), provider, nick)
+}
+
+func (c *Client) SearchProfilesContext(ctx context.Context, provider Provider, nick string) (profiles []ProfileDTO, err error) {
+	err = c.retry(ctx, func() error {
+		profiles, err = c.search(ctx, provider, "nick", nick)
+		return err
+	})
+	return profiles, err
+}
+
+// search sends a search request for profiles whose key ("nick" or "uniquenick") matches value
+func (c *Client) search(ctx context.Context, provider Provider, key string, value string) ([]ProfileDTO, error) {
+	req := new(gamespy.Packet)
+	req.Add("search", "")
+	req.Add("sesskey", "0")
+	req.Add("profileid", "0")
+	req.Add("namespaceid", namespaceID)
+	req.Add(key, value)
+	req.Add("gamename", gameName)
 
-	if err = write(conn, c.timeout, signup); err != nil {
-		return fmt.Errorf("failed to write request: %w", err)
+	res, err := c.searchRequest(ctx, provider, req)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := read(conn, c.timeout)
+	return decodeSearchResponse(res)
+}
+
+// Check checks the email address and password of the account's profile with the given nick, returning the profile's
+// ID. Unlike Login, it does not require the profile to have a uniquenick.
+func (c *Client) Check(provider Provider, nick, email, password string) (CheckDTO, error) {
+	return c.CheckContext(context.Background(), provider, nick, email, password)
+}
+
+func (c *Client) CheckContext(ctx context.Context, provider Provider, nick, email, password string) (check CheckDTO, err error) {
+	err = c.retry(ctx, func() error {
+		check, err = c.check(ctx, provider, nick, email, password)
+		return err
+	})
+	return check, err
+}
+
+func (c *Client) check(ctx context.Context, provider Provider, nick, email, password string) (CheckDTO, error) {
+	req := new(gamespy.Packet)
+	req.Add("check", "")
+	req.Add("nick", nick)
+	req.Add("email", email)
+	req.Add("partnerid", partnerID)
+	req.Add("passenc", gamespy.EncodePassword(password))
+	req.Add("namespaceid", namespaceID)
+	req.Add("gamename", gameName)
+
+	res, err := c.searchRequest(ctx, provider, req)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return CheckDTO{}, err
 	}
 
-	if errmsg, exists := res.Lookup("errmsg"); exists {
-		return fmt.Errorf("%s (code: %s)", errmsg, res.Get("err"))
+	return decodeCheckResponse(res)
+}
+
+// searchRequest sends the request to the provider's search server, returning the response
+func (c *Client) searchRequest(ctx context.Context, provider Provider, req *gamespy.Packet) (res *gamespy.Packet, err error) {
+	conn, err := c.dial(ctx, getHostname(provider, serviceGPSP), portGPSP)
+	if err != nil {
+		return nil, err
 	}
+	stop := closeOnDone(ctx, conn)
+	defer func() {
+		stop()
+		err = multierr.Append(contextErr(ctx, err), disconnect(conn))
+	}()
 
-	return nil
+	// Keep data following a packet for the next read, like the session's receive loop does
+	r := newPacketReader(conn)
+
+	if err = write(ctx, conn, c.writeTimeout, req, c.packetLog(provider)); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	res, err = read(ctx, conn, r, c.readTimeout, c.packetLog(provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return res, nil
+}
+
+// Ping measures the time it takes to connect to the provider's login server (excluding the hostname lookup unless
+// connecting via a proxy)
+func (c *Client) Ping(provider Provider) (time.Duration, error) {
+	return c.PingContext(context.Background(), provider)
 }
 
-func connect(host string, port string) (net.Conn, error) {
-	raddr, err := net.ResolveTCPAddr(network, net.JoinHostPort(host, port))
+func (c *Client) PingContext(ctx context.Context, provider Provider) (time.Duration, error) {
+	proxy, err := c.proxyFor(net.JoinHostPort(getHostname(provider, serviceGPCM), portGPCM))
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve address: %w", err)
+		return 0, err
 	}
+	if proxy != nil {
+		start := time.Now()
+		conn, err2 := c.dial(ctx, getHostname(provider, serviceGPCM), portGPCM)
+		if err2 != nil {
+			return 0, err2
+		}
+		latency := time.Since(start)
 
-	conn, err := net.DialTCP(raddr.Network(), nil, raddr)
+		if err2 = disconnect(conn); err2 != nil {
+			return 0, err2
+		}
+		return latency, nil
+	}
+
+	ips, err := c.resolve(ctx, getHostname(provider, serviceGPCM))
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	conn, err := c.dialAddrs(ctx, ips, portGPCM)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", raddr.String(), err)
+		return 0, err
+	}
+	latency := time.Since(start)
+
+	if err = disconnect(conn); err != nil {
+		return 0, err
+	}
+
+	return latency, nil
+}
+
+// retry calls do until it succeeds, fails with an error which is not transient or the retries are exhausted, waiting
+// for an exponentially increasing delay between attempts
+func (c *Client) retry(ctx context.Context, do func() error) error {
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := do()
+		if err == nil || attempt >= c.retries || !isTransient(err) {
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				return permanent.err
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func isProviderError(err error) bool {
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr)
+}
+
+// permanentError marks errors which must not be retried, even if they are transient
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// isTransient returns whether the error is caused by a network issue which may not occur again on retrying
+func isTransient(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, ErrSessionClosed) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// closeOnDone closes conn once ctx is done, interrupting any pending read/write. The returned function stops watching
+// ctx and must be called once conn is no longer used.
+func closeOnDone(ctx context.Context, conn net.Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// contextErr returns ctx's error instead of err if ctx is done, since err then only describes the interruption
+// (e.g. "use of closed network connection")
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
 	}
+	return err
+}
 
-	return conn, nil
+// deadline returns the time the next read/write must complete by
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(d) {
+		return ctxDeadline
+	}
+	return d
 }
 
 func disconnect(conn net.Conn) error {
@@ -174,8 +655,12 @@ func disconnect(conn net.Conn) error {
 	return nil
 }
 
-func write(conn net.Conn, timeout time.Duration, packet *gamespy.Packet) error {
-	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+func write(ctx context.Context, conn net.Conn, timeout time.Duration, packet *gamespy.Packet, log packetLog) error {
+	if log != nil {
+		log(PacketSent, packet.Bytes())
+	}
+
+	if err := conn.SetWriteDeadline(deadline(ctx, timeout)); err != nil {
 		return fmt.Errorf("failed to set write deadline: %w", err)
 	}
 
@@ -185,18 +670,23 @@ func write(conn net.Conn, timeout time.Duration, packet *gamespy.Packet) error {
 	return nil
 }
 
-func read(conn net.Conn, timeout time.Duration) (*gamespy.Packet, error) {
-	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+// read reads the next packet from conn using r, which must be the (only) packetReader of conn so data received after a
+// packet is not lost
+func read(ctx context.Context, conn net.Conn, r *packetReader, timeout time.Duration, log packetLog) (*gamespy.Packet, error) {
+	if err := conn.SetReadDeadline(deadline(ctx, timeout)); err != nil {
 		return nil, fmt.Errorf("failed to set read deadline: %w", err)
 	}
 
-	buffer := make([]byte, 1024)
-	n, err := conn.Read(buffer)
+	// Responses (e.g. of accounts with many nicks) may span several reads
+	raw, err := r.next()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read packet: %w", err)
 	}
+	if log != nil {
+		log(PacketReceived, raw)
+	}
 
-	res, err := gamespy.NewPacketFromBytes(buffer[:n])
+	res, err := gamespy.NewPacketFromBytes(raw)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse packet: %w", err)
 	}
@@ -0,0 +1,57 @@
+package gamespy
+
+import (
+	"strings"
+)
+
+// PacketDirection tells whether a logged packet was sent to or received from a provider
+type PacketDirection string
+
+const (
+	PacketSent     PacketDirection = "sent"
+	PacketReceived PacketDirection = "received"
+	// Received packet which was discarded without being processed (e.g. a response nobody waited for anymore)
+	PacketDropped PacketDirection = "dropped"
+
+	redacted = "<redacted>"
+)
+
+// Keys of packet values which contain or are derived from passwords (or grant access to the account)
+var sensitiveKeys = map[string]bool{
+	"pass":        true,
+	"passenc":     true,
+	"password":    true,
+	"passwordenc": true,
+	"newpass":     true,
+	"response":    true,
+	"proof":       true,
+	"lt":          true,
+	"authtoken":   true,
+}
+
+// packetLog is called with every raw packet sent/received on a connection (nil to not log packets)
+type packetLog func(direction PacketDirection, raw []byte)
+
+// packetLog returns the packetLog passing packets of connections to the provider (with sensitive values redacted) to
+// ClientOptions.LogPacket, or nil if it is not set
+func (c *Client) packetLog(provider Provider) packetLog {
+	if c.logPacket == nil {
+		return nil
+	}
+
+	return func(direction PacketDirection, raw []byte) {
+		c.logPacket(provider, direction, RedactPacket(string(raw)))
+	}
+}
+
+// RedactPacket replaces the values of password (and derived) keys in a raw GameSpy packet ("\key\value\...\final\")
+func RedactPacket(packet string) string {
+	// Splitting on the leading backslash yields an empty first element, followed by alternating keys and values
+	parts := strings.Split(packet, `\`)
+	for i := 1; i+1 < len(parts); i += 2 {
+		if sensitiveKeys[strings.ToLower(parts[i])] && parts[i+1] != "" {
+			parts[i+1] = redacted
+		}
+	}
+	return strings.Join(parts, `\`)
+}
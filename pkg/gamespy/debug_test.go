@@ -0,0 +1,77 @@
+package gamespy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func TestRedactPacket(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   string
+		expected string
+	}{
+		{
+			name:     "redacts nicks request passwords",
+			packet:   `\nicks\\email\mister249@example.com\pass\secret\passenc\J8DHxh==\namespaceid\0\gamename\gmtest\final\`,
+			expected: `\nicks\\email\mister249@example.com\pass\<redacted>\passenc\<redacted>\namespaceid\0\gamename\gmtest\final\`,
+		},
+		{
+			name:     "redacts login response",
+			packet:   `\login\\challenge\abc\uniquenick\mister249\response\638ac6fccc7f5a79f25b82132c87572b\id\1\final\`,
+			expected: `\login\\challenge\abc\uniquenick\mister249\response\<redacted>\id\1\final\`,
+		},
+		{
+			name:     "redacts login proof and token",
+			packet:   `\lc\2\sesskey\123\proof\0d4f7a\userid\200123\profileid\500123\lt\XdR2LlH69X\final\`,
+			expected: `\lc\2\sesskey\123\proof\<redacted>\userid\200123\profileid\500123\lt\<redacted>\final\`,
+		},
+		{
+			name:     "redacts password update",
+			packet:   `\updateui\\sesskey\123\passwordenc\J8DHxh==\id\2\final\`,
+			expected: `\updateui\\sesskey\123\passwordenc\<redacted>\id\2\final\`,
+		},
+		{
+			name:     "matches keys regardless of case",
+			packet:   `\newuser\\Password\secret\final\`,
+			expected: `\newuser\\Password\<redacted>\final\`,
+		},
+		{
+			name:     "keeps values which are named like sensitive keys",
+			packet:   `\nick\pass\uniquenick\response\final\`,
+			expected: `\nick\pass\uniquenick\response\final\`,
+		},
+		{
+			name:     "keeps empty values",
+			packet:   `\pass\\final\`,
+			expected: `\pass\\final\`,
+		},
+		{
+			name:     "keeps packets without sensitive values",
+			packet:   `\ka\\final\`,
+			expected: `\ka\\final\`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if redacted := RedactPacket(tt.packet); redacted != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, redacted)
+			}
+		})
+	}
+}
+
+func TestRedactPacketEncodedPassword(t *testing.T) {
+	// Neither the password nor its encoded form may remain anywhere in the packet
+	const password = "hunter2!secret"
+	encoded := gamespy.EncodePassword(password)
+	packet := `\nicks\\email\a@example.com\pass\` + password + `\passenc\` + encoded + `\final\`
+
+	redacted := RedactPacket(packet)
+	if strings.Contains(redacted, password) || strings.Contains(redacted, encoded) {
+		t.Errorf("password remains in redacted packet: %q", redacted)
+	}
+}
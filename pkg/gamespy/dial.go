@@ -0,0 +1,158 @@
+package gamespy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	network = "tcp"
+	// Time to wait for a connection using the preferred address family before also trying the other one (as
+	// recommended by RFC 8305 and used by net.Dialer)
+	fallbackDelay = 300 * time.Millisecond
+)
+
+// resolve looks up the IPv4 and IPv6 addresses of host using c.resolver (or the system resolver if there is none),
+// in the resolver's order of preference
+func (c *Client) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	var resolver Resolver = net.DefaultResolver
+	if c.resolver != nil {
+		resolver = c.resolver
+	}
+
+	ips, err := resolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, contextErr(ctx, fmt.Errorf("failed to resolve address: %w", err))
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("failed to resolve address: no addresses found for %s", host)
+	}
+
+	return ips, nil
+}
+
+func (c *Client) connect(ctx context.Context, host string, port string) (net.Conn, error) {
+	ips, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.dialAddrs(ctx, ips, port)
+}
+
+// dialAddrs connects to the first reachable address, racing the addresses of the preferred family against those of
+// the other family ("Happy Eyeballs", RFC 8305), so broken IPv6 (or IPv4 on IPv6-only networks) does not stall
+// connecting
+func (c *Client) dialAddrs(ctx context.Context, ips []net.IP, port string) (net.Conn, error) {
+	primaries, fallbacks := partitionAddrs(ips)
+	if len(fallbacks) == 0 {
+		return c.dialSerial(ctx, primaries, port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+	race := func(ips []net.IP, primary bool) {
+		conn, err := c.dialSerial(ctx, ips, port)
+		results <- dialResult{conn: conn, err: err, primary: primary}
+	}
+
+	go race(primaries, true)
+	fallbackTimer := time.NewTimer(fallbackDelay)
+	defer fallbackTimer.Stop()
+
+	var primaryErr, fallbackErr error
+	fallbackStarted := false
+	for pending := 1; pending > 0; {
+		select {
+		case <-fallbackTimer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				go race(fallbacks, false)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// The other attempt is canceled once this function returns, but may still succeed before noticing
+				if pending > 0 {
+					go closeLoser(results)
+				}
+				return res.conn, nil
+			}
+
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+			// Try the other family right away if the preferred one failed
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				go race(fallbacks, false)
+			}
+		}
+	}
+
+	// Errors of the preferred family are usually more relevant
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+	return nil, fallbackErr
+}
+
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+// closeLoser closes the connection of the attempt which lost the race, should it succeed after all
+func closeLoser(results <-chan dialResult) {
+	if res := <-results; res.err == nil {
+		_ = res.conn.Close()
+	}
+}
+
+// dialSerial tries connecting to each address in turn, returning the first connection or the first error
+func (c *Client) dialSerial(ctx context.Context, ips []net.IP, port string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: c.connectTimeout}
+
+	var firstErr error
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip.String(), port)
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = contextErr(ctx, fmt.Errorf("failed to connect to %s: %w", addr, err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// partitionAddrs splits the addresses into those of the first address's family (primaries) and all others
+func partitionAddrs(ips []net.IP) (primaries, fallbacks []net.IP) {
+	primaryIs4 := ips[0].To4() != nil
+	for _, ip := range ips {
+		if (ip.To4() != nil) == primaryIs4 {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+	return primaries, fallbacks
+}
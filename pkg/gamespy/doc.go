@@ -0,0 +1,15 @@
+// Package gamespy implements the parts of the GameSpy presence protocol needed to manage accounts on GameSpy
+// replacement providers (e.g. OpenSpy), such as listing an account's nicks, creating accounts, changing passwords and
+// email addresses and testing logins.
+//
+// Errors reported by a provider's backend are returned as *ProviderError, with Code being one of the ErrCode constants
+// regardless of the language of the provider's message. Malformed or incomplete responses are returned as
+// *ResponseError. All other errors are network errors.
+//
+// Client reuses presence connections for consecutive operations to avoid tripping providers' rate limits. Use
+// Client.OpenSession to control the lifetime of a connection explicitly.
+//
+// The exported API of this package follows semantic versioning: exported identifiers are only removed or changed in
+// incompatible ways with a new major version. New behavior is added via new functions or ClientOptions fields instead
+// of changing existing signatures.
+package gamespy
@@ -1,6 +1,38 @@
 package gamespy
 
+// NickDTO is a nick of an account, as returned by GetNicks
 type NickDTO struct {
 	Nick       string
 	UniqueNick string
 }
+
+// NicksResult is the result of looking up an account's nicks on a provider, as returned by GetNicksAll
+type NicksResult struct {
+	Nicks []NickDTO
+	Err   error
+}
+
+// LoginDTO describes the account and profile logged in to, as returned by Login
+type LoginDTO struct {
+	UserID     string
+	ProfileID  string
+	UniqueNick string
+}
+
+// NewUserDTO describes the account and profile created, as returned by NewUser. Not all providers return the IDs.
+type NewUserDTO struct {
+	UserID    string
+	ProfileID string
+}
+
+// CheckDTO describes the profile whose credentials were checked, as returned by Check
+type CheckDTO struct {
+	ProfileID string
+}
+
+// ProfileDTO is a profile found by searching a provider, as returned by SearchProfiles
+type ProfileDTO struct {
+	ProfileID  string
+	Nick       string
+	UniqueNick string
+}
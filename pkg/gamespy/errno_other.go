@@ -0,0 +1,14 @@
+//go:build !windows
+
+package gamespy
+
+import (
+	"syscall"
+)
+
+// Errors of connections which were reset/aborted/refused
+var transientErrnos = []error{
+	syscall.ECONNRESET,
+	syscall.ECONNABORTED,
+	syscall.ECONNREFUSED,
+}
@@ -0,0 +1,14 @@
+package gamespy
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Errors of connections which were reset/aborted/refused, which Windows reports using Winsock's error codes
+var transientErrnos = []error{
+	syscall.WSAECONNRESET,
+	syscall.WSAECONNABORTED,
+	windows.WSAECONNREFUSED,
+}
@@ -0,0 +1,45 @@
+package gamespy
+
+import (
+	"fmt"
+)
+
+// Error codes of the GameSpy presence protocol, which all providers use regardless of the language of their messages
+const (
+	ErrCodeGeneral                = "0"
+	ErrCodeParse                  = "1"
+	ErrCodeDatabase               = "4"
+	ErrCodeForcedDisconnect       = "6"
+	ErrCodeLogin                  = "256"
+	ErrCodeLoginBadNick           = "258"
+	ErrCodeLoginBadEmail          = "259"
+	ErrCodeLoginBadPassword       = "260"
+	ErrCodeLoginBadProfile        = "261"
+	ErrCodeLoginProfileDeleted    = "262"
+	ErrCodeLoginConnectionRefused = "263"
+	ErrCodeLoginBadUniqueNick     = "265"
+	ErrCodeNewUser                = "512"
+	ErrCodeNewUserBadNick         = "513"
+	ErrCodeNewUserBadPassword     = "514"
+	ErrCodeNewUserUniqueNickBad   = "515"
+	ErrCodeNewUserUniqueNickUsed  = "516"
+)
+
+// ProviderError is an error reported by a provider's backend, with a message in whatever language the provider uses
+type ProviderError struct {
+	Code    string
+	Message string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s (code: %s)", e.Message, e.Code)
+}
+
+// Describe returns a description of the error and how to resolve it in the given language (see DescribeErrorCode),
+// falling back to the provider's message for unknown error codes
+func (e *ProviderError) Describe(lang Language) string {
+	if description, ok := DescribeErrorCode(e.Code, lang); ok {
+		return description
+	}
+	return e.Error()
+}
@@ -0,0 +1,59 @@
+package gamespy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	packetTerminator = "\\final\\"
+	// Size of each read from the connection, packets may span any number of reads
+	readBufferSize = 1024
+	// Responses larger than this are not GameSpy packets (even accounts with hundreds of nicks stay well below it)
+	maxPacketSize = 64 * 1024
+)
+
+// packetReader splits the data read from r into packets, which end with packetTerminator. Packets may be split across
+// several reads (TCP segments) and a single read may contain several packets.
+type packetReader struct {
+	r       io.Reader
+	buffer  []byte
+	pending []byte
+}
+
+func newPacketReader(r io.Reader) *packetReader {
+	return &packetReader{
+		r:      r,
+		buffer: make([]byte, readBufferSize),
+	}
+}
+
+// next returns the next packet (including the terminator), reading until it has been received completely
+func (p *packetReader) next() ([]byte, error) {
+	for {
+		if i := bytes.Index(p.pending, []byte(packetTerminator)); i != -1 {
+			raw := p.pending[:i+len(packetTerminator)]
+			p.pending = p.pending[i+len(packetTerminator):]
+			return raw, nil
+		}
+
+		if len(p.pending) > maxPacketSize {
+			return nil, fmt.Errorf("packet exceeds %d bytes without terminator", maxPacketSize)
+		}
+
+		n, err := p.r.Read(p.buffer)
+		p.pending = append(p.pending, p.buffer[:n]...)
+		if err != nil {
+			// Data received before the error may still complete a packet
+			if n > 0 && bytes.Contains(p.pending, []byte(packetTerminator)) {
+				continue
+			}
+			if errors.Is(err, io.EOF) && len(p.pending) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+}
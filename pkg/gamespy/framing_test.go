@@ -0,0 +1,126 @@
+package gamespy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestPacketReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        io.Reader
+		expected []string
+		wantErr  error
+	}{
+		{
+			name:     "reads packet split across reads",
+			r:        iotest.OneByteReader(strings.NewReader(`\lc\1\challenge\ABCDEFGHIJ\id\1\final\`)),
+			expected: []string{`\lc\1\challenge\ABCDEFGHIJ\id\1\final\`},
+			wantErr:  io.EOF,
+		},
+		{
+			name:     "reads several packets from a single read",
+			r:        strings.NewReader(`\nr\0\ndone\\final\\ka\\final\`),
+			expected: []string{`\nr\0\ndone\\final\`, `\ka\\final\`},
+			wantErr:  io.EOF,
+		},
+		{
+			name:     "returns packet completed by data read along with an error",
+			r:        iotest.DataErrReader(strings.NewReader(`\ka\\final\`)),
+			expected: []string{`\ka\\final\`},
+			wantErr:  io.EOF,
+		},
+		{
+			name:     "reports connection closed mid-packet",
+			r:        strings.NewReader(`\ka\\final\\nr\1\nick\`),
+			expected: []string{`\ka\\final\`},
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := newPacketReader(tt.r)
+			for _, expected := range tt.expected {
+				raw, err := pr.next()
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if string(raw) != expected {
+					t.Errorf("expected %q, got %q", expected, raw)
+				}
+			}
+			if _, err := pr.next(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPacketReaderMaxPacketSize(t *testing.T) {
+	// Reading must stop once the limit is exceeded, rather than buffering everything the other side sends
+	r := &countingReader{r: bytes.NewReader(bytes.Repeat([]byte{'a'}, 4*maxPacketSize))}
+	pr := newPacketReader(r)
+
+	if _, err := pr.next(); err == nil {
+		t.Fatal("expected error for packet exceeding maximum size")
+	}
+	if r.n > maxPacketSize+readBufferSize {
+		t.Errorf("read %d bytes, expected at most %d", r.n, maxPacketSize+readBufferSize)
+	}
+
+	// Packets right below the limit are still accepted
+	packet := `\ka\` + strings.Repeat("a", maxPacketSize-len(`\ka\\final\`)) + `\final\`
+	raw, err := newPacketReader(strings.NewReader(packet)).next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(raw) != len(packet) {
+		t.Errorf("expected %d bytes, got %d", len(packet), len(raw))
+	}
+}
+
+func TestReadKeepsFollowingPackets(t *testing.T) {
+	// GIVEN
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		// Both packets arrive with a single read
+		_, _ = server.Write([]byte(`\ka\\final\\nr\0\ndone\\final\`))
+	}()
+	r := newPacketReader(client)
+
+	// WHEN
+	first, err := read(context.Background(), client, r, time.Second, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	second, err := read(context.Background(), client, r, time.Second, nil)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if first.Get("ka") != "" || second.Get("nr") != "0" {
+		t.Errorf("unexpected packets: %q, %q", first.Bytes(), second.Bytes())
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += n
+	return n, err
+}
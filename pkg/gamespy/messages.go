@@ -0,0 +1,180 @@
+package gamespy
+
+// Language is a language error descriptions are available in, identified by its ISO 639-1 code
+type Language string
+
+const (
+	LanguageEnglish Language = "en"
+	LanguageRussian Language = "ru"
+)
+
+// Languages lists the languages error descriptions are available in
+var Languages = []Language{LanguageEnglish, LanguageRussian}
+
+type errorDescription struct {
+	// What went wrong
+	problem string
+	// What the user can do about it
+	action string
+}
+
+// Descriptions of error codes, keyed by language. Error codes are described rather than the (English or Russian,
+// depending on provider) messages, since they are the same across providers.
+var errorDescriptions = map[Language]map[string]errorDescription{
+	LanguageEnglish: {
+		ErrCodeGeneral: {
+			problem: "The provider reported an unspecified error",
+			action:  "Please try again later.",
+		},
+		ErrCodeParse: {
+			problem: "The provider could not understand the request",
+			action:  "Make sure you are using the latest version of the migrator.",
+		},
+		ErrCodeDatabase: {
+			problem: "The provider is having database issues",
+			action:  "Please try again later.",
+		},
+		ErrCodeForcedDisconnect: {
+			problem: "The provider closed the connection",
+			action:  "Make sure the account is not logged in elsewhere (e.g. in-game) and try again.",
+		},
+		ErrCodeLogin: {
+			problem: "The provider refused the login",
+			action:  "Check the email address, nick and password and try again.",
+		},
+		ErrCodeLoginBadNick: {
+			problem: "No account with this email/nick exists on the provider",
+			action:  "Check the email address and nick for typos, or migrate the profile to create the account.",
+		},
+		ErrCodeLoginBadEmail: {
+			problem: "No account with this email address exists on the provider",
+			action:  "Check the email address for typos, or migrate the profile to create the account.",
+		},
+		ErrCodeLoginBadPassword: {
+			problem: "The password does not match the provider account",
+			action:  "The profile's password may differ from the one used on the provider. Enter the password you set on the provider, or reset it on the provider's website.",
+		},
+		ErrCodeLoginBadProfile: {
+			problem: "No profile with this nick exists on the provider",
+			action:  "Check the nick for typos, or migrate the profile to create it.",
+		},
+		ErrCodeLoginProfileDeleted: {
+			problem: "The profile has been deleted on the provider",
+			action:  "Contact the provider's support to restore it, or migrate using a different nick.",
+		},
+		ErrCodeLoginConnectionRefused: {
+			problem: "The provider refused the connection, which usually means the account or your IP address is banned",
+			action:  "Contact the provider's support if you think this is a mistake.",
+		},
+		ErrCodeLoginBadUniqueNick: {
+			problem: "No account with this nick exists on the provider",
+			action:  "Check the nick for typos, or migrate the profile to create the account.",
+		},
+		ErrCodeNewUser: {
+			problem: "The provider could not create the account",
+			action:  "Check the email address, nick and password and try again.",
+		},
+		ErrCodeNewUserBadNick: {
+			problem: "An account with this email already exists on the provider, but uses a different password",
+			action:  "Enter the password you set on the provider, or reset it on the provider's website.",
+		},
+		ErrCodeNewUserBadPassword: {
+			problem: "An account with this email already exists on the provider, but uses a different password",
+			action:  "Enter the password you set on the provider, or reset it on the provider's website.",
+		},
+		ErrCodeNewUserUniqueNickBad: {
+			problem: "The nick is not allowed by the provider",
+			action:  "Choose a nick of at most 20 characters without spaces or special characters.",
+		},
+		ErrCodeNewUserUniqueNickUsed: {
+			problem: "The nick is already taken by another account on the provider",
+			action:  "Choose a different nick, or log in to the account the nick belongs to if it is yours.",
+		},
+	},
+	LanguageRussian: {
+		ErrCodeGeneral: {
+			problem: "Провайдер сообщил о неизвестной ошибке",
+			action:  "Повторите попытку позже.",
+		},
+		ErrCodeParse: {
+			problem: "Провайдер не смог обработать запрос",
+			action:  "Убедитесь, что вы используете последнюю версию программы.",
+		},
+		ErrCodeDatabase: {
+			problem: "У провайдера проблемы с базой данных",
+			action:  "Повторите попытку позже.",
+		},
+		ErrCodeForcedDisconnect: {
+			problem: "Провайдер закрыл соединение",
+			action:  "Убедитесь, что в аккаунт не выполнен вход в другом месте (например, в игре), и повторите попытку.",
+		},
+		ErrCodeLogin: {
+			problem: "Провайдер отклонил вход",
+			action:  "Проверьте email, ник и пароль и повторите попытку.",
+		},
+		ErrCodeLoginBadNick: {
+			problem: "У провайдера нет аккаунта с таким email/ником",
+			action:  "Проверьте email и ник на опечатки или перенесите профиль, чтобы создать аккаунт.",
+		},
+		ErrCodeLoginBadEmail: {
+			problem: "У провайдера нет аккаунта с таким email",
+			action:  "Проверьте email на опечатки или перенесите профиль, чтобы создать аккаунт.",
+		},
+		ErrCodeLoginBadPassword: {
+			problem: "Пароль не подходит к аккаунту провайдера",
+			action:  "Пароль профиля может отличаться от пароля у провайдера. Введите пароль, установленный у провайдера, или сбросьте его на сайте провайдера.",
+		},
+		ErrCodeLoginBadProfile: {
+			problem: "У провайдера нет профиля с таким ником",
+			action:  "Проверьте ник на опечатки или перенесите профиль, чтобы создать его.",
+		},
+		ErrCodeLoginProfileDeleted: {
+			problem: "Профиль был удалён у провайдера",
+			action:  "Обратитесь в поддержку провайдера, чтобы восстановить его, или перенесите профиль под другим ником.",
+		},
+		ErrCodeLoginConnectionRefused: {
+			problem: "Провайдер отклонил соединение — обычно это означает, что аккаунт или ваш IP-адрес заблокирован",
+			action:  "Обратитесь в поддержку провайдера, если считаете это ошибкой.",
+		},
+		ErrCodeLoginBadUniqueNick: {
+			problem: "У провайдера нет аккаунта с таким ником",
+			action:  "Проверьте ник на опечатки или перенесите профиль, чтобы создать аккаунт.",
+		},
+		ErrCodeNewUser: {
+			problem: "Провайдер не смог создать аккаунт",
+			action:  "Проверьте email, ник и пароль и повторите попытку.",
+		},
+		ErrCodeNewUserBadNick: {
+			problem: "Аккаунт с таким email уже существует у провайдера, но с другим паролем",
+			action:  "Введите пароль, установленный у провайдера, или сбросьте его на сайте провайдера.",
+		},
+		ErrCodeNewUserBadPassword: {
+			problem: "Аккаунт с таким email уже существует у провайдера, но с другим паролем",
+			action:  "Введите пароль, установленный у провайдера, или сбросьте его на сайте провайдера.",
+		},
+		ErrCodeNewUserUniqueNickBad: {
+			problem: "Провайдер не допускает такой ник",
+			action:  "Выберите ник длиной не более 20 символов без пробелов и специальных символов.",
+		},
+		ErrCodeNewUserUniqueNickUsed: {
+			problem: "Ник уже занят другим аккаунтом у провайдера",
+			action:  "Выберите другой ник или войдите в аккаунт, которому принадлежит ник, если он ваш.",
+		},
+	},
+}
+
+// DescribeErrorCode returns a description of the error code and how to resolve it in the given language, falling back
+// to English if the language is not available. Returns false if the error code is unknown.
+func DescribeErrorCode(code string, lang Language) (string, bool) {
+	descriptions, ok := errorDescriptions[lang]
+	if !ok {
+		descriptions = errorDescriptions[LanguageEnglish]
+	}
+
+	d, ok := descriptions[code]
+	if !ok {
+		return "", false
+	}
+
+	return d.problem + ". " + d.action, true
+}
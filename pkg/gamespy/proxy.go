@@ -0,0 +1,289 @@
+package gamespy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	socksVersion5          = 0x05
+	socksAuthNone          = 0x00
+	socksAuthPassword      = 0x02
+	socksAuthNoAcceptable  = 0xff
+	socksAuthPasswordVer   = 0x01
+	socksCmdConnect        = 0x01
+	socksAddrTypeIPv4      = 0x01
+	socksAddrTypeDomain    = 0x03
+	socksAddrTypeIPv6      = 0x04
+	socksReplySucceeded    = 0x00
+	defaultHTTPProxyPort   = "80"
+	defaultSOCKS5ProxyPort = "1080"
+)
+
+// ProxyFromEnvironment returns the proxy to use for connecting to addr ("host:port") based on the HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY environment variables (or their lowercase versions). HTTP proxies are asked to tunnel the
+// connection (CONNECT), so they need to allow connecting to the GameSpy ports. A nil URL means no proxy should be used.
+// It can be used as ClientOptions.Proxy.
+func ProxyFromEnvironment(addr string) (*url.URL, error) {
+	// Tunneled connections are handled like HTTPS requests, but proxies are often only configured via HTTP_PROXY
+	for _, scheme := range []string{"https", "http"} {
+		proxy, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+		if err != nil || proxy != nil {
+			return proxy, err
+		}
+	}
+	return nil, nil
+}
+
+// proxyFor returns the proxy to connect to addr through, or nil if there is none
+func (c *Client) proxyFor(addr string) (*url.URL, error) {
+	if c.proxy == nil {
+		return nil, nil
+	}
+
+	proxy, err := c.proxy(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine proxy: %w", err)
+	}
+	return proxy, nil
+}
+
+// dial connects to host:port, via the proxy returned by c.proxy (if any)
+func (c *Client) dial(ctx context.Context, host string, port string) (net.Conn, error) {
+	addr := net.JoinHostPort(host, port)
+	proxy, err := c.proxyFor(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxy == nil {
+		return c.connect(ctx, host, port)
+	}
+
+	return c.connectViaProxy(ctx, proxy, addr)
+}
+
+// connectViaProxy connects to addr through the HTTP (CONNECT) or SOCKS5 proxy
+func (c *Client) connectViaProxy(ctx context.Context, proxy *url.URL, addr string) (net.Conn, error) {
+	var defaultPort string
+	switch proxy.Scheme {
+	case "http":
+		defaultPort = defaultHTTPProxyPort
+	case "socks5", "socks5h":
+		defaultPort = defaultSOCKS5ProxyPort
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", proxy.Scheme)
+	}
+
+	port := proxy.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	conn, err := c.connect(ctx, proxy.Hostname(), port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+	}
+
+	// Handshakes use a single deadline covering all their reads/writes
+	if err = conn.SetDeadline(deadline(ctx, c.connectTimeout)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to set proxy handshake deadline: %w", err)
+	}
+
+	tunnel := conn
+	if proxy.Scheme == "http" {
+		tunnel, err = handshakeHTTP(conn, proxy, addr)
+	} else {
+		err = handshakeSOCKS5(conn, proxy, addr)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, contextErr(ctx, fmt.Errorf("failed to connect to %s via proxy %s: %w", addr, proxy.Host, err))
+	}
+
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to reset proxy handshake deadline: %w", err)
+	}
+
+	return tunnel, nil
+}
+
+// handshakeHTTP asks the HTTP proxy to tunnel the connection to addr. Returns a connection which also returns any data
+// the proxy sent after its response.
+func handshakeHTTP(conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send CONNECT request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy refused CONNECT: %s", res.Status)
+	}
+
+	// Presence servers send their challenge right away, which may already have been buffered
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn reads from r (which wraps Conn) to not lose any buffered data
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// handshakeSOCKS5 asks the SOCKS5 proxy to connect to addr (RFC 1928), authenticating with the proxy URL's username
+// and password if set (RFC 1929). Host names are always resolved by the proxy, since networks requiring a proxy often
+// do not resolve external names either.
+func handshakeSOCKS5(conn net.Conn, proxy *url.URL, addr string) error {
+	method := byte(socksAuthNone)
+	if proxy.User != nil {
+		method = socksAuthPassword
+	}
+
+	if _, err := conn.Write([]byte{socksVersion5, 1, method}); err != nil {
+		return fmt.Errorf("failed to send greeting: %w", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read greeting reply: %w", err)
+	}
+	if reply[0] != socksVersion5 {
+		return fmt.Errorf("unexpected SOCKS version: %d", reply[0])
+	}
+	if reply[1] == socksAuthNoAcceptable || reply[1] != method {
+		return errors.New("proxy does not accept any offered authentication method")
+	}
+
+	if method == socksAuthPassword {
+		if err := authenticateSOCKS5(conn, proxy.User); err != nil {
+			return err
+		}
+	}
+
+	req, err := socksConnectRequest(addr)
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send connect request: %w", err)
+	}
+
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("failed to read connect reply: %w", err)
+	}
+	if header[1] != socksReplySucceeded {
+		return fmt.Errorf("proxy refused to connect (reply code %d)", header[1])
+	}
+
+	// Skip the bound address, which is of no use here
+	var skip int
+	switch header[3] {
+	case socksAddrTypeIPv4:
+		skip = net.IPv4len
+	case socksAddrTypeIPv6:
+		skip = net.IPv6len
+	case socksAddrTypeDomain:
+		length := make([]byte, 1)
+		if _, err = io.ReadFull(conn, length); err != nil {
+			return fmt.Errorf("failed to read connect reply: %w", err)
+		}
+		skip = int(length[0])
+	default:
+		return fmt.Errorf("unexpected address type in connect reply: %d", header[3])
+	}
+	if _, err = io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fmt.Errorf("failed to read connect reply: %w", err)
+	}
+
+	return nil
+}
+
+func authenticateSOCKS5(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("proxy username/password is too long")
+	}
+
+	req := []byte{socksAuthPasswordVer, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send credentials: %w", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read authentication reply: %w", err)
+	}
+	if reply[1] != socksReplySucceeded {
+		return errors.New("proxy rejected username/password")
+	}
+
+	return nil
+}
+
+// socksConnectRequest returns a connect request for addr, letting the proxy resolve host names
+func socksConnectRequest(addr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %q", portStr)
+	}
+
+	req := []byte{socksVersion5, socksCmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host name is too long: %q", host)
+		}
+		req = append(req, socksAddrTypeDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socksAddrTypeIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socksAddrTypeIPv6)
+		req = append(req, ip.To16()...)
+	}
+
+	return binary.BigEndian.AppendUint16(req, uint16(port)), nil
+}
@@ -0,0 +1,103 @@
+package gamespy
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCreateUserInterval is the minimum time between creating users on the same provider if
+	// ClientOptions.CreateUserInterval is not set
+	DefaultCreateUserInterval = 3 * time.Second
+	// DefaultThrottleCooldown is the time to wait after a provider throttled creating users if
+	// ClientOptions.ThrottleCooldown is not set
+	DefaultThrottleCooldown = 30 * time.Second
+	// Number of times creating a user is retried after the provider throttled it
+	maxThrottledRetries = 3
+)
+
+// Phrases providers use in (English or Russian) messages when refusing requests due to their anti-abuse protections,
+// which do not use a dedicated error code
+var throttlePhrases = []string{
+	"too many",
+	"rate limit",
+	"slow down",
+	"flood",
+	"try again later",
+	"слишком много",
+	"попробуйте позже",
+}
+
+// Throttled returns whether the provider refused the request due to too many requests (rather than due to the request
+// itself), meaning it may succeed if sent again after a while
+func (e *ProviderError) Throttled() bool {
+	msg := strings.ToLower(e.Message)
+	for _, phrase := range throttlePhrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimiter spaces out requests to each provider
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	// Earliest time the next request may be sent to each provider
+	next map[Provider]time.Time
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		interval: interval,
+		next:     make(map[Provider]time.Time),
+	}
+}
+
+// wait blocks until the next request may be sent to the provider, reserving the slot for the caller
+func (l *rateLimiter) wait(ctx context.Context, provider Provider) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[provider]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[provider] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// cooldown keeps any requests from being sent to the provider for the given duration
+func (l *rateLimiter) cooldown(provider Provider, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.next[provider]) {
+		l.next[provider] = until
+	}
+}
+
+// sleep waits for the duration, returning early if ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
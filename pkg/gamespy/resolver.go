@@ -0,0 +1,222 @@
+package gamespy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultDNSPort = "53"
+
+	dnsHeaderLength  = 12
+	dnsFlagRecursion = 0x0100
+	dnsTypeA         = 1
+	dnsTypeAAAA      = 28
+	dnsClassIN       = 1
+	dnsRcodeMask     = 0x000f
+	dnsRcodeNXDomain = 3
+	dnsPointerMask   = 0xc0
+	// Responses larger than this are not answers to a single query
+	maxDNSMessageSize = 65535
+
+	dohContentType = "application/dns-message"
+)
+
+// Resolver looks up the IP addresses of provider host names. *net.Resolver implements it.
+type Resolver interface {
+	// LookupIP returns the addresses of host for network ("ip", "ip4" or "ip6"), in order of preference
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// NewDNSResolver returns a resolver sending DNS queries to server ("host" or "host:port", port 53 by default) instead
+// of the system's DNS servers
+func NewDNSResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultDNSPort)
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// NewDoHResolver returns a resolver sending DNS queries to a DNS-over-HTTPS endpoint (RFC 8484, e.g.
+// https://cloudflare-dns.com/dns-query), using client to send the requests (nil to use http.DefaultClient). The
+// endpoint's own host name is still resolved by the system. IPv4 addresses are preferred over IPv6 addresses, since
+// the resolver cannot tell whether IPv6 is usable.
+func NewDoHResolver(endpoint string, client *http.Client) Resolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &dohResolver{endpoint: endpoint, client: client}
+}
+
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (r *dohResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var types []uint16
+	switch network {
+	case "ip":
+		types = []uint16{dnsTypeA, dnsTypeAAAA}
+	case "ip4":
+		types = []uint16{dnsTypeA}
+	case "ip6":
+		types = []uint16{dnsTypeAAAA}
+	default:
+		return nil, fmt.Errorf("unsupported network: %q", network)
+	}
+
+	var ips []net.IP
+	for _, qtype := range types {
+		found, err := r.query(ctx, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, found...)
+	}
+
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return ips, nil
+}
+
+// query returns the addresses of the records of type qtype for host
+func (r *dohResolver) query(ctx context.Context, host string, qtype uint16) ([]net.IP, error) {
+	query, err := newDNSQuery(host, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send DNS-over-HTTPS request: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS request failed: %s", res.Status)
+	}
+
+	msg, err := io.ReadAll(io.LimitReader(res.Body, maxDNSMessageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DNS-over-HTTPS response: %w", err)
+	}
+
+	return parseDNSResponse(msg, host, qtype)
+}
+
+// newDNSQuery returns a DNS message asking for the records of type qtype of host (with ID 0, as recommended for DoH)
+func newDNSQuery(host string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, dnsHeaderLength, dnsHeaderLength+len(host)+6)
+	binary.BigEndian.PutUint16(msg[2:], dnsFlagRecursion)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid host name: %q", host)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	return msg, nil
+}
+
+// parseDNSResponse returns the addresses of all A/AAAA (qtype) records in the answer section (including those of any
+// CNAME targets). Returns no addresses without an error if the host does not exist or has no such records.
+func parseDNSResponse(msg []byte, host string, qtype uint16) ([]net.IP, error) {
+	errMalformed := errors.New("malformed DNS response")
+	if len(msg) < dnsHeaderLength {
+		return nil, errMalformed
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	switch rcode := flags & dnsRcodeMask; rcode {
+	case 0:
+	case dnsRcodeNXDomain:
+		return nil, nil
+	default:
+		return nil, &net.DNSError{Err: fmt.Sprintf("server responded with rcode %d", rcode), Name: host}
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := dnsHeaderLength
+	var ok bool
+	for i := 0; i < questions; i++ {
+		// Skip name, type and class
+		if off, ok = skipDNSName(msg, off); !ok || off+4 > len(msg) {
+			return nil, errMalformed
+		}
+		off += 4
+	}
+
+	var ips []net.IP
+	for i := 0; i < answers; i++ {
+		// Skip name, followed by type, class, TTL and data length
+		if off, ok = skipDNSName(msg, off); !ok || off+10 > len(msg) {
+			return nil, errMalformed
+		}
+		rrType := binary.BigEndian.Uint16(msg[off:])
+		rrClass := binary.BigEndian.Uint16(msg[off+2:])
+		length := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+length > len(msg) {
+			return nil, errMalformed
+		}
+
+		if rrType == qtype && rrClass == dnsClassIN && (length == net.IPv4len || length == net.IPv6len) {
+			ips = append(ips, net.IP(append([]byte(nil), msg[off:off+length]...)))
+		}
+		off += length
+	}
+
+	return ips, nil
+}
+
+// skipDNSName returns the offset following the (possibly compressed) name starting at off
+func skipDNSName(msg []byte, off int) (int, bool) {
+	for off < len(msg) {
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1, true
+		case length&dnsPointerMask == dnsPointerMask:
+			// Pointers always end a name
+			return off + 2, off+2 <= len(msg)
+		default:
+			off += 1 + length
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,248 @@
+package gamespy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const (
+	dnsTypeCNAME = 5
+	// Pointer to the question's name, which directly follows the header
+	dnsQuestionNamePointer = 0xc000 | dnsHeaderLength
+)
+
+type dnsRecord struct {
+	rrType uint16
+	data   []byte
+}
+
+// buildDNSResponse returns a response to the query with the given records, all using a compressed name pointing to
+// the question's name
+func buildDNSResponse(t *testing.T, host string, qtype uint16, rcode uint16, records ...dnsRecord) []byte {
+	t.Helper()
+	msg, err := newDNSQuery(host, qtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.BigEndian.PutUint16(msg[2:], 0x8000|dnsFlagRecursion|rcode)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(records)))
+	for _, r := range records {
+		msg = binary.BigEndian.AppendUint16(msg, dnsQuestionNamePointer)
+		msg = binary.BigEndian.AppendUint16(msg, r.rrType)
+		msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+		msg = binary.BigEndian.AppendUint32(msg, 300)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(r.data)))
+		msg = append(msg, r.data...)
+	}
+
+	return msg
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	const host = "gpcm.openspy.net"
+	// CNAME target "a.b" using an uncompressed name
+	cname := []byte{1, 'a', 1, 'b', 0}
+
+	tests := []struct {
+		name     string
+		msg      func(t *testing.T) []byte
+		qtype    uint16
+		expected []net.IP
+		wantErr  bool
+	}{
+		{
+			name: "parses A records with compressed names",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeA, 0,
+					dnsRecord{rrType: dnsTypeCNAME, data: cname},
+					dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 1}},
+					dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 2}},
+				)
+			},
+			qtype:    dnsTypeA,
+			expected: []net.IP{{192, 0, 2, 1}, {192, 0, 2, 2}},
+		},
+		{
+			name: "parses AAAA records",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeAAAA, 0,
+					dnsRecord{rrType: dnsTypeAAAA, data: net.ParseIP("2001:db8::1")},
+				)
+			},
+			qtype:    dnsTypeAAAA,
+			expected: []net.IP{net.ParseIP("2001:db8::1")},
+		},
+		{
+			name: "ignores records of other types",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeAAAA, 0,
+					dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 1}},
+				)
+			},
+			qtype: dnsTypeAAAA,
+		},
+		{
+			name: "returns no addresses for non-existent host",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeA, dnsRcodeNXDomain)
+			},
+			qtype: dnsTypeA,
+		},
+		{
+			name: "returns error for server failure",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeA, 2)
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+		{
+			name: "rejects truncated answer data",
+			msg: func(t *testing.T) []byte {
+				msg := buildDNSResponse(t, host, dnsTypeA, 0, dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 1}})
+				return msg[:len(msg)-2]
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+		{
+			name: "rejects truncated answer pointer",
+			msg: func(t *testing.T) []byte {
+				msg := buildDNSResponse(t, host, dnsTypeA, 0, dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 1}})
+				query, _ := newDNSQuery(host, dnsTypeA)
+				return msg[:len(query)+1]
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+		{
+			name: "rejects missing answers",
+			msg: func(t *testing.T) []byte {
+				msg := buildDNSResponse(t, host, dnsTypeA, 0)
+				binary.BigEndian.PutUint16(msg[6:], 1)
+				return msg
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+		{
+			name: "rejects truncated question",
+			msg: func(t *testing.T) []byte {
+				return buildDNSResponse(t, host, dnsTypeA, 0)[:dnsHeaderLength+3]
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+		{
+			name: "rejects truncated header",
+			msg: func(t *testing.T) []byte {
+				return make([]byte, dnsHeaderLength-1)
+			},
+			qtype:   dnsTypeA,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, err := parseDNSResponse(tt.msg(t), host, tt.qtype)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", ips)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(ips, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, ips)
+			}
+		})
+	}
+}
+
+func TestNewDNSQuery(t *testing.T) {
+	msg, err := newDNSQuery("gpcm.openspy.net.", dnsTypeAAAA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		0, 0, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+		4, 'g', 'p', 'c', 'm', 7, 'o', 'p', 'e', 'n', 's', 'p', 'y', 3, 'n', 'e', 't', 0,
+		0, dnsTypeAAAA, 0, dnsClassIN,
+	}
+	if !reflect.DeepEqual(msg, expected) {
+		t.Errorf("expected %v, got %v", expected, msg)
+	}
+
+	if _, err = newDNSQuery("gpcm..net", dnsTypeA); err == nil {
+		t.Error("expected error for empty label")
+	}
+}
+
+func TestDoHResolver(t *testing.T) {
+	const host = "gpcm.openspy.net"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != dohContentType {
+			http.Error(w, "unexpected content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		query, err := io.ReadAll(r.Body)
+		if err != nil || len(query) < dnsHeaderLength+4 {
+			http.Error(w, "malformed query", http.StatusBadRequest)
+			return
+		}
+
+		var records []dnsRecord
+		switch binary.BigEndian.Uint16(query[len(query)-4:]) {
+		case dnsTypeA:
+			records = append(records, dnsRecord{rrType: dnsTypeA, data: []byte{192, 0, 2, 1}})
+		case dnsTypeAAAA:
+			records = append(records, dnsRecord{rrType: dnsTypeAAAA, data: net.ParseIP("2001:db8::1")})
+		}
+		w.Header().Set("Content-Type", dohContentType)
+		_, _ = w.Write(buildDNSResponse(t, host, binary.BigEndian.Uint16(query[len(query)-4:]), 0, records...))
+	}))
+	defer server.Close()
+
+	resolver := NewDoHResolver(server.URL, server.Client())
+	ips, err := resolver.LookupIP(context.Background(), "ip", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// IPv4 addresses are preferred
+	expected := []net.IP{{192, 0, 2, 1}, net.ParseIP("2001:db8::1")}
+	if !reflect.DeepEqual(ips, expected) {
+		t.Errorf("expected %v, got %v", expected, ips)
+	}
+
+	if _, err = resolver.LookupIP(context.Background(), "tcp", host); err == nil {
+		t.Error("expected error for unsupported network")
+	}
+}
+
+func TestDoHResolverNotFound(t *testing.T) {
+	const host = "gpcm.example.invalid"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", dohContentType)
+		_, _ = w.Write(buildDNSResponse(t, host, dnsTypeA, dnsRcodeNXDomain))
+	}))
+	defer server.Close()
+
+	_, err := NewDoHResolver(server.URL, server.Client()).LookupIP(context.Background(), "ip4", host)
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("expected not found DNS error, got %v", err)
+	}
+}
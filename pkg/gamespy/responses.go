@@ -0,0 +1,199 @@
+package gamespy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+// Success value of the result codes of check ("cur") and newuser ("nur") responses
+const resultCodeOK = "0"
+
+// ResponseError is returned if a provider's response is malformed or incomplete (as opposed to the provider
+// reporting an error, see ProviderError)
+type ResponseError struct {
+	// Type of the response, e.g. "nicks" or "login"
+	Response string
+	Reason   string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("invalid %s response: %s", e.Response, e.Reason)
+}
+
+// decodeProviderError returns the error reported in the response, or nil if there is none
+func decodeProviderError(res *gamespy.Packet) error {
+	if errmsg, exists := res.Lookup("errmsg"); exists {
+		return &ProviderError{Code: res.Get("err"), Message: errmsg}
+	}
+	return nil
+}
+
+// splitRecords splits the elements of a response listing several records (e.g. nicks) into records, each starting
+// with the first key. Elements preceding the first record are returned as header.
+func splitRecords(res *gamespy.Packet, first string) (header []gamespy.KeyValuePair, records [][]gamespy.KeyValuePair) {
+	res.Do(func(element gamespy.KeyValuePair) {
+		switch {
+		case element.Key == first:
+			records = append(records, []gamespy.KeyValuePair{element})
+		case len(records) == 0:
+			header = append(header, element)
+		default:
+			records[len(records)-1] = append(records[len(records)-1], element)
+		}
+	})
+	return header, records
+}
+
+func lookupElement(elements []gamespy.KeyValuePair, key string) (string, bool) {
+	for _, element := range elements {
+		if element.Key == key {
+			return element.Value, true
+		}
+	}
+	return "", false
+}
+
+// decodeNicksResponse decodes a response to a nicks request ("\nr\<count>\nick\...\uniquenick\...\ndone\"), checking
+// that it contains as many nicks as it announces
+func decodeNicksResponse(res *gamespy.Packet) ([]NickDTO, error) {
+	if err := decodeProviderError(res); err != nil {
+		return nil, err
+	}
+
+	header, records := splitRecords(res, "nick")
+	nicks := make([]NickDTO, 0, len(records))
+	for _, record := range records {
+		nick, _ := lookupElement(record, "nick")
+		if nick == "" {
+			return nil, &ResponseError{Response: "nicks", Reason: "nick is empty"}
+		}
+		uniqueNick, _ := lookupElement(record, "uniquenick")
+		nicks = append(nicks, NickDTO{Nick: nick, UniqueNick: uniqueNick})
+	}
+
+	// Not all providers announce the number of nicks, but if they do, it must match (else the response is truncated)
+	if count, ok := lookupElement(header, "nr"); ok && count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, &ResponseError{Response: "nicks", Reason: fmt.Sprintf("invalid number of nicks: %q", count)}
+		}
+		if n != len(nicks) {
+			return nil, &ResponseError{Response: "nicks", Reason: fmt.Sprintf("announced %d nicks, but contains %d", n, len(nicks))}
+		}
+	}
+
+	return nicks, nil
+}
+
+// decodeSearchResponse decodes a response to a search request ("\bsr\<profileid>\nick\...\uniquenick\...\bsrdone\")
+func decodeSearchResponse(res *gamespy.Packet) ([]ProfileDTO, error) {
+	if err := decodeProviderError(res); err != nil {
+		return nil, err
+	}
+
+	_, records := splitRecords(res, "bsr")
+	profiles := make([]ProfileDTO, 0, len(records))
+	for _, record := range records {
+		profileID, _ := lookupElement(record, "bsr")
+		if _, err := strconv.ParseUint(profileID, 10, 32); err != nil {
+			return nil, &ResponseError{Response: "search", Reason: fmt.Sprintf("profile id is not a number: %q", profileID)}
+		}
+		nick, _ := lookupElement(record, "nick")
+		uniqueNick, _ := lookupElement(record, "uniquenick")
+		profiles = append(profiles, ProfileDTO{ProfileID: profileID, Nick: nick, UniqueNick: uniqueNick})
+	}
+
+	return profiles, nil
+}
+
+// decodeCheckResponse decodes a response to a check request ("\cur\<result>\pid\<profileid>\")
+func decodeCheckResponse(res *gamespy.Packet) (CheckDTO, error) {
+	if err := decodeProviderError(res); err != nil {
+		return CheckDTO{}, err
+	}
+
+	result, exists := res.Lookup("cur")
+	if !exists {
+		return CheckDTO{}, &ResponseError{Response: "check", Reason: "result is missing"}
+	}
+	// Failed checks report the error code as result
+	if result != resultCodeOK {
+		return CheckDTO{}, &ProviderError{Code: result, Message: "profile check failed"}
+	}
+
+	profileID, err := decodeID(res, "check", "pid")
+	if err != nil {
+		return CheckDTO{}, err
+	}
+
+	return CheckDTO{ProfileID: profileID}, nil
+}
+
+// decodeNewUserResponse decodes a response to a newuser request ("\nur\<result>\userid\...\profileid\...\id\...\")
+func decodeNewUserResponse(res *gamespy.Packet) (NewUserDTO, error) {
+	if err := decodeProviderError(res); err != nil {
+		return NewUserDTO{}, err
+	}
+
+	// The account has been created unless the provider reports an error, so responses are never rejected for only
+	// lacking details (which would make callers retry creating an existing account)
+	if result := res.Get("nur"); result != "" && result != resultCodeOK {
+		return NewUserDTO{}, &ProviderError{Code: result, Message: "account creation failed"}
+	}
+
+	// Some providers only confirm the creation without returning (all) IDs
+	var dto NewUserDTO
+	var err error
+	if _, ok := res.Lookup("userid"); ok {
+		if dto.UserID, err = decodeID(res, "newuser", "userid"); err != nil {
+			return NewUserDTO{}, err
+		}
+	}
+	for _, key := range []string{"profileid", "pid"} {
+		if _, ok := res.Lookup(key); ok {
+			if dto.ProfileID, err = decodeID(res, "newuser", key); err != nil {
+				return NewUserDTO{}, err
+			}
+			break
+		}
+	}
+
+	return dto, nil
+}
+
+// decodeLoginResponse decodes a response to a login request ("\lc\2\sesskey\...\proof\...\userid\...\profileid\...\").
+// The proof is returned separately, since it needs to be verified by the caller.
+func decodeLoginResponse(res *gamespy.Packet) (login LoginDTO, proof string, sessKey string, err error) {
+	if err = decodeProviderError(res); err != nil {
+		return LoginDTO{}, "", "", err
+	}
+
+	proof, exists := res.Lookup("proof")
+	if !exists || proof == "" {
+		return LoginDTO{}, "", "", &ResponseError{Response: "login", Reason: "proof is missing"}
+	}
+
+	if login.UserID, err = decodeID(res, "login", "userid"); err != nil {
+		return LoginDTO{}, "", "", err
+	}
+	if login.ProfileID, err = decodeID(res, "login", "profileid"); err != nil {
+		return LoginDTO{}, "", "", err
+	}
+	login.UniqueNick = res.Get("uniquenick")
+
+	return login, proof, res.Get("sesskey"), nil
+}
+
+// decodeID returns the numeric ID stored under key
+func decodeID(res *gamespy.Packet, response string, key string) (string, error) {
+	id, exists := res.Lookup(key)
+	if !exists || id == "" {
+		return "", &ResponseError{Response: response, Reason: fmt.Sprintf("%s is missing", key)}
+	}
+	if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+		return "", &ResponseError{Response: response, Reason: fmt.Sprintf("%s is not a number: %q", key, id)}
+	}
+	return id, nil
+}
@@ -0,0 +1,253 @@
+package gamespy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func mustParsePacket(t *testing.T, raw string) *gamespy.Packet {
+	t.Helper()
+	packet, err := gamespy.NewPacketFromBytes([]byte(raw))
+	if err != nil {
+		t.Fatalf("failed to parse packet %q: %s", raw, err)
+	}
+	return packet
+}
+
+// checkDecodeError verifies that err is a *ResponseError (wantResponseErr), a *ProviderError with the given code
+// (wantProviderCode) or nil
+func checkDecodeError(t *testing.T, err error, wantResponseErr bool, wantProviderCode string) {
+	t.Helper()
+	var responseErr *ResponseError
+	var providerErr *ProviderError
+	switch {
+	case wantResponseErr:
+		if !errors.As(err, &responseErr) {
+			t.Errorf("expected *ResponseError, got %v", err)
+		}
+	case wantProviderCode != "":
+		if !errors.As(err, &providerErr) || providerErr.Code != wantProviderCode {
+			t.Errorf("expected *ProviderError with code %s, got %v", wantProviderCode, err)
+		}
+	case err != nil:
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestDecodeNicksResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expected         []NickDTO
+		wantResponseErr  bool
+		wantProviderCode string
+	}{
+		{
+			name: "decodes nicks",
+			raw:  `\nr\2\nick\mister249\uniquenick\mister249\nick\[ABC]mister249\uniquenick\[ABC]mister249\ndone\\final\`,
+			expected: []NickDTO{
+				{Nick: "mister249", UniqueNick: "mister249"},
+				{Nick: "[ABC]mister249", UniqueNick: "[ABC]mister249"},
+			},
+		},
+		{
+			name:     "decodes nicks without announced count",
+			raw:      `\nr\\nick\mister249\uniquenick\mister249\ndone\\final\`,
+			expected: []NickDTO{{Nick: "mister249", UniqueNick: "mister249"}},
+		},
+		{
+			name:     "decodes empty response",
+			raw:      `\nr\0\ndone\\final\`,
+			expected: []NickDTO{},
+		},
+		{
+			name:             "returns provider error",
+			raw:              `\error\\err\260\fatal\\errmsg\The password provided is incorrect.\id\1\final\`,
+			wantProviderCode: ErrCodeLoginBadPassword,
+		},
+		{
+			name:            "rejects truncated response",
+			raw:             `\nr\3\nick\mister249\uniquenick\mister249\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects invalid count",
+			raw:             `\nr\two\nick\mister249\uniquenick\mister249\ndone\\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects empty nick",
+			raw:             `\nr\1\nick\\uniquenick\mister249\ndone\\final\`,
+			wantResponseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nicks, err := decodeNicksResponse(mustParsePacket(t, tt.raw))
+			checkDecodeError(t, err, tt.wantResponseErr, tt.wantProviderCode)
+			if err == nil && !reflect.DeepEqual(nicks, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, nicks)
+			}
+		})
+	}
+}
+
+func TestDecodeSearchResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expected         []ProfileDTO
+		wantResponseErr  bool
+		wantProviderCode string
+	}{
+		{
+			name: "decodes profiles",
+			raw:  `\bsr\500123\nick\mister249\uniquenick\mister249\bsr\500124\nick\mister250\uniquenick\mister250\bsrdone\\final\`,
+			expected: []ProfileDTO{
+				{ProfileID: "500123", Nick: "mister249", UniqueNick: "mister249"},
+				{ProfileID: "500124", Nick: "mister250", UniqueNick: "mister250"},
+			},
+		},
+		{
+			name:     "decodes empty result",
+			raw:      `\bsrdone\\final\`,
+			expected: []ProfileDTO{},
+		},
+		{
+			name:             "returns provider error",
+			raw:              `\error\\err\0\errmsg\Search failed\final\`,
+			wantProviderCode: ErrCodeGeneral,
+		},
+		{
+			name:            "rejects non-numeric profile id",
+			raw:             `\bsr\abc\nick\mister249\uniquenick\mister249\bsrdone\\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects truncated record",
+			raw:             `\bsr\\final\`,
+			wantResponseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := decodeSearchResponse(mustParsePacket(t, tt.raw))
+			checkDecodeError(t, err, tt.wantResponseErr, tt.wantProviderCode)
+			if err == nil && !reflect.DeepEqual(profiles, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, profiles)
+			}
+		})
+	}
+}
+
+func TestDecodeCheckResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expected         CheckDTO
+		wantResponseErr  bool
+		wantProviderCode string
+	}{
+		{
+			name:     "decodes profile id",
+			raw:      `\cur\0\pid\500123\final\`,
+			expected: CheckDTO{ProfileID: "500123"},
+		},
+		{
+			name:             "returns failed result as provider error",
+			raw:              `\cur\261\final\`,
+			wantProviderCode: ErrCodeLoginBadProfile,
+		},
+		{
+			name:             "returns provider error",
+			raw:              `\error\\err\4\errmsg\Database error\final\`,
+			wantProviderCode: ErrCodeDatabase,
+		},
+		{
+			name:            "rejects missing result",
+			raw:             `\pid\500123\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects truncated response",
+			raw:             `\cur\0\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects non-numeric profile id",
+			raw:             `\cur\0\pid\-1\final\`,
+			wantResponseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto, err := decodeCheckResponse(mustParsePacket(t, tt.raw))
+			checkDecodeError(t, err, tt.wantResponseErr, tt.wantProviderCode)
+			if err == nil && dto != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, dto)
+			}
+		})
+	}
+}
+
+func TestDecodeNewUserResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expected         NewUserDTO
+		wantResponseErr  bool
+		wantProviderCode string
+	}{
+		{
+			name:     "decodes ids",
+			raw:      `\nur\0\userid\200123\profileid\500123\id\1\final\`,
+			expected: NewUserDTO{UserID: "200123", ProfileID: "500123"},
+		},
+		{
+			name:     "decodes pid as profile id",
+			raw:      `\nur\0\pid\500123\id\1\final\`,
+			expected: NewUserDTO{ProfileID: "500123"},
+		},
+		{
+			name:     "accepts confirmation without ids",
+			raw:      `\nur\\id\1\final\`,
+			expected: NewUserDTO{},
+		},
+		{
+			name:             "returns failed result as provider error",
+			raw:              `\nur\516\id\1\final\`,
+			wantProviderCode: ErrCodeNewUserUniqueNickUsed,
+		},
+		{
+			name:             "returns provider error",
+			raw:              `\error\\err\516\fatal\\errmsg\This unique nick is already in use.\id\1\final\`,
+			wantProviderCode: ErrCodeNewUserUniqueNickUsed,
+		},
+		{
+			name:            "rejects truncated user id",
+			raw:             `\nur\0\userid\\final\`,
+			wantResponseErr: true,
+		},
+		{
+			name:            "rejects non-numeric profile id",
+			raw:             `\nur\0\userid\200123\profileid\x\final\`,
+			wantResponseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto, err := decodeNewUserResponse(mustParsePacket(t, tt.raw))
+			checkDecodeError(t, err, tt.wantResponseErr, tt.wantProviderCode)
+			if err == nil && dto != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, dto)
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package gamespy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+	"go.uber.org/multierr"
+)
+
+const (
+	// Interval at which keep-alive packets are sent to keep the provider from dropping idle sessions
+	keepAliveInterval = 30 * time.Second
+	// Time idle sessions are kept for reuse by Client
+	sessionIdleTimeout = 2 * time.Minute
+	// Number of received packets buffered until they are waited for
+	sessionPacketBuffer = 32
+)
+
+// ErrSessionClosed is returned when using a Session which has been closed (by either side)
+var ErrSessionClosed = errors.New("session is closed")
+
+// ErrAlreadyLoggedIn is returned when logging in using a Session which has already been used to log in
+var ErrAlreadyLoggedIn = errors.New("session is already logged in")
+
+// ErrNotLoggedIn is returned when using a Session for an operation which requires logging in first
+var ErrNotLoggedIn = errors.New("session is not logged in")
+
+// Session is a connection to a provider's presence (gpcm) server, which can be used for multiple operations (e.g.
+// creating several users before logging in) instead of connecting for each of them. Keep-alive packets are sent while
+// the session is open. Operations on a session must not be run concurrently.
+type Session struct {
+	provider  Provider
+	conn      net.Conn
+	challenge string
+	// Time limits for reading a response and writing a request
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	log          packetLog
+
+	packets chan *gamespy.Packet
+	// Closed once the connection has been closed (and thus no more packets will be received)
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	writeMu   sync.Mutex
+
+	nextID int
+
+	// loginMu guards loggedIn, sessKey and profileID, which Close reads from the receive and keep-alive goroutines
+	loginMu   sync.Mutex
+	loggedIn  bool
+	sessKey   string
+	profileID string
+}
+
+// OpenSession connects to the provider's presence server and reads its login challenge. The session must be closed
+// once it is no longer needed.
+func (c *Client) OpenSession(provider Provider) (*Session, error) {
+	return c.OpenSessionContext(context.Background(), provider)
+}
+
+func (c *Client) OpenSessionContext(ctx context.Context, provider Provider) (*Session, error) {
+	conn, err := c.dial(ctx, getHostname(provider, serviceGPCM), portGPCM)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newSession(ctx, provider, conn)
+}
+
+// newSession starts receiving packets from conn and reads the login challenge prompt
+func (c *Client) newSession(ctx context.Context, provider Provider, conn net.Conn) (*Session, error) {
+	s := &Session{
+		provider:     provider,
+		conn:         conn,
+		readTimeout:  c.readTimeout,
+		writeTimeout: c.writeTimeout,
+		log:          c.packetLog(provider),
+		packets:      make(chan *gamespy.Packet, sessionPacketBuffer),
+		done:         make(chan struct{}),
+		nextID:       1,
+	}
+	go s.receive()
+
+	// Login challenge prompt is sent immediately upon connecting
+	prompt, err := s.wait(ctx, func(packet *gamespy.Packet) bool {
+		_, isPrompt := packet.Lookup("challenge")
+		return isPrompt
+	})
+	if err != nil {
+		return nil, multierr.Append(fmt.Errorf("failed to read login challenge prompt: %w", err), s.Close())
+	}
+	challenge, exists := prompt.Lookup("challenge")
+	if !exists {
+		return nil, multierr.Append(fmt.Errorf("login challenge prompt does not contain a challenge"), s.Close())
+	}
+	s.challenge = challenge
+
+	go s.keepAlive()
+
+	return s, nil
+}
+
+// idleSession is a session kept for reuse, which is closed if it is not reused in time
+type idleSession struct {
+	session *Session
+	timer   *time.Timer
+}
+
+// takeSession returns the idle session to the provider (if there is one which is still open), else opens a new one
+func (c *Client) takeSession(ctx context.Context, provider Provider) (*Session, error) {
+	c.sessionsMu.Lock()
+	idle, ok := c.sessions[provider]
+	delete(c.sessions, provider)
+	c.sessionsMu.Unlock()
+
+	if ok && idle.timer.Stop() && !idle.session.Closed() {
+		// Packets left over from earlier operations or received while idle (e.g. late errors, which are sent without
+		// an id) would otherwise be taken as responses to the next request
+		idle.session.drain()
+		return idle.session, nil
+	}
+
+	return c.OpenSessionContext(ctx, provider)
+}
+
+// releaseSession keeps the session for reuse by the next operation, closing it after sessionIdleTimeout
+func (c *Client) releaseSession(s *Session) {
+	if s.Closed() || s.LoggedIn() {
+		_ = s.Close()
+		return
+	}
+
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	// Only keep one idle session per provider
+	if _, exists := c.sessions[s.provider]; exists {
+		_ = s.Close()
+		return
+	}
+
+	idle := &idleSession{session: s}
+	idle.timer = time.AfterFunc(sessionIdleTimeout, func() {
+		c.sessionsMu.Lock()
+		if c.sessions[s.provider] == idle {
+			delete(c.sessions, s.provider)
+		}
+		c.sessionsMu.Unlock()
+		_ = s.Close()
+	})
+	c.sessions[s.provider] = idle
+}
+
+// Close closes any idle presence sessions kept for reuse. The client can still be used afterwards.
+func (c *Client) Close() error {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	var err error
+	for provider, idle := range c.sessions {
+		idle.timer.Stop()
+		err = multierr.Append(err, idle.session.Close())
+		delete(c.sessions, provider)
+	}
+	return err
+}
+
+// Provider returns the provider the session is connected to
+func (s *Session) Provider() Provider {
+	return s.provider
+}
+
+// Closed returns whether the session has been closed (by either side)
+func (s *Session) Closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// LoggedIn returns whether the session has been used to log in
+func (s *Session) LoggedIn() bool {
+	loggedIn, _, _ := s.loginState()
+	return loggedIn
+}
+
+// loginState returns whether the session has been used to log in, along with the session key and profile id of the
+// login
+func (s *Session) loginState() (bool, string, string) {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.loggedIn, s.sessKey, s.profileID
+}
+
+// Close closes the connection, logging out if the session is logged in
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		if loggedIn, sessKey, _ := s.loginState(); loggedIn && !s.Closed() {
+			logout := new(gamespy.Packet)
+			logout.Add("logout", "")
+			logout.Add("sesskey", sessKey)
+			// Closing the connection logs out as well, so logging out is a courtesy only
+			_ = s.write(context.Background(), logout)
+		}
+		s.closeErr = disconnect(s.conn)
+		close(s.done)
+	})
+	return s.closeErr
+}
+
+// CreateUser creates an account with the given email address and password, using nick as both nick and uniquenick
+func (s *Session) CreateUser(email, password, nick string) error {
+	return s.CreateUserContext(context.Background(), email, password, nick)
+}
+
+func (s *Session) CreateUserContext(ctx context.Context, email, password, nick string) error {
+	_, err := s.NewUserContext(ctx, email, password, nick)
+	return err
+}
+
+// NewUser creates the user like CreateUser, returning the IDs of the created account and profile (if the provider
+// returns them)
+func (s *Session) NewUser(email, password, nick string) (NewUserDTO, error) {
+	return s.NewUserContext(context.Background(), email, password, nick)
+}
+
+func (s *Session) NewUserContext(ctx context.Context, email, password, nick string) (NewUserDTO, error) {
+	user, _, err := s.createUser(ctx, email, password, nick)
+	return user, err
+}
+
+// createUser creates the user like NewUserContext, additionally returning whether the request has been
+// (attempted to be) sent, after which the user may have been created even if an error is returned
+func (s *Session) createUser(ctx context.Context, email, password, nick string) (NewUserDTO, bool, error) {
+	if s.Closed() {
+		return NewUserDTO{}, false, ErrSessionClosed
+	}
+
+	id := s.requestID()
+	signup := new(gamespy.Packet)
+	signup.Add("newuser", "")
+	signup.Add("email", email)
+	signup.Add("nick", nick)
+	signup.Add("passwordenc", gamespy.EncodePassword(password))
+	signup.Add("productid", productID)
+	signup.Add("gamename", gameName)
+	signup.Add("namespaceid", namespaceID)
+	signup.Add("uniquenick", nick)
+	signup.Add("id", id)
+
+	res, err := s.request(ctx, signup, id)
+	if err != nil {
+		return NewUserDTO{}, true, err
+	}
+
+	user, err := decodeNewUserResponse(res)
+	return user, true, err
+}
+
+// Login performs a full presence login (as the game does when logging in) using the uniquenick and password, verifying
+// the proof sent by the provider. A session can only be used to log in once.
+func (s *Session) Login(uniqueNick, password string) (LoginDTO, error) {
+	return s.LoginContext(context.Background(), uniqueNick, password)
+}
+
+func (s *Session) LoginContext(ctx context.Context, uniqueNick, password string) (LoginDTO, error) {
+	login, err := s.login(ctx, "uniquenick", uniqueNick, password)
+	if err != nil {
+		return LoginDTO{}, err
+	}
+
+	// Not all providers echo the uniquenick
+	if login.UniqueNick == "" {
+		login.UniqueNick = uniqueNick
+	}
+
+	return login, nil
+}
+
+// login logs in as user, which is either a uniquenick (key "uniquenick") or "<nick>@<email>" (key "user")
+func (s *Session) login(ctx context.Context, key string, user string, password string) (LoginDTO, error) {
+	if s.LoggedIn() {
+		return LoginDTO{}, ErrAlreadyLoggedIn
+	}
+
+	clientChallenge := gamespy.RandString(clientChallengeLength)
+	hash := gamespy.ComputeMD5(password)
+
+	id := s.requestID()
+	req := new(gamespy.Packet)
+	req.Add("login", "")
+	req.Add("challenge", clientChallenge)
+	req.Add(key, user)
+	req.Add("response", gamespy.GenerateProof(user, hash, clientChallenge, s.challenge))
+	req.Add("productid", productID)
+	req.Add("gamename", gameName)
+	req.Add("namespaceid", namespaceID)
+	req.Add("sdkrevision", sdkRevision)
+	req.Add("id", id)
+
+	res, err := s.request(ctx, req, id)
+	if err != nil {
+		return LoginDTO{}, err
+	}
+
+	login, proof, sessKey, err := decodeLoginResponse(res)
+	if err != nil {
+		return LoginDTO{}, err
+	}
+
+	// The proof shows that the provider knows the password as well, so it is not just accepting any login
+	if proof != gamespy.GenerateProof(user, hash, s.challenge, clientChallenge) {
+		return LoginDTO{}, fmt.Errorf("provider sent an invalid login proof")
+	}
+	s.loginMu.Lock()
+	s.loggedIn = true
+	s.sessKey = sessKey
+	s.profileID = login.ProfileID
+	s.loginMu.Unlock()
+
+	return login, nil
+}
+
+// ChangePassword changes the password of the account logged in to. Providers do not confirm the change, so callers
+// should verify it by using the new password (e.g. with GetNicks).
+func (s *Session) ChangePassword(newPassword string) error {
+	return s.ChangePasswordContext(context.Background(), newPassword)
+}
+
+func (s *Session) ChangePasswordContext(ctx context.Context, newPassword string) error {
+	return s.updateAccount(ctx, "passwordenc", gamespy.EncodePassword(newPassword))
+}
+
+// ChangeEmail changes the email address of the account logged in to. Not all providers allow changing the email
+// address. Providers do not confirm the change, so callers should verify it by using the new email address (e.g. with
+// GetNicks).
+func (s *Session) ChangeEmail(newEmail string) error {
+	return s.ChangeEmailContext(context.Background(), newEmail)
+}
+
+func (s *Session) ChangeEmailContext(ctx context.Context, newEmail string) error {
+	return s.updateAccount(ctx, "email", newEmail)
+}
+
+// updateAccount sets the account detail stored under key (as the game does when updating the account's details)
+func (s *Session) updateAccount(ctx context.Context, key string, value string) error {
+	loggedIn, sessKey, profileID := s.loginState()
+	if !loggedIn {
+		return ErrNotLoggedIn
+	}
+
+	update := new(gamespy.Packet)
+	update.Add("updateui", "")
+	update.Add("sesskey", sessKey)
+	update.Add(key, value)
+	if err := s.write(ctx, update); err != nil {
+		return multierr.Append(fmt.Errorf("failed to send request: %w", err), s.Close())
+	}
+
+	// Updates are only answered if they fail, so request the profile to know when any error would have been received
+	// (errors are sent without an id, so they are matched as the response)
+	id := s.requestID()
+	req := new(gamespy.Packet)
+	req.Add("getprofile", "")
+	req.Add("sesskey", sessKey)
+	req.Add("profileid", profileID)
+	req.Add("id", id)
+
+	res, err := s.request(ctx, req, id)
+	if err != nil {
+		return err
+	}
+
+	return decodeProviderError(res)
+}
+
+func (s *Session) requestID() string {
+	id := strconv.Itoa(s.nextID)
+	s.nextID++
+	return id
+}
+
+// request sends the packet and waits for the response with the given id. The session is closed if the request is
+// interrupted, since responses could no longer be matched to requests reliably.
+func (s *Session) request(ctx context.Context, packet *gamespy.Packet, id string) (*gamespy.Packet, error) {
+	if err := s.write(ctx, packet); err != nil {
+		return nil, multierr.Append(fmt.Errorf("failed to send request: %w", err), s.Close())
+	}
+
+	res, err := s.wait(ctx, func(packet *gamespy.Packet) bool {
+		return isResponse(packet, id)
+	})
+	if err != nil {
+		return nil, multierr.Append(fmt.Errorf("failed to read response: %w", err), s.Close())
+	}
+
+	return res, nil
+}
+
+func (s *Session) write(ctx context.Context, packet *gamespy.Packet) error {
+	if s.Closed() {
+		return ErrSessionClosed
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return contextErr(ctx, write(ctx, s.conn, s.writeTimeout, packet, s.log))
+}
+
+// wait returns the next received packet matching match, skipping any unrelated packets (e.g. buddy messages)
+func (s *Session) wait(ctx context.Context, match func(packet *gamespy.Packet) bool) (*gamespy.Packet, error) {
+	timeout := time.NewTimer(time.Until(deadline(ctx, s.readTimeout)))
+	defer timeout.Stop()
+
+	for {
+		select {
+		case packet := <-s.packets:
+			if match(packet) {
+				return packet, nil
+			}
+		case <-s.done:
+			// Packets received before the connection was closed may still contain the response
+			for {
+				select {
+				case packet := <-s.packets:
+					if match(packet) {
+						return packet, nil
+					}
+				default:
+					return nil, ErrSessionClosed
+				}
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, fmt.Errorf("timed out waiting for response")
+		}
+	}
+}
+
+// drain discards any received packets nobody has waited for
+func (s *Session) drain() {
+	for {
+		select {
+		case packet := <-s.packets:
+			s.logDropped(packet.Bytes())
+		default:
+			return
+		}
+	}
+}
+
+// isResponse returns whether the packet is the response to the request with the given id
+func isResponse(packet *gamespy.Packet, id string) bool {
+	packetID, hasID := packet.Lookup("id")
+	_, isError := packet.Lookup("error")
+	// Errors are not always sent with the id of the request they refer to
+	return packetID == id || (isError && !hasID)
+}
+
+// receive reads packets until the connection is closed, passing them on to wait (without keep-alive packets)
+func (s *Session) receive() {
+	defer func() {
+		_ = s.Close()
+	}()
+
+	r := newPacketReader(s.conn)
+	for {
+		raw, err := r.next()
+		if err != nil {
+			return
+		}
+		if s.log != nil {
+			s.log(PacketReceived, raw)
+		}
+
+		packet, err := gamespy.NewPacketFromBytes(raw)
+		if err != nil {
+			continue
+		}
+		if _, isKeepAlive := packet.Lookup("ka"); isKeepAlive {
+			continue
+		}
+
+		select {
+		case s.packets <- packet:
+		default:
+			// Drop unsolicited packets nobody waits for rather than blocking reads
+			s.logDropped(raw)
+		}
+	}
+}
+
+func (s *Session) logDropped(raw []byte) {
+	if s.log != nil {
+		s.log(PacketDropped, raw)
+	}
+}
+
+// keepAlive sends keep-alive packets until the session is closed
+func (s *Session) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			packet := new(gamespy.Packet)
+			packet.Add("ka", "")
+			if err := s.write(context.Background(), packet); err != nil {
+				_ = s.Close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
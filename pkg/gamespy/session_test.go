@@ -0,0 +1,212 @@
+package gamespy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const testServerChallenge = "ABCDEFGHIJ"
+
+// loginServer plays the presence server side of a login on conn, closing the connection right after responding (so
+// the session is closed by its receive goroutine while the login is still being completed)
+func loginServer(t *testing.T, conn net.Conn, password string, validProof bool) {
+	defer conn.Close()
+
+	prompt := new(gamespy.Packet)
+	prompt.Add("lc", "1")
+	prompt.Add("challenge", testServerChallenge)
+	prompt.Add("id", "1")
+	if _, err := conn.Write(prompt.Bytes()); err != nil {
+		return
+	}
+
+	raw, err := newPacketReader(conn).next()
+	if err != nil {
+		return
+	}
+	req, err := gamespy.NewPacketFromBytes(raw)
+	if err != nil {
+		t.Errorf("failed to parse login request: %v", err)
+		return
+	}
+
+	user, clientChallenge := req.Get("uniquenick"), req.Get("challenge")
+	hash := gamespy.ComputeMD5(password)
+	if req.Get("response") != gamespy.GenerateProof(user, hash, clientChallenge, testServerChallenge) {
+		t.Errorf("login request contains an invalid response")
+	}
+
+	proof := gamespy.GenerateProof(user, hash, testServerChallenge, clientChallenge)
+	if !validProof {
+		proof = gamespy.ComputeMD5("not-the-proof")
+	}
+	res := new(gamespy.Packet)
+	res.Add("lc", "2")
+	res.Add("sesskey", "123456")
+	res.Add("proof", proof)
+	res.Add("userid", "100")
+	res.Add("profileid", "200")
+	res.Add("uniquenick", user)
+	res.Add("id", req.Get("id"))
+	_, _ = conn.Write(res.Bytes())
+}
+
+func TestSessionLogin(t *testing.T) {
+	type test struct {
+		name             string
+		validProof       bool
+		wantErr          bool
+		expectedLoggedIn bool
+	}
+
+	tests := []test{
+		{
+			name:             "logs in with valid proof",
+			validProof:       true,
+			expectedLoggedIn: true,
+		},
+		{
+			name:       "errors for invalid proof",
+			validProof: false,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			client, server := net.Pipe()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				loginServer(t, server, "secret", tt.validProof)
+			}()
+
+			c := NewClientWithOptions(ClientOptions{Timeout: 5 * time.Second})
+			s, err := c.newSession(context.Background(), ProviderBF2Hub, client)
+			if err != nil {
+				t.Fatalf("failed to open session: %v", err)
+			}
+
+			// WHEN
+			login, err := s.Login("mister249", "secret")
+
+			// THEN
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if login.UserID != "100" || login.ProfileID != "200" || login.UniqueNick != "mister249" {
+					t.Errorf("unexpected login: %+v", login)
+				}
+			}
+			if loggedIn := s.LoggedIn(); loggedIn != tt.expectedLoggedIn {
+				t.Errorf("expected LoggedIn() to be %t, got %t", tt.expectedLoggedIn, loggedIn)
+			}
+
+			// Closing again must not race with the close triggered by the provider dropping the connection
+			_ = s.Close()
+			<-done
+			if !s.Closed() {
+				t.Errorf("expected session to be closed")
+			}
+		})
+	}
+}
+
+func TestSessionUpdateAccountNotLoggedIn(t *testing.T) {
+	// GIVEN
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer server.Close()
+		prompt := new(gamespy.Packet)
+		prompt.Add("lc", "1")
+		prompt.Add("challenge", testServerChallenge)
+		prompt.Add("id", "1")
+		_, _ = server.Write(prompt.Bytes())
+	}()
+
+	c := NewClientWithOptions(ClientOptions{Timeout: 5 * time.Second})
+	s, err := c.newSession(context.Background(), ProviderBF2Hub, client)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer s.Close()
+
+	// WHEN
+	err = s.ChangePassword("new-secret")
+
+	// THEN
+	if !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("expected ErrNotLoggedIn, got %v", err)
+	}
+	<-done
+}
+
+func TestTakeSessionDrainsStalePackets(t *testing.T) {
+	// GIVEN
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		prompt := new(gamespy.Packet)
+		prompt.Add("lc", "1")
+		prompt.Add("challenge", testServerChallenge)
+		prompt.Add("id", "1")
+		_, _ = server.Write(prompt.Bytes())
+
+		// Late error of an earlier operation, which (lacking an id) would match the next request
+		stale := new(gamespy.Packet)
+		stale.Add("error", "")
+		stale.Add("err", "0")
+		stale.Add("errmsg", "stale")
+		_, _ = server.Write(stale.Bytes())
+	}()
+
+	var dropped int
+	c := NewClientWithOptions(ClientOptions{
+		Timeout: 5 * time.Second,
+		LogPacket: func(_ Provider, direction PacketDirection, _ string) {
+			if direction == PacketDropped {
+				dropped++
+			}
+		},
+	})
+	defer c.Close()
+	s, err := c.newSession(context.Background(), ProviderBF2Hub, client)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	for len(s.packets) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.releaseSession(s)
+
+	// WHEN
+	taken, err := c.takeSession(context.Background(), ProviderBF2Hub)
+
+	// THEN
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if taken != s {
+		t.Fatalf("expected idle session to be reused")
+	}
+	if n := len(taken.packets); n != 0 {
+		t.Errorf("expected no buffered packets, got %d", n)
+	}
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped packet to be logged, got %d", dropped)
+	}
+	_ = taken.Close()
+}
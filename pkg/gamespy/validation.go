@@ -0,0 +1,71 @@
+package gamespy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits of the GameSpy SDK (buffer sizes excluding the terminating null byte), which the game truncates longer values
+// to. Providers reimplement the GameSpy backend, so they enforce the same limits.
+const (
+	MinNickLength     = 3
+	MaxNickLength     = 20
+	MinPasswordLength = 1
+	MaxPasswordLength = 30
+	MaxEmailLength    = 50
+	// Characters a nick must not start with, reserved by the presence protocol for addressing nicks/namespaces
+	ForbiddenNickPrefixes = "@+:#"
+)
+
+// ValidationError describes why a value would be rejected (or silently mangled) by a provider
+type ValidationError struct {
+	// Name of the invalid field, e.g. "nick"
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ValidateNick checks whether providers accept the nick (as uniquenick) without rejecting or truncating it
+func ValidateNick(nick string) error {
+	if len(nick) < MinNickLength {
+		return &ValidationError{Field: "nick", Reason: fmt.Sprintf("must be at least %d characters long", MinNickLength)}
+	}
+	if len(nick) > MaxNickLength {
+		return &ValidationError{Field: "nick", Reason: fmt.Sprintf("must be at most %d characters long (longer nicks get truncated)", MaxNickLength)}
+	}
+	if nick != "" && strings.ContainsAny(nick[:1], ForbiddenNickPrefixes) {
+		return &ValidationError{Field: "nick", Reason: fmt.Sprintf("must not start with any of %s", ForbiddenNickPrefixes)}
+	}
+	for _, r := range nick {
+		// Only printable ASCII characters are supported, backslashes would break the protocol's packets
+		if r <= ' ' || r > '~' || r == '\\' {
+			return &ValidationError{Field: "nick", Reason: fmt.Sprintf("must not contain %q (only letters, digits and symbols other than backslash, no spaces)", r)}
+		}
+	}
+	return nil
+}
+
+// ValidatePassword checks whether providers accept the password without the game truncating it
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return &ValidationError{Field: "password", Reason: fmt.Sprintf("must be at least %d characters long", MinPasswordLength)}
+	}
+	if len(password) > MaxPasswordLength {
+		return &ValidationError{Field: "password", Reason: fmt.Sprintf("must be at most %d characters long (the game truncates longer passwords)", MaxPasswordLength)}
+	}
+	return nil
+}
+
+// ValidateNewUser checks whether providers accept an account with the given credentials, as created by CreateUser
+func ValidateNewUser(email, password, nick string) error {
+	if len(email) > MaxEmailLength {
+		return &ValidationError{Field: "email", Reason: fmt.Sprintf("must be at most %d characters long", MaxEmailLength)}
+	}
+	if err := ValidateNick(nick); err != nil {
+		return err
+	}
+	return ValidatePassword(password)
+}
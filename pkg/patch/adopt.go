@@ -0,0 +1,90 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/multierr"
+)
+
+// Adopt normalizes a binary patched for a known provider by another patcher to the layout used by Patch, with every
+// modified string nil-padded to its full length. Other patchers may leave remnants of longer original strings after
+// the nil-terminator, which keeps Patch from finding the expected number of strings. Returns the provider the binary
+// is patched for and whether any changes were made.
+func Adopt(patchable Patchable, dir string) (provider Provider, changed bool, err error) {
+	path := LongPath(filepath.Join(dir, patchable.GetFileName()))
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ProviderUnknown, false, ErrNotExist
+		}
+		return ProviderUnknown, false, err
+	}
+	defer multierr.AppendInvoke(&err, multierr.Close(f))
+
+	original, err := io.ReadAll(f)
+	if err != nil {
+		return ProviderUnknown, false, err
+	}
+
+	// Adopting only works if the strings of the provider can still be identified
+	provider, err = determineCurrentlyUsedProvider(original, patchable.GetFingerprints())
+	if err != nil {
+		return ProviderUnknown, false, err
+	}
+
+	modifications, err := patchable.GetModifications(provider, provider)
+	if err != nil {
+		return provider, false, err
+	}
+
+	modified := append([]byte(nil), original...)
+	for _, m := range modifications {
+		offsets := findTerminated(modified, m.New, m.Length)
+		if len(offsets) != m.Count {
+			return provider, false, fmt.Errorf("cannot adopt %q strings (found %d, expected %d): %w", m.Name, len(offsets), m.Count, ErrUnknownModifications)
+		}
+
+		for _, offset := range offsets {
+			for i := offset + len(m.New); i < offset+m.Length; i++ {
+				modified[i] = 0
+			}
+		}
+	}
+
+	if bytes.Equal(original, modified) {
+		return provider, false, nil
+	}
+
+	UpdateChecksum(modified)
+
+	if err = writeJournaled(f, path, original, modified); err != nil {
+		return provider, false, err
+	}
+
+	return provider, true, nil
+}
+
+// findTerminated returns the offsets of s in b which are nil-terminated and followed by enough bytes to pad s to length
+func findTerminated(b []byte, s []byte, length int) []int {
+	var offsets []int
+	for offset := 0; offset < len(b); {
+		i := bytes.Index(b[offset:], s)
+		if i == -1 {
+			break
+		}
+
+		start := offset + i
+		end := start + len(s)
+		if end < len(b) && b[end] == 0 && start+length <= len(b) {
+			offsets = append(offsets, start)
+		}
+
+		offset = start + 1
+	}
+
+	return offsets
+}
@@ -0,0 +1,67 @@
+package patch
+
+import (
+	"encoding/binary"
+)
+
+const (
+	peSignatureOffsetOffset = 0x3c
+	// Offset of the CheckSum field from the start of the PE signature (4 byte signature + 20 byte file header + 64)
+	peChecksumOffset = 4 + 20 + 64
+)
+
+// UpdateChecksum recalculates the PE optional header checksum of b in place.
+// Binaries without a valid PE header or without a checksum (zero) are left untouched,
+// since the loader does not verify the checksum for non-driver executables anyway.
+func UpdateChecksum(b []byte) {
+	offset, ok := getChecksumOffset(b)
+	if !ok {
+		return
+	}
+
+	if binary.LittleEndian.Uint32(b[offset:]) == 0 {
+		return
+	}
+
+	binary.LittleEndian.PutUint32(b[offset:], calculateChecksum(b, offset))
+}
+
+func getChecksumOffset(b []byte) (int, bool) {
+	if len(b) < peSignatureOffsetOffset+4 || b[0] != 'M' || b[1] != 'Z' {
+		return 0, false
+	}
+
+	peOffset := int(binary.LittleEndian.Uint32(b[peSignatureOffsetOffset:]))
+	if peOffset < 0 || peOffset+peChecksumOffset+4 > len(b) {
+		return 0, false
+	}
+
+	if b[peOffset] != 'P' || b[peOffset+1] != 'E' || b[peOffset+2] != 0 || b[peOffset+3] != 0 {
+		return 0, false
+	}
+
+	return peOffset + peChecksumOffset, true
+}
+
+// calculateChecksum implements the algorithm used by CheckSumMappedFile (imagehlp.dll),
+// treating the checksum field itself as zero
+func calculateChecksum(b []byte, checksumOffset int) uint32 {
+	var sum uint64
+	for i := 0; i < len(b); i += 2 {
+		var word uint64
+		if i >= checksumOffset && i < checksumOffset+4 {
+			word = 0
+		} else if i+1 < len(b) {
+			word = uint64(binary.LittleEndian.Uint16(b[i:]))
+		} else {
+			word = uint64(b[i])
+		}
+
+		sum += word
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+
+	sum = (sum & 0xffff) + (sum >> 16)
+
+	return uint32(sum) + uint32(len(b))
+}
@@ -0,0 +1,12 @@
+// Package patch modifies game binaries to use a different provider (e.g. OpenSpy instead of GameSpy) by replacing
+// hostnames and similar strings in place, without changing the binary's length.
+//
+// Binaries are described by Patchable implementations, which provide the fingerprints used to detect the provider a
+// binary is currently patched for and the modifications needed to switch between providers. Patch, PatchContext,
+// DetectProvider and Inspect then work on any such binary, so tools like launchers or server panels can embed patching
+// by supplying their own Patchable (or the ones shipped with the migrator).
+//
+// The exported API of this package follows semantic versioning: exported identifiers are only removed or changed in
+// incompatible ways with a new major version. New behavior is added via new functions or Options fields instead of
+// changing existing signatures.
+package patch
@@ -0,0 +1,70 @@
+package patch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Inspection is the result of reading, hashing and fingerprinting a patchable's binary in a single pass
+type Inspection struct {
+	Data   []byte
+	SHA256 string
+	// Provider the binary is patched for (ProviderUnknown if DetectErr is set)
+	Provider  Provider
+	DetectErr error
+	// File the binary was read from, used to tell whether it changed since (see PatchContext)
+	file os.FileInfo
+}
+
+// Reinspect detects the provider of the inspected binary using another patchable's fingerprints (e.g. another variant
+// of the same executable), without reading the binary again
+func (i Inspection) Reinspect(patchable Patchable) Inspection {
+	i.Provider, i.DetectErr = determineCurrentlyUsedProvider(i.Data, patchable.GetFingerprints())
+	return i
+}
+
+// unchanged returns whether stats describe the inspected file, not modified since it was read
+func (i Inspection) unchanged(stats os.FileInfo) bool {
+	return i.file != nil && os.SameFile(i.file, stats) &&
+		i.file.Size() == stats.Size() && i.file.ModTime().Equal(stats.ModTime())
+}
+
+// Inspect reads the patchable's binary in dir once, hashing it while reading and detecting its provider afterwards
+func Inspect(patchable Patchable, dir string) (Inspection, error) {
+	f, err := os.Open(LongPath(filepath.Join(dir, patchable.GetFileName())))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Inspection{}, ErrNotExist
+		}
+		return Inspection{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	stats, err := f.Stat()
+	if err != nil {
+		return Inspection{}, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(int(stats.Size()))
+
+	h := sha256.New()
+	if _, err = io.Copy(&buf, io.TeeReader(f, h)); err != nil {
+		return Inspection{}, err
+	}
+
+	inspection := Inspection{
+		Data:   buf.Bytes(),
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+		file:   stats,
+	}
+	inspection.Provider, inspection.DetectErr = determineCurrentlyUsedProvider(inspection.Data, patchable.GetFingerprints())
+
+	return inspection, nil
+}
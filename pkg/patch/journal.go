@@ -0,0 +1,207 @@
+package patch
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	journalEntryExtension    = ".json"
+	journalOriginalExtension = ".orig"
+	journalModifiedExtension = ".new"
+)
+
+// JournalDir is where Patch keeps the original and modified content of a binary while writing it, allowing to recover
+// binaries left partially written by an interrupted run (journaling is disabled if empty)
+var JournalDir string
+
+type journalEntry struct {
+	Path string `json:"path"`
+}
+
+// InterruptedPatch is a binary left partially written by an interrupted patch, which needs to either be resumed or
+// rolled back
+type InterruptedPatch struct {
+	Path string
+	id   string
+}
+
+// Resume finishes writing the modified content to the binary
+func (p InterruptedPatch) Resume() error {
+	return p.restore(journalModifiedExtension)
+}
+
+// RollBack restores the original content of the binary
+func (p InterruptedPatch) RollBack() error {
+	return p.restore(journalOriginalExtension)
+}
+
+func (p InterruptedPatch) restore(extension string) error {
+	b, err := os.ReadFile(filepath.Join(JournalDir, p.id+extension))
+	if err != nil {
+		return err
+	}
+
+	if err = writeInPlace(p.Path, b); err != nil {
+		return err
+	}
+
+	return removeJournalEntry(p.id)
+}
+
+// RecoverJournal removes the journal entries of patches which were either not started or completed, returning
+// those which were interrupted while writing the binary
+func RecoverJournal() ([]InterruptedPatch, error) {
+	if JournalDir == "" {
+		return nil, nil
+	}
+
+	files, err := os.ReadDir(JournalDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	ids := map[string]bool{}
+	for _, f := range files {
+		id := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		ids[id] = ids[id] || filepath.Ext(f.Name()) == journalEntryExtension
+	}
+
+	var interrupted []InterruptedPatch
+	for id, complete := range ids {
+		// The entry is written last, so binaries of incomplete entries have not been touched yet
+		if !complete {
+			err = multierr.Append(err, removeJournalEntry(id))
+			continue
+		}
+
+		damaged, path, err2 := checkJournalEntry(id)
+		if err2 != nil {
+			err = multierr.Append(err, err2)
+			continue
+		}
+		if !damaged {
+			err = multierr.Append(err, removeJournalEntry(id))
+			continue
+		}
+
+		interrupted = append(interrupted, InterruptedPatch{Path: path, id: id})
+	}
+
+	return interrupted, err
+}
+
+// checkJournalEntry returns whether the entry's binary matches neither its original nor modified content
+func checkJournalEntry(id string) (bool, string, error) {
+	content, err := os.ReadFile(filepath.Join(JournalDir, id+journalEntryExtension))
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read journal entry: %w", err)
+	}
+
+	var entry journalEntry
+	if err = json.Unmarshal(content, &entry); err != nil {
+		// Unreadable entries cannot be recovered either way
+		return false, "", nil
+	}
+
+	b, err := os.ReadFile(LongPath(entry.Path))
+	if err != nil {
+		// Binaries which no longer exist do not need to be recovered
+		if errors.Is(err, os.ErrNotExist) {
+			return false, entry.Path, nil
+		}
+		return false, entry.Path, err
+	}
+
+	for _, extension := range []string{journalOriginalExtension, journalModifiedExtension} {
+		expected, err2 := os.ReadFile(filepath.Join(JournalDir, id+extension))
+		if err2 != nil {
+			return false, entry.Path, fmt.Errorf("failed to read journal entry: %w", err2)
+		}
+		if bytes.Equal(b, expected) {
+			return false, entry.Path, nil
+		}
+	}
+
+	return true, entry.Path, nil
+}
+
+// beginJournalEntry records the original and modified content of the binary at path before it is written, returning
+// the entry's id
+func beginJournalEntry(path string, original, modified []byte) (string, error) {
+	if err := os.MkdirAll(JournalDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(strings.ToLower(abs)))
+	id := hex.EncodeToString(sum[:])
+
+	if err = writeSynced(filepath.Join(JournalDir, id+journalOriginalExtension), original); err != nil {
+		return "", fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err = writeSynced(filepath.Join(JournalDir, id+journalModifiedExtension), modified); err != nil {
+		return "", fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	content, err := json.Marshal(journalEntry{Path: abs})
+	if err != nil {
+		return "", err
+	}
+	if err = writeSynced(filepath.Join(JournalDir, id+journalEntryExtension), content); err != nil {
+		return "", fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	return id, nil
+}
+
+func removeJournalEntry(id string) error {
+	var err error
+	for _, extension := range []string{journalEntryExtension, journalOriginalExtension, journalModifiedExtension} {
+		if err2 := os.Remove(filepath.Join(JournalDir, id+extension)); err2 != nil && !errors.Is(err2, os.ErrNotExist) {
+			err = multierr.Append(err, err2)
+		}
+	}
+	return err
+}
+
+func writeSynced(path string, b []byte) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer multierr.AppendInvoke(&err, multierr.Close(f))
+
+	if _, err = f.Write(b); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// writeInPlace overwrites the binary's content without replacing the file itself, keeping its permissions intact
+func writeInPlace(path string, b []byte) (err error) {
+	f, err := os.OpenFile(LongPath(path), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer multierr.AppendInvoke(&err, multierr.Close(f))
+
+	_, err = f.WriteAt(b, 0)
+	return err
+}
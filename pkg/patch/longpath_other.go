@@ -0,0 +1,8 @@
+//go:build !windows
+
+package patch
+
+// LongPath returns path as is, since only Windows limits the length of paths
+func LongPath(path string) string {
+	return path
+}
@@ -0,0 +1,34 @@
+package patch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Paths of this length or longer exceed MAX_PATH once the system appends an 8.3 file name (MAX_PATH - 12)
+	maxShortPathLength = 248
+	extendedPathPrefix = `\\?\`
+)
+
+// LongPath returns the absolute form of path, adding the extended-length prefix (\\?\) if it exceeds MAX_PATH. Go
+// (as of 1.20) only adds the prefix itself for absolute paths, and external tools (e.g. takeown) never do.
+func LongPath(path string) string {
+	if strings.HasPrefix(path, extendedPathPrefix) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if len(abs) < maxShortPathLength {
+		return abs
+	}
+
+	// Network shares use a dedicated prefix (\\server\share -> \\?\UNC\server\share)
+	if strings.HasPrefix(abs, `\\`) {
+		return extendedPathPrefix + "UNC" + abs[1:]
+	}
+	return extendedPathPrefix + abs
+}
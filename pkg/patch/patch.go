@@ -2,15 +2,20 @@ package patch
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"go.uber.org/multierr"
 )
 
+// Provider identifies the backend a binary is patched for (e.g. "OpenSpy"). Which providers exist is up to the
+// Patchable implementation, the package itself only knows ProviderUnknown.
 type Provider string
 
 const (
@@ -18,29 +23,121 @@ const (
 )
 
 var (
-	ErrNotExist     = os.ErrNotExist
+	// ErrNotExist is returned if the patchable's binary does not exist in the given folder
+	ErrNotExist = os.ErrNotExist
+	// ErrNotPatchable is returned (as a *NotPatchableError) if the binary does not match any provider's fingerprint
 	ErrNotPatchable = errors.New("binary contains unknown/mixed modifications")
+	// ErrUnknownModifications is returned if a binary does not contain the expected number of a modification's strings
+	ErrUnknownModifications = errors.New("binary contains unknown modifications, revert changes first")
+	// ErrChanged is returned if the binary was modified after the inspection passed to PatchContext
+	ErrChanged = errors.New("binary has changed since it was inspected")
 )
 
+// Patchable describes a binary which can be patched between providers
 type Patchable interface {
+	// GetFileName returns the name of the binary, relative to the folder passed to Patch and the like
 	GetFileName() string
 	GetFingerprints() map[Provider]Fingerprint
 	GetModifications(old, new Provider) ([]Modification, error)
 }
 
+// Fingerprint detects whether a binary is patched for a provider
 type Fingerprint interface {
 	Matches(b []byte) bool
 }
 
+// ScoringFingerprint is a Fingerprint made up of several markers, which can tell how close a binary is to matching
+type ScoringFingerprint interface {
+	Fingerprint
+	// Score returns how many of the fingerprint's markers are contained in b
+	Score(b []byte) (matched int, total int)
+}
+
+// Score returns how many of the fingerprint's markers are contained in b. Fingerprints not implementing
+// ScoringFingerprint count as a single marker.
+func Score(fingerprint Fingerprint, b []byte) (matched int, total int) {
+	if scoring, ok := fingerprint.(ScoringFingerprint); ok {
+		return scoring.Score(b)
+	}
+	if fingerprint.Matches(b) {
+		return 1, 1
+	}
+	return 0, 1
+}
+
+// Candidate describes how well a provider's fingerprint matches a binary
+type Candidate struct {
+	Provider Provider
+	Matched  int
+	Total    int
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s (%d/%d markers)", c.Provider, c.Matched, c.Total)
+}
+
+// NotPatchableError is returned if no fingerprint fully matches a binary, listing the best partial matches
+type NotPatchableError struct {
+	Candidates []Candidate
+}
+
+func (e *NotPatchableError) Error() string {
+	if len(e.Candidates) == 0 {
+		return ErrNotPatchable.Error()
+	}
+
+	closest := make([]string, 0, len(e.Candidates))
+	for _, c := range e.Candidates {
+		closest = append(closest, c.String())
+	}
+	return fmt.Sprintf("%s, closest matches: %s", ErrNotPatchable.Error(), strings.Join(closest, ", "))
+}
+
+func (e *NotPatchableError) Is(target error) bool {
+	return target == ErrNotPatchable
+}
+
+// Modification replaces all Count occurrences of Old with New, with both being padded to Length using null bytes
 type Modification struct {
+	Name   string
 	Old    []byte
 	New    []byte
 	Length int
 	Count  int
+	// If set, used instead of Old to find the strings to replace, allowing for minor variations
+	Pattern *Pattern
 }
 
-func Patch(patchable Patchable, dir string, new Provider) (err error) {
-	path := filepath.Join(dir, patchable.GetFileName())
+// Options configure PatchContext
+type Options struct {
+	// Called (if set) whenever a new step is reached
+	Progress ProgressFunc
+	// Result of inspecting the binary with the same patchable beforehand (see Inspect and Inspection.Reinspect), used
+	// instead of reading and fingerprinting the binary again
+	Inspection *Inspection
+}
+
+// Patch patches the patchable's binary in dir to use the new provider. Binaries already patched for the new provider are
+// left as they are.
+func Patch(patchable Patchable, dir string, new Provider) error {
+	return PatchContext(context.Background(), patchable, dir, new, Options{})
+}
+
+// PatchWithProgress patches the binary like Patch, calling progress (if set) whenever a new step is reached
+func PatchWithProgress(patchable Patchable, dir string, new Provider, progress ProgressFunc) error {
+	return PatchContext(context.Background(), patchable, dir, new, Options{Progress: progress})
+}
+
+// PatchContext patches the binary like Patch, returning ctx's error if it is done before the binary is written.
+// Once writing has started, the binary is always written completely, since stopping half-way would break it.
+func PatchContext(ctx context.Context, patchable Patchable, dir string, new Provider, opts Options) (err error) {
+	report := func(step Step, current, total int) {
+		if opts.Progress != nil {
+			opts.Progress(patchable.GetFileName(), step, current, total)
+		}
+	}
+
+	path := LongPath(filepath.Join(dir, patchable.GetFileName()))
 
 	stats, err := os.Stat(path)
 	if err != nil {
@@ -59,13 +156,26 @@ func Patch(patchable Patchable, dir string, new Provider) (err error) {
 	}
 	defer multierr.AppendInvoke(&err, multierr.Close(f))
 
-	original, err := io.ReadAll(f)
-	if err != nil {
+	if err = ctx.Err(); err != nil {
 		return err
 	}
 
-	// Detect "old"/current provider based on what's in the binary
-	old, err := determineCurrentlyUsedProvider(original, patchable.GetFingerprints())
+	report(StepRead, 0, 1)
+	var original []byte
+	var old Provider
+	if opts.Inspection != nil {
+		// Only act on the binary as it was inspected, not on whatever replaced it since
+		if !opts.Inspection.unchanged(stats) {
+			return ErrChanged
+		}
+		original, old, err = opts.Inspection.Data, opts.Inspection.Provider, opts.Inspection.DetectErr
+	} else {
+		if original, err = io.ReadAll(f); err != nil {
+			return err
+		}
+		// Detect "old"/current provider based on what's in the binary
+		old, err = determineCurrentlyUsedProvider(original, patchable.GetFingerprints())
+	}
 	if err != nil {
 		return err
 	}
@@ -81,14 +191,25 @@ func Patch(patchable Patchable, dir string, new Provider) (err error) {
 	}
 
 	// Apply modifications to a copy of the original
-	modified := original[:]
-	for _, m := range modifications {
+	modified := append([]byte(nil), original...)
+	for i, m := range modifications {
+		report(StepModify, i, len(modifications))
 		o := padRight(m.Old, 0, m.Length)
 		n := padRight(m.New, 0, m.Length)
 
+		if m.Pattern != nil {
+			p := m.Pattern.padRight(0, m.Length)
+			if count := p.Count(modified); count != m.Count {
+				return ErrUnknownModifications
+			}
+
+			p.ReplaceAll(modified, n)
+			continue
+		}
+
 		count := bytes.Count(modified, o)
 		if count != m.Count {
-			return fmt.Errorf("binary contains unknown modifications, revert changes first")
+			return ErrUnknownModifications
 		}
 
 		// Replace all occurrences, making sure to keep the binary the same length
@@ -100,22 +221,113 @@ func Patch(patchable Patchable, dir string, new Provider) (err error) {
 		return fmt.Errorf("length of modified binary does not match length of original")
 	}
 
-	_, err = f.WriteAt(modified, 0)
-	if err != nil {
+	// Raw string replacements invalidate the checksum, which some anti-virus engines flag
+	UpdateChecksum(modified)
+
+	// Last chance to stop without having changed anything
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	report(StepWrite, 0, 1)
+	if err = writeJournaled(f, path, original, modified); err != nil {
+		return err
+	}
+
+	// Read the binary back, so failed writes (e.g. by a full disk or another process) do not go unnoticed
+	report(StepVerify, 0, 1)
+	written := make([]byte, len(modified))
+	if _, err = f.ReadAt(written, 0); err != nil {
+		return fmt.Errorf("failed to verify written binary: %w", err)
+	}
+	if !bytes.Equal(written, modified) {
+		return fmt.Errorf("failed to verify written binary: content does not match")
+	}
+
+	return nil
+}
+
+// writeJournaled overwrites the content of the opened binary at path, recording both versions in the journal (if
+// enabled) first, so a binary left partially written by an interrupted run can be recovered
+func writeJournaled(f *os.File, path string, original, modified []byte) error {
+	var journalID string
+	if JournalDir != "" {
+		var err error
+		if journalID, err = beginJournalEntry(path, original, modified); err != nil {
+			return err
+		}
+	}
+
+	if _, err := f.WriteAt(modified, 0); err != nil {
 		return err
 	}
 
+	if journalID != "" {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		return removeJournalEntry(journalID)
+	}
+
 	return nil
 }
 
+// DetectProvider determines the provider the patchable's binary in dir is currently patched for
+func DetectProvider(patchable Patchable, dir string) (Provider, error) {
+	inspection, err := Inspect(patchable, dir)
+	if err != nil {
+		return ProviderUnknown, err
+	}
+
+	return inspection.Provider, inspection.DetectErr
+}
+
 func determineCurrentlyUsedProvider(b []byte, fingerprints map[Provider]Fingerprint) (Provider, error) {
+	candidates := make([]Candidate, 0, len(fingerprints))
 	for provider, fingerprint := range fingerprints {
-		if fingerprint.Matches(b) {
-			return provider, nil
+		matched, total := Score(fingerprint, b)
+		candidates = append(candidates, Candidate{
+			Provider: provider,
+			Matched:  matched,
+			Total:    total,
+		})
+	}
+
+	// Sort by completeness first, then by number of matched markers (more specific fingerprints win)
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if fi, fj := ci.Matched == ci.Total, cj.Matched == cj.Total; fi != fj {
+			return fi
+		}
+		if ci.Matched != cj.Matched {
+			return ci.Matched > cj.Matched
+		}
+		return ci.Provider < cj.Provider
+	})
+
+	if len(candidates) > 0 && candidates[0].Total > 0 && candidates[0].Matched == candidates[0].Total {
+		// Providers whose fingerprints match equally well cannot be told apart, so picking either would be a guess
+		ambiguous := candidates[:1]
+		for _, c := range candidates[1:] {
+			if c.Matched == c.Total && c.Matched == candidates[0].Matched {
+				ambiguous = append(ambiguous, c)
+			}
+		}
+		if len(ambiguous) == 1 {
+			return candidates[0].Provider, nil
+		}
+		return ProviderUnknown, &NotPatchableError{Candidates: ambiguous}
+	}
+
+	// Report partial matches only, the rest is not helpful
+	partial := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Matched > 0 {
+			partial = append(partial, c)
 		}
 	}
 
-	return ProviderUnknown, ErrNotPatchable
+	return ProviderUnknown, &NotPatchableError{Candidates: partial}
 }
 
 func padRight(b []byte, c byte, l int) []byte {
@@ -132,6 +344,30 @@ func padRight(b []byte, c byte, l int) []byte {
 	return p
 }
 
+// CountContained returns how many of bbs are contained in b
+func CountContained(b []byte, bbs [][]byte) int {
+	count := 0
+	for _, bb := range bbs {
+		if bytes.Contains(b, bb) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ContainsAllPatterns returns whether b contains each of the patterns
+func ContainsAllPatterns(b []byte, patterns []Pattern) bool {
+	for _, p := range patterns {
+		if !p.Matches(b) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ContainsAll returns whether b contains each of bbs
 func ContainsAll(b []byte, bbs [][]byte) bool {
 	for _, bb := range bbs {
 		if !bytes.Contains(b, bb) {
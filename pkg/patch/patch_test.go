@@ -0,0 +1,350 @@
+package patch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testFileName = "test.exe"
+
+	providerA Provider = "A"
+	providerB Provider = "B"
+)
+
+var testHostnames = map[Provider]string{
+	providerA: "a-provider.com",
+	providerB: "b.net",
+}
+
+// testPatchable patches "gpcm.<hostname>" strings (padded to 20 bytes, 2 occurrences) between two providers
+type testPatchable struct{}
+
+func (p testPatchable) GetFileName() string {
+	return testFileName
+}
+
+func (p testPatchable) GetFingerprints() map[Provider]Fingerprint {
+	fingerprints := make(map[Provider]Fingerprint, len(testHostnames))
+	for provider, hostname := range testHostnames {
+		fingerprints[provider] = testFingerprint{[]byte(hostname)}
+	}
+	return fingerprints
+}
+
+func (p testPatchable) GetModifications(old, new Provider) ([]Modification, error) {
+	return []Modification{
+		{
+			Name:   "gpcm",
+			Old:    []byte(fmt.Sprintf("gpcm.%s", testHostnames[old])),
+			New:    []byte(fmt.Sprintf("gpcm.%s", testHostnames[new])),
+			Length: 20,
+			Count:  2,
+		},
+	}, nil
+}
+
+type testFingerprint [][]byte
+
+func (f testFingerprint) Matches(b []byte) bool {
+	return ContainsAll(b, f)
+}
+
+func (f testFingerprint) Score(b []byte) (int, int) {
+	return CountContained(b, f), len(f)
+}
+
+func testBinary(hostnames ...string) []byte {
+	var b []byte
+	for _, hostname := range hostnames {
+		b = append(b, 'x', 0)
+		b = append(b, padRight([]byte("gpcm."+hostname), 0, 20)...)
+	}
+	return append(b, 'x', 0)
+}
+
+func writeTestBinary(t *testing.T, b []byte) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, testFileName)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, path
+}
+
+func readTestBinary(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPatch(t *testing.T) {
+	original := testBinary("a-provider.com", "a-provider.com")
+	dir, path := writeTestBinary(t, original)
+
+	if err := Patch(testPatchable{}, dir, providerB); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, testBinary("b.net", "b.net")) {
+		t.Errorf("unexpected patched binary: %q", b)
+	}
+
+	if err := Patch(testPatchable{}, dir, providerA); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, original) {
+		t.Errorf("patching back did not restore original: %q", b)
+	}
+}
+
+func TestPatchAlreadyPatched(t *testing.T) {
+	original := testBinary("b.net", "b.net")
+	dir, path := writeTestBinary(t, original)
+
+	if err := Patch(testPatchable{}, dir, providerB); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, original) {
+		t.Errorf("binary was modified: %q", b)
+	}
+}
+
+func TestPatchUnknownModifications(t *testing.T) {
+	original := testBinary("a-provider.com")
+	dir, path := writeTestBinary(t, original)
+
+	if err := Patch(testPatchable{}, dir, providerB); !errors.Is(err, ErrUnknownModifications) {
+		t.Errorf("expected ErrUnknownModifications, got %v", err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, original) {
+		t.Errorf("binary was modified: %q", b)
+	}
+}
+
+func TestPatchNotPatchable(t *testing.T) {
+	dir, _ := writeTestBinary(t, testBinary("c.org", "c.org"))
+
+	err := Patch(testPatchable{}, dir, providerB)
+	if !errors.Is(err, ErrNotPatchable) {
+		t.Errorf("expected ErrNotPatchable, got %v", err)
+	}
+}
+
+func TestPatchNotExist(t *testing.T) {
+	if err := Patch(testPatchable{}, t.TempDir(), providerB); !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestPatchInspected(t *testing.T) {
+	dir, path := writeTestBinary(t, testBinary("a-provider.com", "a-provider.com"))
+	inspection, err := Inspect(testPatchable{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = PatchContext(context.Background(), testPatchable{}, dir, providerB, Options{Inspection: &inspection}); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, testBinary("b.net", "b.net")) {
+		t.Errorf("unexpected patched binary: %q", b)
+	}
+}
+
+func TestPatchInspectedChanged(t *testing.T) {
+	dir, path := writeTestBinary(t, testBinary("a-provider.com", "a-provider.com"))
+	inspection, err := Inspect(testPatchable{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the binary after inspecting it
+	replaced := testBinary("a-provider.com", "a-provider.com", "a-provider.com")
+	if err = os.WriteFile(path, replaced, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = PatchContext(context.Background(), testPatchable{}, dir, providerB, Options{Inspection: &inspection})
+	if !errors.Is(err, ErrChanged) {
+		t.Errorf("expected ErrChanged, got %v", err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, replaced) {
+		t.Errorf("binary was modified: %q", b)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	// Another patcher only nil-terminated the shorter string, leaving a remnant of the original
+	b := testBinary("b.net", "b.net")
+	remnant := []byte("gpcm.b.net\x00provider")
+	b = bytes.Replace(b, padRight([]byte("gpcm.b.net"), 0, 20), padRight(remnant, 0, 20), 1)
+	dir, path := writeTestBinary(t, b)
+
+	provider, changed, err := Adopt(testPatchable{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider != providerB || !changed {
+		t.Errorf("expected %s and changed, got %s and %t", providerB, provider, changed)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, testBinary("b.net", "b.net")) {
+		t.Errorf("unexpected adopted binary: %q", b)
+	}
+
+	if _, changed, err = Adopt(testPatchable{}, dir); err != nil || changed {
+		t.Errorf("expected no changes adopting again, got %t (%v)", changed, err)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		b       string
+		exact   bool
+		count   int
+	}{
+		{pattern: `\drivers\etc\hosts`, b: `\drivers\etc\hosts`, exact: true, count: 1},
+		{pattern: `\drivers\et?\h?sts`, b: `\drivers\etz\hasts \drivers\etc\hosts`, exact: false, count: 2},
+		{pattern: `what??`, b: `what? whatever`, exact: true, count: 1},
+		{pattern: `aa`, b: `aaaaa`, exact: true, count: 2},
+		{pattern: `x?`, b: `x`, exact: false, count: 0},
+	}
+
+	for _, tt := range tests {
+		p := ParsePattern(tt.pattern)
+		if p.IsExact() != tt.exact {
+			t.Errorf("%q: expected exact %t", tt.pattern, tt.exact)
+		}
+		if count := p.Count([]byte(tt.b)); count != tt.count {
+			t.Errorf("%q: expected %d matches in %q, got %d", tt.pattern, tt.count, tt.b, count)
+		}
+		if p.Matches([]byte(tt.b)) != (tt.count > 0) {
+			t.Errorf("%q: unexpected match result for %q", tt.pattern, tt.b)
+		}
+	}
+}
+
+func TestPatternReplaceAll(t *testing.T) {
+	b := []byte("%s.ms1.x %s.ms2.x")
+	ParsePattern("%s.ms?.x").ReplaceAll(b, []byte("%s.ms.yy"))
+	if string(b) != "%s.ms.yy %s.ms.yy" {
+		t.Errorf("unexpected result: %q", b)
+	}
+}
+
+func TestPatchPattern(t *testing.T) {
+	original := testBinary("a-provider.com", "a-provider.com")
+	dir, path := writeTestBinary(t, original)
+
+	p := ParsePattern("gpcm.?-provider.com")
+	patchable := patternPatchable{pattern: &p}
+	if err := Patch(patchable, dir, providerB); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTestBinary(t, path); !bytes.Equal(b, testBinary("b.net", "b.net")) {
+		t.Errorf("unexpected patched binary: %q", b)
+	}
+}
+
+// patternPatchable locates the strings of provider A using a pattern rather than the exact string
+type patternPatchable struct {
+	testPatchable
+	pattern *Pattern
+}
+
+func (p patternPatchable) GetModifications(old, new Provider) ([]Modification, error) {
+	modifications, err := p.testPatchable.GetModifications(old, new)
+	if err != nil {
+		return nil, err
+	}
+	if old == providerA {
+		modifications[0].Pattern = p.pattern
+	}
+	return modifications, nil
+}
+
+func testPE(checksum uint32) []byte {
+	b := make([]byte, 0x101)
+	copy(b, "MZ")
+	b[0x3c] = 0x40
+	copy(b[0x40:], "PE\x00\x00")
+	for i := 0x44; i < len(b); i++ {
+		b[i] = byte(i * 7)
+	}
+	b[0x98], b[0x99], b[0x9a], b[0x9b] = byte(checksum), byte(checksum>>8), byte(checksum>>16), byte(checksum>>24)
+	return b
+}
+
+func TestUpdateChecksum(t *testing.T) {
+	b := testPE(0xdeadbeef)
+	UpdateChecksum(b)
+	if checksum := b[0x98:0x9c]; !bytes.Equal(checksum, []byte{0x82, 0xc8, 0x00, 0x00}) {
+		t.Errorf("unexpected checksum: %x", checksum)
+	}
+
+	// Binaries without a checksum are left untouched
+	b = testPE(0)
+	UpdateChecksum(b)
+	if !bytes.Equal(b, testPE(0)) {
+		t.Error("binary without checksum was modified")
+	}
+
+	// As are binaries which are not PE files
+	b = []byte("not a PE file, but long enough to contain the offset of the PE header")
+	original := append([]byte(nil), b...)
+	UpdateChecksum(b)
+	if !bytes.Equal(b, original) {
+		t.Error("non-PE binary was modified")
+	}
+}
+
+func TestDetermineCurrentlyUsedProviderTie(t *testing.T) {
+	fingerprints := map[Provider]Fingerprint{
+		providerA: testFingerprint{[]byte("gamespy.com")},
+		providerB: testFingerprint{[]byte("gamespy.com")},
+	}
+
+	provider, err := determineCurrentlyUsedProvider([]byte("gpcm.gamespy.com"), fingerprints)
+	if !errors.Is(err, ErrNotPatchable) {
+		t.Errorf("expected ErrNotPatchable for equal scores, got %s (%v)", provider, err)
+	}
+
+	// More specific fingerprints still win
+	fingerprints[providerB] = testFingerprint{[]byte("gamespy.com"), []byte("bf2hbc.dll")}
+	provider, err = determineCurrentlyUsedProvider([]byte("gpcm.gamespy.com bf2hbc.dll"), fingerprints)
+	if err != nil || provider != providerB {
+		t.Errorf("expected %s, got %s (%v)", providerB, provider, err)
+	}
+}
+
+// matchOnlyFingerprint implements Fingerprint without ScoringFingerprint, as external implementations may
+type matchOnlyFingerprint []byte
+
+func (f matchOnlyFingerprint) Matches(b []byte) bool {
+	return bytes.Contains(b, f)
+}
+
+func TestDetermineCurrentlyUsedProviderMatchOnly(t *testing.T) {
+	fingerprints := map[Provider]Fingerprint{
+		providerA: matchOnlyFingerprint("a-provider.com"),
+		providerB: testFingerprint{[]byte("b.net"), []byte("bf2hbc.dll")},
+	}
+
+	provider, err := determineCurrentlyUsedProvider([]byte("gpcm.a-provider.com"), fingerprints)
+	if err != nil || provider != providerA {
+		t.Errorf("expected %s, got %s (%v)", providerA, provider, err)
+	}
+
+	if matched, total := Score(fingerprints[providerA], []byte("gpcm.b.net")); matched != 0 || total != 1 {
+		t.Errorf("expected score 0/1, got %d/%d", matched, total)
+	}
+}
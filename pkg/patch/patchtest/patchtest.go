@@ -0,0 +1,163 @@
+// Package patchtest verifies patchables against golden-byte fixtures: sanitized string table segments of executables
+// in a known provider-patched state. Fixtures are stored as <dir>/<executable>/<provider>.bin.
+package patchtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cetteup/bf2-migrator/pkg/patch"
+)
+
+const (
+	fixtureExtension = ".bin"
+)
+
+type Fixture struct {
+	FileName string
+	Provider patch.Provider
+	Data     []byte
+}
+
+func (f Fixture) String() string {
+	return fmt.Sprintf("%s/%s", f.FileName, f.Provider)
+}
+
+// LoadFixtures reads all fixtures from dir in fsys
+func LoadFixtures(fsys fs.FS, dir string) ([]Fixture, error) {
+	executables, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []Fixture
+	for _, executable := range executables {
+		if !executable.IsDir() {
+			continue
+		}
+
+		entries, err := fs.ReadDir(fsys, path.Join(dir, executable.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || path.Ext(entry.Name()) != fixtureExtension {
+				continue
+			}
+
+			data, err := fs.ReadFile(fsys, path.Join(dir, executable.Name(), entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			fixtures = append(fixtures, Fixture{
+				FileName: executable.Name(),
+				Provider: patch.Provider(strings.TrimSuffix(entry.Name(), fixtureExtension)),
+				Data:     data,
+			})
+		}
+	}
+
+	return fixtures, nil
+}
+
+// WriteFixture stores data as the fixture of the given executable and provider in dir
+func WriteFixture(dir string, fileName string, provider patch.Provider, data []byte) error {
+	target := filepath.Join(dir, fileName)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(target, string(provider)+fixtureExtension), data, 0644)
+}
+
+// Check verifies each fixture against the matching patchable: the fixture's provider must be detected, patching it to
+// every target must result in the target's fixture (if one exists) and patching it back must restore the original bytes.
+// All problems are returned rather than stopping at the first one.
+func Check(patchables []patch.Patchable, fixtures []Fixture, targets []patch.Provider) []error {
+	golden := make(map[string]Fixture, len(fixtures))
+	for _, f := range fixtures {
+		golden[f.String()] = f
+	}
+
+	var errs []error
+	for _, f := range fixtures {
+		p := findPatchable(patchables, f.FileName)
+		if p == nil {
+			errs = append(errs, fmt.Errorf("%s: no patchable for executable", f))
+			continue
+		}
+
+		for _, target := range targets {
+			if err := checkRoundTrip(p, f, target, golden); err != nil {
+				errs = append(errs, fmt.Errorf("%s -> %s: %w", f, target, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+func checkRoundTrip(p patch.Patchable, f Fixture, target patch.Provider, golden map[string]Fixture) (err error) {
+	dir, err := os.MkdirTemp("", "patchtest")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	path := filepath.Join(dir, p.GetFileName())
+	if err = os.WriteFile(path, f.Data, 0644); err != nil {
+		return err
+	}
+
+	detected, err := patch.DetectProvider(p, dir)
+	if err != nil {
+		return fmt.Errorf("failed to detect provider: %w", err)
+	}
+	if detected != f.Provider {
+		return fmt.Errorf("detected provider %s", detected)
+	}
+
+	if err = patch.Patch(p, dir, target); err != nil {
+		return fmt.Errorf("failed to patch: %w", err)
+	}
+
+	patched, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if expected, ok := golden[Fixture{FileName: f.FileName, Provider: target}.String()]; ok && !bytes.Equal(patched, expected.Data) {
+		return fmt.Errorf("patched bytes do not match fixture of target")
+	}
+
+	if err = patch.Patch(p, dir, f.Provider); err != nil {
+		return fmt.Errorf("failed to patch back: %w", err)
+	}
+
+	reverted, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(reverted, f.Data) {
+		return fmt.Errorf("patching back did not restore original bytes")
+	}
+
+	return nil
+}
+
+func findPatchable(patchables []patch.Patchable, fileName string) patch.Patchable {
+	for _, p := range patchables {
+		if strings.EqualFold(p.GetFileName(), fileName) {
+			return p
+		}
+	}
+	return nil
+}
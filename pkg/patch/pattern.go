@@ -0,0 +1,121 @@
+package patch
+
+const (
+	wildcard = '?'
+)
+
+// Pattern is a byte sequence in which some bytes match any byte. In the pattern syntax, "?" is a wildcard
+// and "??" is a literal question mark, e.g. "\drivers\e?c\h?sts" matches both "\drivers\etc\hosts" and "\drivers\etz\hasts".
+type Pattern struct {
+	bytes     []byte
+	wildcards []bool
+}
+
+// ParsePattern parses the pattern syntax described on Pattern
+func ParsePattern(s string) Pattern {
+	p := Pattern{
+		bytes:     make([]byte, 0, len(s)),
+		wildcards: make([]bool, 0, len(s)),
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == wildcard {
+			if i+1 < len(s) && s[i+1] == wildcard {
+				p.bytes = append(p.bytes, wildcard)
+				p.wildcards = append(p.wildcards, false)
+				i++
+				continue
+			}
+			p.bytes = append(p.bytes, 0)
+			p.wildcards = append(p.wildcards, true)
+			continue
+		}
+		p.bytes = append(p.bytes, s[i])
+		p.wildcards = append(p.wildcards, false)
+	}
+
+	return p
+}
+
+// Bytes returns the pattern's bytes, with wildcards represented as nil-bytes
+func (p Pattern) Bytes() []byte {
+	return p.bytes
+}
+
+func (p Pattern) Len() int {
+	return len(p.bytes)
+}
+
+// IsExact returns whether the pattern contains no wildcards
+func (p Pattern) IsExact() bool {
+	for _, w := range p.wildcards {
+		if w {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Pattern) Matches(b []byte) bool {
+	return p.Index(b) != -1
+}
+
+func (p Pattern) Index(b []byte) int {
+	for i := 0; i+len(p.bytes) <= len(b); i++ {
+		if p.matchAt(b, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Count returns the number of non-overlapping matches in b
+func (p Pattern) Count(b []byte) int {
+	return len(p.indices(b))
+}
+
+// ReplaceAll replaces all non-overlapping matches in b with n in place, n must be as long as the pattern
+func (p Pattern) ReplaceAll(b []byte, n []byte) {
+	for _, i := range p.indices(b) {
+		copy(b[i:i+len(p.bytes)], n)
+	}
+}
+
+func (p Pattern) padRight(c byte, l int) Pattern {
+	padded := Pattern{
+		bytes:     padRight(p.bytes, c, l),
+		wildcards: make([]bool, len(p.wildcards), l),
+	}
+	copy(padded.wildcards, p.wildcards)
+	for len(padded.wildcards) < len(padded.bytes) {
+		padded.wildcards = append(padded.wildcards, false)
+	}
+
+	return padded
+}
+
+func (p Pattern) indices(b []byte) []int {
+	if len(p.bytes) == 0 {
+		return nil
+	}
+
+	var indices []int
+	for i := 0; i+len(p.bytes) <= len(b); {
+		if p.matchAt(b, i) {
+			indices = append(indices, i)
+			i += len(p.bytes)
+			continue
+		}
+		i++
+	}
+
+	return indices
+}
+
+func (p Pattern) matchAt(b []byte, i int) bool {
+	for j, c := range p.bytes {
+		if !p.wildcards[j] && b[i+j] != c {
+			return false
+		}
+	}
+	return true
+}
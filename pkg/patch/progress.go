@@ -0,0 +1,18 @@
+package patch
+
+// Step is a stage of patching a binary
+type Step string
+
+const (
+	StepRead   Step = "read"
+	StepModify Step = "modify"
+	StepWrite  Step = "write"
+	StepVerify Step = "verify"
+)
+
+// Steps lists the steps in the order they are reached while patching a binary
+var Steps = []Step{StepRead, StepModify, StepWrite, StepVerify}
+
+// ProgressFunc is called whenever patching the binary of fileName reaches a new step. Current and total describe the
+// progress within the step (e.g. the number of modifications applied so far).
+type ProgressFunc func(fileName string, step Step, current, total int)
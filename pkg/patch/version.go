@@ -0,0 +1,79 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Signature of the VS_FIXEDFILEINFO structure embedded in a PE's version resource
+var fixedFileInfoSignature = []byte{0xbd, 0x04, 0xef, 0xfe}
+
+type Version struct {
+	Major uint16
+	Minor uint16
+	Patch uint16
+	Build uint16
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// ReadFileVersion extracts the file version from the version resource of a PE binary
+func ReadFileVersion(b []byte) (Version, bool) {
+	i := bytes.Index(b, fixedFileInfoSignature)
+	// Signature is followed by dwStrucVersion, dwFileVersionMS and dwFileVersionLS
+	if i == -1 || i+16 > len(b) {
+		return Version{}, false
+	}
+
+	ms := binary.LittleEndian.Uint32(b[i+8:])
+	ls := binary.LittleEndian.Uint32(b[i+12:])
+	return Version{
+		Major: uint16(ms >> 16),
+		Minor: uint16(ms),
+		Patch: uint16(ls >> 16),
+		Build: uint16(ls),
+	}, true
+}
+
+// Less returns whether v is an older version than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	if v.Patch != other.Patch {
+		return v.Patch < other.Patch
+	}
+	return v.Build < other.Build
+}
+
+// ParseVersion parses a version in "major[.minor[.patch[.build]]]" notation
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return Version{}, fmt.Errorf("invalid version: %s", s)
+	}
+
+	var numbers [4]uint16
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 16)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version: %s", s)
+		}
+		numbers[i] = uint16(n)
+	}
+
+	return Version{
+		Major: numbers[0],
+		Minor: numbers[1],
+		Patch: numbers[2],
+		Build: numbers[3],
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Package sysproxy determines the proxy to use for HTTP requests based on the operating system's configuration
+package sysproxy
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FromSystem returns the proxy configured for the request's URL in the system settings (WinHTTP/Internet Options on
+// Windows, including PAC files and auto-detection), falling back to the environment (HTTP_PROXY etc.).
+// A nil URL means no proxy should be used. It can be used as http.Transport.Proxy.
+func FromSystem(req *http.Request) (*url.URL, error) {
+	proxy, ok, err := fromSystem(req.URL)
+	if err != nil || !ok {
+		// System settings are not available/usable, environment variables are still worth a try
+		return http.ProxyFromEnvironment(req)
+	}
+
+	return proxy, nil
+}
+
+// selectProxy picks the proxy for u from a WinHTTP-style proxy list ("host:port" or "http=host:port;https=host:port")
+// and bypass list ("<local>;*.example.com"). Returns nil if no proxy should be used.
+func selectProxy(u *url.URL, list string, bypass string) (*url.URL, error) {
+	if list == "" || isBypassed(u, bypass) {
+		return nil, nil
+	}
+
+	var fallback string
+	for _, entry := range splitList(list) {
+		scheme, address, found := strings.Cut(entry, "=")
+		if !found {
+			if fallback == "" {
+				fallback = entry
+			}
+			continue
+		}
+		if strings.EqualFold(scheme, u.Scheme) {
+			return parseProxy(address)
+		}
+	}
+
+	if fallback == "" {
+		return nil, nil
+	}
+
+	return parseProxy(fallback)
+}
+
+func isBypassed(u *url.URL, bypass string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range splitList(bypass) {
+		pattern = strings.ToLower(pattern)
+		if pattern == "<local>" {
+			// Refers to any host name without a period
+			if !strings.Contains(host, ".") {
+				return true
+			}
+			continue
+		}
+
+		if matched, err := path.Match(pattern, host); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseProxy(address string) (*url.URL, error) {
+	// Proxies are usually configured without scheme, in which case they are HTTP proxies
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	return url.Parse(address)
+}
+
+func splitList(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package sysproxy
+
+import (
+	"net/url"
+)
+
+func fromSystem(_ *url.URL) (*url.URL, bool, error) {
+	return nil, false, nil
+}
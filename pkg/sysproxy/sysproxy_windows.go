@@ -0,0 +1,150 @@
+package sysproxy
+
+import (
+	"net/url"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	winHTTPAccessTypeNoProxy    = 1
+	winHTTPAccessTypeNamedProxy = 3
+
+	winHTTPAutoProxyAutoDetect = 0x00000001
+	winHTTPAutoProxyConfigURL  = 0x00000002
+
+	winHTTPAutoDetectTypeDHCP = 0x00000001
+	winHTTPAutoDetectTypeDNSA = 0x00000002
+)
+
+var (
+	winhttp = windows.NewLazySystemDLL("winhttp.dll")
+
+	winHTTPOpen                           = winhttp.NewProc("WinHttpOpen")
+	winHTTPCloseHandle                    = winhttp.NewProc("WinHttpCloseHandle")
+	winHTTPGetProxyForURL                 = winhttp.NewProc("WinHttpGetProxyForUrl")
+	winHTTPGetIEProxyConfigForCurrentUser = winhttp.NewProc("WinHttpGetIEProxyConfigForCurrentUser")
+	winHTTPGetDefaultProxyConfiguration   = winhttp.NewProc("WinHttpGetDefaultProxyConfiguration")
+
+	kernel32   = windows.NewLazySystemDLL("kernel32.dll")
+	globalFree = kernel32.NewProc("GlobalFree")
+)
+
+// WINHTTP_CURRENT_USER_IE_PROXY_CONFIG
+type ieProxyConfig struct {
+	autoDetect    int32
+	autoConfigURL *uint16
+	proxy         *uint16
+	proxyBypass   *uint16
+}
+
+// WINHTTP_AUTOPROXY_OPTIONS
+type autoProxyOptions struct {
+	flags                 uint32
+	autoDetectFlags       uint32
+	autoConfigURL         *uint16
+	reserved1             uintptr
+	reserved2             uint32
+	autoLogonIfChallenged int32
+}
+
+// WINHTTP_PROXY_INFO
+type proxyInfo struct {
+	accessType  uint32
+	proxy       *uint16
+	proxyBypass *uint16
+}
+
+func fromSystem(u *url.URL) (*url.URL, bool, error) {
+	if err := winhttp.Load(); err != nil {
+		return nil, false, err
+	}
+
+	// Internet Options (per user), which may point to a PAC file or enable auto-detection
+	var ie ieProxyConfig
+	if r, _, _ := winHTTPGetIEProxyConfigForCurrentUser.Call(uintptr(unsafe.Pointer(&ie))); r != 0 {
+		defer freeAll(ie.autoConfigURL, ie.proxy, ie.proxyBypass)
+
+		if ie.autoDetect != 0 || ie.autoConfigURL != nil {
+			if info, ok := getProxyForURL(u, ie.autoDetect != 0, ie.autoConfigURL); ok {
+				defer freeAll(info.proxy, info.proxyBypass)
+				return fromProxyInfo(u, info)
+			}
+		}
+
+		if ie.proxy != nil {
+			proxy, err := selectProxy(u, windows.UTF16PtrToString(ie.proxy), utf16PtrToString(ie.proxyBypass))
+			return proxy, true, err
+		}
+	}
+
+	// Machine-wide WinHTTP configuration (netsh winhttp set proxy)
+	var info proxyInfo
+	if r, _, _ := winHTTPGetDefaultProxyConfiguration.Call(uintptr(unsafe.Pointer(&info))); r != 0 {
+		defer freeAll(info.proxy, info.proxyBypass)
+		if info.accessType == winHTTPAccessTypeNamedProxy {
+			return fromProxyInfo(u, info)
+		}
+	}
+
+	return nil, false, nil
+}
+
+// getProxyForURL evaluates the PAC file (at autoConfigURL or found via auto-detection) for u
+func getProxyForURL(u *url.URL, autoDetect bool, autoConfigURL *uint16) (proxyInfo, bool) {
+	session, _, _ := winHTTPOpen.Call(0, winHTTPAccessTypeNoProxy, 0, 0, 0)
+	if session == 0 {
+		return proxyInfo{}, false
+	}
+	defer func() {
+		_, _, _ = winHTTPCloseHandle.Call(session)
+	}()
+
+	options := autoProxyOptions{
+		autoLogonIfChallenged: 1,
+	}
+	if autoConfigURL != nil {
+		options.flags |= winHTTPAutoProxyConfigURL
+		options.autoConfigURL = autoConfigURL
+	}
+	if autoDetect {
+		options.flags |= winHTTPAutoProxyAutoDetect
+		options.autoDetectFlags = winHTTPAutoDetectTypeDHCP | winHTTPAutoDetectTypeDNSA
+	}
+
+	target, err := windows.UTF16PtrFromString(u.String())
+	if err != nil {
+		return proxyInfo{}, false
+	}
+
+	var info proxyInfo
+	r, _, _ := winHTTPGetProxyForURL.Call(session, uintptr(unsafe.Pointer(target)), uintptr(unsafe.Pointer(&options)), uintptr(unsafe.Pointer(&info)))
+	return info, r != 0
+}
+
+func fromProxyInfo(u *url.URL, info proxyInfo) (*url.URL, bool, error) {
+	if info.accessType != winHTTPAccessTypeNamedProxy || info.proxy == nil {
+		// Explicitly configured to not use a proxy
+		return nil, true, nil
+	}
+
+	proxy, err := selectProxy(u, windows.UTF16PtrToString(info.proxy), utf16PtrToString(info.proxyBypass))
+	return proxy, true, err
+}
+
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	return windows.UTF16PtrToString(p)
+}
+
+// freeAll frees strings allocated by WinHTTP
+func freeAll(ptrs ...*uint16) {
+	for _, p := range ptrs {
+		if p != nil {
+			_, _, _ = globalFree.Call(uintptr(unsafe.Pointer(p)))
+		}
+	}
+}